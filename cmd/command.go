@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gavin/blog/config"
+	"github.com/gavin/blog/migrations"
+)
+
+const usage = `usage: go run ./cmd [-config file] [-port addr] [-db-driver name] [-db-dsn dsn] [command]
+
+commands:
+  go run ./cmd migrate up [version]     执行未执行的迁移（可指定执行到的版本）
+  go run ./cmd migrate down [steps]     回滚最近的 steps 个迁移，默认 1
+  go run ./cmd migrate status           查看迁移状态
+  go run ./cmd migrate create <name>    在 migrations/ 下生成新的迁移文件
+  go run ./cmd recount-comments         重新统计所有文章的评论数
+`
+
+// runCommand 执行命令行子命令，执行完退出，不启动 HTTP 服务
+func runCommand(cfg *config.Config, args []string) {
+	switch args[0] {
+	case "migrate":
+		runMigrate(cfg, args[1:])
+	case "recount-comments":
+		config.InitDB(&cfg.Database)
+		config.Migrate(&cfg.Database)
+		updated, err := newServices().Comments.Recount(context.Background())
+		if err != nil {
+			exitf("recount comments: %v", err)
+		}
+		fmt.Printf("recounted comments for %d posts\n", updated)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	// create 只生成文件，不需要连接数据库
+	if args[0] == "create" {
+		if len(args) < 2 {
+			exitf("migration name is required")
+		}
+		path, err := migrations.Create("migrations", args[1])
+		if err != nil {
+			exitf("create migration: %v", err)
+		}
+		fmt.Println("created", path)
+		return
+	}
+
+	config.InitDB(&cfg.Database)
+	switch args[0] {
+	case "up":
+		target := ""
+		if len(args) > 1 {
+			target = args[1]
+		}
+		if err := migrations.Up(config.DB, target); err != nil {
+			exitf("migrate up: %v", err)
+		}
+		printStatus()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				exitf("invalid steps: %s", args[1])
+			}
+			steps = n
+		}
+		if err := migrations.Down(config.DB, steps); err != nil {
+			exitf("migrate down: %v", err)
+		}
+		printStatus()
+	case "status":
+		printStatus()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func printStatus() {
+	list, err := migrations.StatusList(config.DB)
+	if err != nil {
+		exitf("migration status: %v", err)
+	}
+	for _, s := range list {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Unknown {
+			state += " (missing in code)"
+		}
+		fmt.Printf("%s  %-30s  %s\n", s.Version, s.Name, state)
+	}
+}
+
+func exitf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
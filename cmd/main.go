@@ -6,7 +6,15 @@ import (
 	"github.com/gavin/blog/config"
 	"github.com/gavin/blog/logger"
 	"github.com/gavin/blog/middleware"
+	"github.com/gavin/blog/repository"
 	"github.com/gavin/blog/routers"
+	"github.com/gavin/blog/scheduler"
+	"github.com/gavin/blog/search"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/spam"
+	"github.com/gavin/blog/store"
+	"github.com/gavin/blog/tracing"
+	"github.com/gavin/blog/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -22,6 +30,19 @@ func main() {
 		logger.Log.Error("Error loading .env file")
 	}
 
+	// 加载配置：配置文件 -> 环境变量 -> 命令行参数
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		logger.Log.Fatalf("load config: %v", err)
+	}
+	utils.InitJWT(cfg.JWT.Secret, cfg.JWT.AccessTokenTTL.Duration, cfg.JWT.RefreshTokenTTL.Duration)
+
+	// 子命令（migrate、recount-comments）执行完直接退出
+	if len(args) > 0 {
+		runCommand(cfg, args)
+		return
+	}
+
 	write := logger.Log.GetIoWriter()
 	// 将 Gin 的日志输出指向 Zap
 	// 重定向必须在 gin.New() 前
@@ -30,20 +51,67 @@ func main() {
 
 	logger.Log.Infof("starting handlers")
 
+	// 初始化链路追踪，exporter 为 none 时埋点为 no-op
+	tracerProvider, err := tracing.Init(&cfg.Tracing)
+	if err != nil {
+		logger.Log.Fatalf("%v", err)
+	}
+
 	router := gin.New()
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware())
 	router.Use(middleware.GinLogMiddleware())
 	router.Use(middleware.GinRecoveryWithLogger())
 
+	// 先开始监听，连接数据库、执行迁移期间 /healthz 返回 200，/readyz 返回 503，完成后切换到完整路由并标记就绪
+	handler := newSwitchHandler(bootRouter())
+	srv := newHTTPServer(&cfg.Server, handler)
+	serverErr, err := listen(srv)
+	if err != nil {
+		logger.Log.Fatalf("listen err: %v", err)
+	}
+
 	// 初始化数据库
-	config.InitDB()
+	config.InitDB(&cfg.Database)
+	if err := config.DB.Use(tracing.GormPlugin{}); err != nil {
+		logger.Log.Errorf("register gorm tracing err: %v", err)
+	}
+
+	config.Migrate(&cfg.Database)
+	config.PromoteAdmins(cfg.AdminUsernames)
 
-	config.Migrate()
+	// 初始化令牌撤销存储
+	store.InitRevocationStore()
+	// 初始化垃圾评论检测
+	spam.InitChecker(config.DB, cfg.Spam)
+
+	// 构建搜索索引
+	if err := search.InitIndex(config.DB); err != nil {
+		logger.Log.Errorf("build search index err: %v", err)
+	}
 
-	routers.InitApi(router)
+	services := newServices()
+	// 启动定时发布调度器，发布经过 PostService，搜索索引随状态流转同步
+	postScheduler := scheduler.New(services.Users, services.Posts, cfg.Scheduler.Interval.Duration)
+	postScheduler.Start()
+	registerHealthChecks(postScheduler)
+
+	routers.InitApi(router, services)
+	workers := append([]worker{postScheduler}, initMetrics(cfg, router)...)
+	// 最后导出剩余的 span
+	if tracerProvider != nil {
+		workers = append(workers, tracerProvider)
+	}
+
+	handler.Set(router)
+	serve(&cfg.Server, srv, serverErr, workers...)
+}
 
-	port := os.Getenv("PORT")
-	logger.Log.Infof("handlers started  addr %s", port)
-	router.Run(port) // 监听并在 0.0.0.0:8080 上启动服务
+// newServices 基于数据库构造业务服务，HTTP 服务和命令行共用
+func newServices() *service.Services {
+	repos := repository.NewGormRepositories(config.DB)
+	return service.New(repos, store.Revocations, spam.Default, search.DBIndexer{DB: config.DB})
 }
 
 func deferClose() {
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gavin/blog/config"
+	"github.com/gavin/blog/health"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/metrics"
+	"github.com/gavin/blog/middleware"
+	"github.com/gavin/blog/routers"
+	"github.com/gavin/blog/scheduler"
+	"github.com/gavin/blog/search"
+	"github.com/gin-gonic/gin"
+)
+
+// worker 需要在退出时停止的后台任务
+type worker interface {
+	Stop(ctx context.Context) error
+}
+
+// registerHealthChecks 注册 GET /readyz 的检查项，数据库故障时实例只会被摘除流量而不会被重启
+func registerHealthChecks(postScheduler *scheduler.Scheduler) {
+	health.Register("database", time.Second, config.PingDB)
+	health.Register("migrations", 2*time.Second, config.CheckSchema)
+	health.Register("search_index", 0, search.Check)
+	health.Register("scheduler", 0, postScheduler.Check)
+}
+
+// serverWorker 把管理端口的 HTTP 服务当作后台任务，随主服务一起退出
+type serverWorker struct {
+	srv *http.Server
+}
+
+func (w serverWorker) Stop(ctx context.Context) error {
+	return w.srv.Shutdown(ctx)
+}
+
+// initMetrics 注册 /metrics：设置了 METRICS_ADDR 时在管理端口提供，返回需要在退出时停止的管理服务；
+// 否则在服务端口提供，必须设置 METRICS_TOKEN
+func initMetrics(cfg *config.Config, router *gin.Engine) []worker {
+	if sqlDB, err := config.DB.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			logger.Log.Errorf("register db stats err: %v", err)
+		}
+	}
+
+	if cfg.Metrics.Addr == "" {
+		if cfg.Metrics.Token == "" {
+			logger.Log.Warnf("metrics endpoint disabled: set METRICS_ADDR or METRICS_TOKEN to expose /metrics")
+			return nil
+		}
+		routers.InitMetrics(router, cfg.Metrics.Token)
+		return nil
+	}
+
+	admin := gin.New()
+	admin.Use(middleware.GinRecoveryWithLogger())
+	routers.InitMetrics(admin, cfg.Metrics.Token)
+	adminConfig := cfg.Server
+	adminConfig.Port = cfg.Metrics.Addr
+	srv := newHTTPServer(&adminConfig, admin)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		logger.Log.Errorf("metrics server listen err: %v", err)
+		return nil
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Errorf("metrics server stopped: %v", err)
+		}
+	}()
+	logger.Log.Infof("metrics server started | addr: %s", ln.Addr())
+	return []worker{serverWorker{srv: srv}}
+}
+
+func newHTTPServer(c *config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.Port,
+		Handler:           handler,
+		ReadTimeout:       c.ReadTimeout.Duration,
+		ReadHeaderTimeout: c.ReadHeaderTimeout.Duration,
+		WriteTimeout:      c.WriteTimeout.Duration,
+		IdleTimeout:       c.IdleTimeout.Duration,
+	}
+}
+
+// switchHandler 运行中可以替换的 http.Handler：启动期间使用只有健康检查的临时路由，初始化完成后切换到完整路由
+// gin 的路由不能在处理请求的同时注册，所以不直接往正在服务的 engine 上添加路由
+type switchHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func newSwitchHandler(handler http.Handler) *switchHandler {
+	h := &switchHandler{}
+	h.Set(handler)
+	return h
+}
+
+func (h *switchHandler) Set(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
+
+// bootRouter 启动期间的临时路由：/healthz 正常返回，/readyz 在就绪前返回 503，其他请求返回 503
+func bootRouter() *gin.Engine {
+	boot := gin.New()
+	boot.Use(middleware.GinRecoveryWithLogger())
+	routers.InitHealth(boot)
+	boot.NoRoute(func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusServiceUnavailable)
+	})
+	return boot
+}
+
+// listen 绑定端口后在后台启动 HTTP 服务，返回服务退出时的错误；端口被占用等绑定失败直接返回错误
+// 在连接数据库、执行迁移之前调用，等待数据库期间存活探针也能访问
+func listen(srv *http.Server) (<-chan error, error) {
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Infof("server listening | addr: %s", ln.Addr())
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.Serve(ln)
+	}()
+	return serverErr, nil
+}
+
+// serve 标记就绪并阻塞到收到 SIGINT / SIGTERM 或服务异常退出，之后按顺序退出：
+// 标记未就绪 -> 等待 ShutdownDelay -> 停止接收新请求并等待处理中的请求 -> 停止后台任务 -> 关闭数据库连接池
+// 日志由 main 最后关闭
+func serve(c *config.ServerConfig, srv *http.Server, serverErr <-chan error, workers ...worker) {
+	health.SetReady(true)
+	logger.Log.Infof("server started | addr: %s", srv.Addr)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case sig := <-quit:
+		logger.Log.Infof("received signal %v, shutting down", sig)
+	case err := <-serverErr:
+		// 监听失败等情况，仍然走完后面的清理
+		logger.Log.Errorf("server stopped: %v", err)
+	}
+
+	health.SetReady(false)
+	if delay := c.ShutdownDelay.Duration; delay > 0 {
+		logger.Log.Infof("not ready, waiting %v before draining", delay)
+		time.Sleep(delay)
+	}
+
+	// 请求和后台任务共用同一个截止时间
+	ctx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Log.Errorf("drain requests err: %v", err)
+		// 截止时间已到，强制断开剩余连接
+		srv.Close()
+	}
+	for _, w := range workers {
+		if err := w.Stop(ctx); err != nil {
+			logger.Log.Errorf("stop background worker err: %v", err)
+		}
+	}
+	if err := config.CloseDB(); err != nil {
+		logger.Log.Errorf("close database err: %v", err)
+	}
+	logger.Log.Infof("server exited")
+}
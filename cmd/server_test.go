@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavin/blog/health"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBootRouterServesHealthUntilSwitched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	health.SetReady(false)
+	handler := newSwitchHandler(bootRouter())
+
+	status := func(path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+	// 启动期间：存活探针可用，未就绪，其他接口暂不可用
+	if got := status("/healthz"); got != http.StatusOK {
+		t.Errorf("boot /healthz = %d, want 200", got)
+	}
+	if got := status("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("boot /readyz = %d, want 503", got)
+	}
+	if got := status("/post/1"); got != http.StatusServiceUnavailable {
+		t.Errorf("boot /post/1 = %d, want 503", got)
+	}
+
+	router := gin.New()
+	router.GET("/post/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	handler.Set(router)
+	if got := status("/post/1"); got != http.StatusOK {
+		t.Errorf("after switch /post/1 = %d, want 200", got)
+	}
+}
+
+func TestListenBindsBeforeServing(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	if _, err := listen(&http.Server{Addr: taken.Addr().String()}); err == nil {
+		t.Fatal("listen on a taken address succeeded")
+	}
+
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	serverErr, err := listen(srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-serverErr; err != http.ErrServerClosed {
+		t.Errorf("serve err = %v, want ErrServerClosed", err)
+	}
+}
@@ -0,0 +1,368 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Config 应用配置
+// 加载顺序（后者覆盖前者）：默认值 -> 配置文件（YAML / TOML）-> 环境变量 -> 命令行参数
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler"`
+	Spam      SpamConfig      `yaml:"spam" toml:"spam"`
+	Metrics   MetricsConfig   `yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	// 启动时设为管理员的用户名，用于初始化第一个管理员
+	AdminUsernames []string `yaml:"admin_usernames" toml:"admin_usernames" env:"ADMIN_USERNAMES"`
+}
+
+type ServerConfig struct {
+	// 监听地址，如 :8080
+	Port string `yaml:"port" toml:"port" env:"PORT"`
+	// 读取整个请求（含请求体）的超时，0 表示不限制
+	ReadTimeout Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	// 读取请求头的超时，为 0 时使用 ReadTimeout
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	// 写响应的超时，0 表示不限制
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	// keep-alive 空闲连接的超时，为 0 时使用 ReadTimeout
+	IdleTimeout Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// 收到退出信号后先标记为未就绪，等待该时长让负载均衡摘除实例，再停止接收新请求
+	ShutdownDelay Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// 等待处理中的请求和后台任务结束的最长时间
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+type DatabaseConfig struct {
+	// mysql / postgres / sqlite
+	Driver string `yaml:"driver" toml:"driver" env:"DB_DRIVER"`
+	// 完整连接字符串，设置后忽略 Host、Port 等连接参数
+	DSN  string `yaml:"dsn" toml:"dsn" env:"DB_DSN"`
+	Host string `yaml:"host" toml:"host" env:"DB_HOST"`
+	// 为 0 时按驱动使用默认端口
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+	// postgres 专用
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" env:"DB_SSLMODE"`
+	TimeZone string `yaml:"timezone" toml:"timezone" env:"DB_TIMEZONE"`
+	// sqlite 数据库文件，:memory: 为内存数据库
+	Path string `yaml:"path" toml:"path" env:"DB_PATH"`
+
+	// 连接池，为 0 时按驱动使用默认值
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// 启动时连接失败的重试次数（指数退避）
+	ConnectRetries int `yaml:"connect_retries" toml:"connect_retries" env:"DB_CONNECT_RETRIES"`
+	// 启动时自动执行迁移；为 false 时存在未执行的迁移则拒绝启动
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start" env:"MIGRATE_ON_START"`
+}
+
+type JWTConfig struct {
+	// 签名密钥，不能为空
+	Secret          string   `yaml:"secret" toml:"secret" env:"JWT_SECRET_KEY"`
+	AccessTokenTTL  Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"JWT_ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"JWT_REFRESH_TOKEN_TTL"`
+}
+
+type SchedulerConfig struct {
+	// 定时发布检查间隔
+	Interval Duration `yaml:"interval" toml:"interval" env:"SCHEDULER_INTERVAL"`
+}
+
+type SpamConfig struct {
+	// 允许的链接数
+	MaxLinks int `yaml:"max_links" toml:"max_links" env:"SPAM_MAX_LINKS"`
+	// 违禁词
+	BannedWords []string `yaml:"banned_words" toml:"banned_words" env:"SPAM_BANNED_WORDS"`
+	// 每个用户/IP 每分钟最多评论数，0 表示不限制
+	RateLimit int `yaml:"rate_limit" toml:"rate_limit" env:"SPAM_RATE_LIMIT"`
+	// Akismet 兼容服务，Endpoint 为空时不启用
+	AkismetEndpoint string `yaml:"akismet_endpoint" toml:"akismet_endpoint" env:"AKISMET_ENDPOINT"`
+	AkismetKey      string `yaml:"akismet_key" toml:"akismet_key" env:"AKISMET_KEY"`
+	AkismetBlog     string `yaml:"akismet_blog" toml:"akismet_blog" env:"AKISMET_BLOG"`
+}
+
+type MetricsConfig struct {
+	// 单独的管理端口，如 :9090，设置后 /metrics 只在该端口提供
+	Addr string `yaml:"addr" toml:"addr" env:"METRICS_ADDR"`
+	// 访问 /metrics 需要的 Bearer 令牌；未设置 Addr 时必须设置，否则不提供 /metrics
+	Token string `yaml:"token" toml:"token" env:"METRICS_TOKEN"`
+}
+
+type TracingConfig struct {
+	// none（不采集）/ otlp / stdout / file
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	// OTLP/HTTP 地址，如 http://localhost:4318，为空时使用 OTEL_EXPORTER_OTLP_ENDPOINT 或默认地址
+	Endpoint string `yaml:"endpoint" toml:"endpoint" env:"TRACING_ENDPOINT"`
+	// exporter 为 file 时写入的文件，每行一个 JSON 格式的 span
+	File string `yaml:"file" toml:"file" env:"TRACING_FILE"`
+	// 采样比例 0~1，上游请求已采样时跟随上游
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+// 链路追踪导出方式
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// Duration 支持在配置文件和环境变量中写 "30s"、"1h" 这样的时长
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Default 默认配置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              ":8080",
+			ReadTimeout:       Duration{15 * time.Second},
+			ReadHeaderTimeout: Duration{5 * time.Second},
+			WriteTimeout:      Duration{30 * time.Second},
+			IdleTimeout:       Duration{60 * time.Second},
+			ShutdownTimeout:   Duration{15 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Driver:         DriverMySQL,
+			Host:           "localhost",
+			User:           "root",
+			Name:           "golang_blog",
+			SSLMode:        "disable",
+			TimeZone:       "Asia/Shanghai",
+			Path:           "golang_blog.db",
+			ConnectRetries: 5,
+			MigrateOnStart: true,
+		},
+		JWT: JWTConfig{
+			AccessTokenTTL:  Duration{time.Hour},
+			RefreshTokenTTL: Duration{7 * 24 * time.Hour},
+		},
+		Scheduler: SchedulerConfig{Interval: Duration{30 * time.Second}},
+		Spam:      SpamConfig{MaxLinks: 2, RateLimit: 5},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			File:        "logs/traces.jsonl",
+			SampleRatio: 1,
+			ServiceName: "golang-blog",
+		},
+	}
+}
+
+// 未指定配置文件时依次查找
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Load 加载配置并校验，args 为命令行参数（不含程序名），返回解析 flag 后剩余的参数（子命令）
+//
+//	-config path       配置文件，也可以用 CONFIG_FILE 环境变量指定
+//	-port addr         监听地址
+//	-db-driver name    数据库驱动
+//	-db-dsn dsn        数据库连接字符串
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("blog", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "config file (yaml or toml)")
+	port := fs.String("port", "", "listen address, e.g. :8080")
+	dbDriver := fs.String("db-driver", "", "database driver: mysql, postgres or sqlite")
+	dbDSN := fs.String("db-dsn", "", "database DSN")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+	if err := cfg.loadFile(*configFile); err != nil {
+		return nil, nil, err
+	}
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Server.Port = *port
+		case "db-driver":
+			cfg.Database.Driver = *dbDriver
+		case "db-dsn":
+			cfg.Database.DSN = *dbDSN
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile 读取配置文件，path 为空时查找默认文件，都不存在则跳过
+func (c *Config) loadFile(path string) error {
+	if path == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file: %s (want .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv 按字段的 env 标签用环境变量覆盖配置
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(Duration{}) {
+				if err := applyEnv(field); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid %s=%q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case Duration:
+		var d Duration
+		if err := d.UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	case []string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported config field type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate 校验配置，返回所有问题
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(c.Server.Port != "", "server.port (PORT) must not be empty")
+	check(c.Server.ReadTimeout.Duration >= 0 && c.Server.ReadHeaderTimeout.Duration >= 0 &&
+		c.Server.WriteTimeout.Duration >= 0 && c.Server.IdleTimeout.Duration >= 0,
+		"server timeouts (SERVER_*_TIMEOUT) must not be negative")
+	check(c.Server.ShutdownDelay.Duration >= 0, "server.shutdown_delay (SHUTDOWN_DELAY) must not be negative")
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive")
+	_, supported := dialectors[c.Database.Driver]
+	check(supported, "database.driver (DB_DRIVER) %q is not supported", c.Database.Driver)
+	check(c.Database.Port >= 0 && c.Database.Port <= 65535, "database.port (DB_PORT) %d is out of range", c.Database.Port)
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns (DB_MAX_OPEN_CONNS) must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns (DB_MAX_IDLE_CONNS) must not be negative")
+	check(c.Database.ConnectRetries >= 1, "database.connect_retries (DB_CONNECT_RETRIES) must be at least 1")
+	check(strings.TrimSpace(c.JWT.Secret) != "", "jwt.secret (JWT_SECRET_KEY) must not be empty")
+	check(c.JWT.AccessTokenTTL.Duration > 0, "jwt.access_token_ttl (JWT_ACCESS_TOKEN_TTL) must be positive")
+	check(c.JWT.RefreshTokenTTL.Duration > c.JWT.AccessTokenTTL.Duration,
+		"jwt.refresh_token_ttl (JWT_REFRESH_TOKEN_TTL) must be longer than access_token_ttl")
+	check(c.Scheduler.Interval.Duration > 0, "scheduler.interval (SCHEDULER_INTERVAL) must be positive")
+	check(c.Spam.MaxLinks >= 0, "spam.max_links (SPAM_MAX_LINKS) must not be negative")
+	check(c.Spam.RateLimit >= 0, "spam.rate_limit (SPAM_RATE_LIMIT) must not be negative")
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterOTLP, TracingExporterStdout:
+	case TracingExporterFile:
+		check(c.Tracing.File != "", "tracing.file (TRACING_FILE) must not be empty when exporter is file")
+	default:
+		check(false, "tracing.exporter (TRACING_EXPORTER) %q is not supported (want none, otlp, stdout or file)", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv 清空所有配置相关的环境变量，避免受运行环境影响
+func clearEnv(t *testing.T, v reflect.Value) {
+	t.Helper()
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if name := typ.Field(i).Tag.Get("env"); name != "" {
+			t.Setenv(name, "")
+		} else if field := v.Field(i); field.Kind() == reflect.Struct {
+			clearEnv(t, field)
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t, reflect.ValueOf(Config{}))
+	t.Setenv("CONFIG_FILE", "")
+	path := writeFile(t, "config.yaml", `
+server:
+  port: ":7000"
+  read_timeout: 20s
+database:
+  driver: postgres
+  dsn: file.db
+  max_open_conns: 8
+jwt:
+  secret: from-file
+spam:
+  banned_words: [a, b]
+`)
+	t.Setenv("PORT", ":7001")
+	t.Setenv("DB_MAX_OPEN_CONNS", "16")
+	t.Setenv("SPAM_BANNED_WORDS", " x, ,y ")
+	t.Setenv("JWT_ACCESS_TOKEN_TTL", "30m")
+
+	cfg, rest, err := Load([]string{"-config", path, "-port", ":7002", "-db-dsn", "flag.db", "migrate", "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		// 命令行参数 > 环境变量 > 配置文件 > 默认值
+		{"port", cfg.Server.Port, ":7002"},
+		{"dsn", cfg.Database.DSN, "flag.db"},
+		{"max_open_conns", cfg.Database.MaxOpenConns, 16},
+		{"banned_words", cfg.Spam.BannedWords, []string{"x", "y"}},
+		{"access_token_ttl", cfg.JWT.AccessTokenTTL.Duration, 30 * time.Minute},
+		{"read_timeout", cfg.Server.ReadTimeout.Duration, 20 * time.Second},
+		{"driver", cfg.Database.Driver, DriverPostgres},
+		{"secret", cfg.JWT.Secret, "from-file"},
+		{"write_timeout", cfg.Server.WriteTimeout.Duration, 30 * time.Second},
+		{"args", rest, []string{"migrate", "up"}},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadTOMLFromEnv(t *testing.T) {
+	clearEnv(t, reflect.ValueOf(Config{}))
+	path := writeFile(t, "config.toml", `
+[database]
+driver = "postgres"
+[jwt]
+secret = "toml"
+`)
+	// 配置文件也可以用 CONFIG_FILE 指定
+	t.Setenv("CONFIG_FILE", path)
+	cfg, _, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Driver != DriverPostgres || cfg.JWT.Secret != "toml" {
+		t.Errorf("driver %q, secret %q", cfg.Database.Driver, cfg.JWT.Secret)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	clearEnv(t, reflect.ValueOf(Config{}))
+	t.Setenv("CONFIG_FILE", "")
+	valid := writeFile(t, "config.yaml", "database:\n  driver: postgres\njwt:\n  secret: s\n")
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"missing file", nil, []string{"-config", filepath.Join(t.TempDir(), "none.yaml")}, "read config file"},
+		{"unsupported file", nil, []string{"-config", writeFile(t, "config.json", "{}")}, "unsupported config file"},
+		{"malformed file", nil, []string{"-config", writeFile(t, "config.yaml", "server: [")}, "parse config file"},
+		{"bad env int", map[string]string{"DB_PORT": "abc"}, []string{"-config", valid}, `invalid DB_PORT="abc"`},
+		{"bad env duration", map[string]string{"SCHEDULER_INTERVAL": "soon"}, []string{"-config", valid}, "invalid SCHEDULER_INTERVAL"},
+		{"unknown flag", nil, []string{"-config", valid, "-nope"}, "flag provided but not defined"},
+		{"missing secret", nil, []string{"-config", writeFile(t, "config.yaml", "database:\n  driver: postgres\n")}, "jwt.secret"},
+		{"bad driver", nil, []string{"-config", valid, "-db-driver", "oracle"}, `database.driver (DB_DRIVER) "oracle"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			_, _, err := Load(tc.args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("err = %v, want containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Driver = DriverPostgres
+	cfg.JWT.Secret = "s"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config with secret: %v", err)
+	}
+
+	cfg.Server.Port = ""
+	cfg.Database.Port = 70000
+	cfg.JWT.RefreshTokenTTL = cfg.JWT.AccessTokenTTL
+	cfg.Tracing.Exporter = TracingExporterFile
+	cfg.Tracing.File = ""
+	cfg.Tracing.SampleRatio = 2
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, want := range []string{
+		"server.port", "database.port (DB_PORT) 70000", "jwt.refresh_token_ttl",
+		"tracing.file", "tracing.sample_ratio",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err missing %q:\n%v", want, err)
+		}
+	}
+}
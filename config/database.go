@@ -1,53 +1,105 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
+	"strings"
+	"time"
 
+	"github.com/gavin/blog/migrations"
 	"github.com/gavin/blog/models"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func InitDB() {
-	var err error
-	// 从环境变量获取MySQL连接配置
-	dbHost := GetEnv("DB_HOST", "localhost")
-	dbPort := GetEnv("DB_PORT", "3306")
-	dbUser := GetEnv("DB_USER", "root")
-	dbPassword := GetEnv("DB_PASSWORD", "123456")
-	dbName := GetEnv("DB_NAME", "golang_blog")
+// InitDB 按配置的驱动（mysql / postgres / sqlite）连接数据库，连接失败时指数退避重试
+func InitDB(c *DatabaseConfig) {
+	open, ok := dialectors[c.Driver]
+	if !ok {
+		log.Fatalf("Unsupported database driver: %s", c.Driver)
+	}
+	dsn, err := buildDSN(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	DB, err = openWithRetry(open(dsn), c.ConnectRetries, time.Second)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s database: %v", c.Driver, err)
+	}
+	if err := configurePool(DB, c); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Migrate 启动时检查数据库结构
+// MigrateOnStart 为 true 时自动执行未执行的迁移；为 false 时只检查，存在未执行的迁移则拒绝启动
+func Migrate(c *DatabaseConfig) {
+	if c.MigrateOnStart {
+		if err := migrations.Up(DB, ""); err != nil {
+			log.Fatal("Failed to migrate database: ", err)
+		}
+		return
+	}
+	pending, err := migrations.Pending(DB)
+	if err != nil {
+		log.Fatal("Failed to check database migrations: ", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("Database schema is out of date: %d pending migrations (first: %s_%s), run `go run ./cmd migrate up`",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+}
 
-	// 构建MySQL连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
+// PromoteAdmins 将配置中的用户设为管理员，用于初始化第一个管理员
+func PromoteAdmins(usernames []string) {
+	for _, name := range usernames {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if err := DB.Model(&models.User{}).Where("username = ?", name).Update("role", models.RoleAdmin).Error; err != nil {
+			log.Println("Failed to promote admin:", name, err)
+		}
+	}
+}
 
-	// 连接MySQL数据库
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// PingDB 检查数据库连接是否可用，用于就绪检查
+func PingDB(ctx context.Context) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
 
+// CheckSchema 检查数据库结构是否为最新，存在未执行的迁移时返回错误
+func CheckSchema(ctx context.Context) error {
+	pending, err := migrations.Pending(DB.WithContext(ctx))
 	if err != nil {
-		log.Fatal("Failed to connect to MySQL database:", err)
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations (first: %s_%s)", len(pending), pending[0].Version, pending[0].Name)
 	}
+	return nil
 }
 
-func Migrate() {
-	DB.AutoMigrate(&models.User{})
-	DB.AutoMigrate(&models.Post{})
-	DB.AutoMigrate(&models.Comment{})
+// CloseDB 关闭数据库连接池，退出时在后台任务停止之后调用
+func CloseDB() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
 
 // GetDB 获取数据库连接实例
 func GetDB() *gorm.DB {
 	return DB
 }
-
-func GetEnv(name string, defaultValue string) string {
-	value := os.Getenv(name)
-	if len(value) == 0 {
-		return defaultValue
-	}
-	return value
-}
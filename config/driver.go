@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// 支持的数据库驱动，sqlite 在 driver_sqlite.go 中注册（可用 -tags nosqlite 去掉）
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+var dialectors = map[string]func(dsn string) gorm.Dialector{
+	DriverMySQL:    mysql.Open,
+	DriverPostgres: postgres.Open,
+}
+
+// buildDSN 按驱动拼接连接字符串，设置了 DSN 时直接使用
+func buildDSN(c *DatabaseConfig) (string, error) {
+	if c.DSN != "" {
+		return c.DSN, nil
+	}
+	switch c.Driver {
+	case DriverMySQL:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			c.User, c.Password, c.Host, portOrDefault(c.Port, 3306), c.Name), nil
+	case DriverPostgres:
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+			c.Host, portOrDefault(c.Port, 5432), c.User, c.Password, c.Name, c.SSLMode, c.TimeZone), nil
+	case DriverSQLite:
+		// 纯 Go 实现，不需要数据库服务
+		return c.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", nil
+	}
+	return "", fmt.Errorf("unsupported database driver: %s", c.Driver)
+}
+
+func portOrDefault(port, defaultPort int) int {
+	if port == 0 {
+		return defaultPort
+	}
+	return port
+}
+
+// openWithRetry 连接数据库，失败时按指数退避重试
+func openWithRetry(dialector gorm.Dialector, attempts int, backoff time.Duration) (*gorm.DB, error) {
+	var lastErr error
+	for i := 1; i <= attempts; i++ {
+		// TranslateError 把各数据库的唯一索引冲突统一转换为 gorm.ErrDuplicatedKey
+		db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+		if err == nil {
+			return db, nil
+		}
+		lastErr = err
+		if i == attempts {
+			break
+		}
+		log.Printf("Failed to connect database (%d/%d): %v, retry in %s", i, attempts, err, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+	return nil, lastErr
+}
+
+// configurePool 设置连接池参数，未配置的项按驱动取默认值：
+// 最大连接数 25（sqlite 为 1，避免写锁冲突），最大空闲连接数 10，
+// 连接最长存活 30m、最长空闲 5m（sqlite 不限制，内存数据库随连接关闭而丢失）
+func configurePool(db *gorm.DB, c *DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	maxOpen, maxIdle, lifetime, idleTime := 25, 10, 30*time.Minute, 5*time.Minute
+	if c.Driver == DriverSQLite {
+		maxOpen, lifetime, idleTime = 1, 0, 0
+	}
+	if c.MaxOpenConns > 0 {
+		maxOpen = c.MaxOpenConns
+	}
+	if c.MaxIdleConns > 0 {
+		maxIdle = c.MaxIdleConns
+	}
+	if c.ConnMaxLifetime.Duration > 0 {
+		lifetime = c.ConnMaxLifetime.Duration
+	}
+	if c.ConnMaxIdleTime.Duration > 0 {
+		idleTime = c.ConnMaxIdleTime.Duration
+	}
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(lifetime)
+	sqlDB.SetConnMaxIdleTime(idleTime)
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !nosqlite
+
+package config
+
+import "github.com/glebarez/sqlite"
+
+func init() {
+	dialectors[DriverSQLite] = sqlite.Open
+}
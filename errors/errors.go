@@ -4,10 +4,20 @@ const (
 	INVALID_PARAMETER int = 1001 + iota // 参数错误
 	SYSTEM_ERROR                        //系统错误
 	OTHER_ERROR
+	VERSION_CONFLICT     // 数据已被他人修改（乐观锁冲突）
+	ALREADY_EXISTS       // 数据已存在（名称等唯一字段重复）
+	INVALID_PRECONDITION // If-Match 等前置条件格式错误
 )
 
 const (
 	AUTH_ERROR int = 2001 + iota
 	POST_ERROR
 	COMMENT_ERROR
+	PERMISSION_DENIED // 权限不足
+	USER_ERROR
+	POST_STATUS_ERROR // 文章状态不允许该操作
+	TAG_ERROR
+	CATEGORY_ERROR
+	SEARCH_ERROR
+	REVISION_ERROR
 )
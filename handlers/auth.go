@@ -1,16 +1,23 @@
 package handlers
 
 import (
-	"github.com/gavin/blog/config"
+	stderrors "errors"
+	"time"
+
 	"github.com/gavin/blog/errors"
 	"github.com/gavin/blog/logger"
-	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
 	"github.com/gavin/blog/utils"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
 )
 
-type AuthHandler struct{}
+type AuthHandler struct {
+	users service.UserService
+}
+
+func NewAuthHandler(users service.UserService) *AuthHandler {
+	return &AuthHandler{users: users}
+}
 
 type RegisterRequest struct {
 	*utils.FieldValidate
@@ -26,9 +33,23 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required,min=6" label:"密码"`
 }
 
+type RefreshRequest struct {
+	*utils.FieldValidate
+	RefreshToken string `json:"refresh_token" binding:"required" label:"刷新令牌"`
+}
+
+type LogoutRequest struct {
+	*utils.FieldValidate
+	// 可选：同时撤销客户端持有的刷新令牌
+	RefreshToken string `json:"refresh_token"`
+}
+
 type AuthResponse struct {
-	Username string `json:"username"`
-	Token    string `json:"token"`
+	Username              string    `json:"username"`
+	Token                 string    `json:"token"`
+	TokenExpiresAt        time.Time `json:"token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -40,28 +61,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := config.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		utils.Fail(c, errors.AUTH_ERROR, "user not found")
+	tokens, err := h.users.Login(c.Request.Context(), req.Username, req.Password)
+	switch {
+	case stderrors.Is(err, service.ErrUserNotFound), stderrors.Is(err, service.ErrPasswordIncorrect):
+		utils.Fail(c, errors.AUTH_ERROR, err.Error())
 		return
-	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		utils.Fail(c, errors.AUTH_ERROR, "password incorrect")
-		return
-	}
-
-	token, err := utils.GenerateToken(uint64(user.ID), user.Username)
-
-	if err != nil {
-		logger.Log.Errorf("generate token err: %v", err)
+	case err != nil:
+		logger.Log.WithContext(c.Request.Context()).Errorf("generate token err: %v", err)
 		utils.Fail(c, errors.AUTH_ERROR, "generate token failed")
 		return
 	}
 
-	utils.Success(c, &AuthResponse{
-		Username: user.Username,
-		Token:    token,
-	}, "")
+	utils.Success(c, toAuthResponse(tokens), "")
 	return
 }
 
@@ -74,53 +85,97 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	if req.Password != req.Password {
+	if req.Password != req.RepeatPassword {
 		utils.Fail(c, errors.AUTH_ERROR, "two password not match")
 		return
 	}
 
-	var existUser models.User
-	config.DB.Where("username = ?", req.Username).First(&existUser)
-	if existUser.ID != 0 {
-		utils.Fail(c, errors.AUTH_ERROR, "username is exist")
+	tokens, err := h.users.Register(c.Request.Context(), service.RegisterInput{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+	})
+	switch {
+	case stderrors.Is(err, service.ErrUsernameTaken), stderrors.Is(err, service.ErrEmailTaken):
+		utils.Fail(c, errors.AUTH_ERROR, err.Error())
+		return
+	case err != nil:
+		logger.Log.WithContext(c.Request.Context()).Errorf("register err: %v", err)
+		utils.Error(c, "create user fail")
 		return
 	}
+	utils.Success(c, toAuthResponse(tokens), "register success")
+	return
+}
 
-	config.DB.Where("email = ?", req.Email).First(&existUser)
-	if existUser.ID != 0 {
-		utils.Fail(c, errors.AUTH_ERROR, "email is exist")
+// Refresh 用刷新令牌换取新的访问令牌，同时轮换刷新令牌
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		logger.Log.Infof("hash err: %v", err)
-		utils.Error(c, "bcrypt password err")
+	tokens, err := h.users.Refresh(c.Request.Context(), req.RefreshToken)
+	switch {
+	case stderrors.Is(err, service.ErrRefreshTokenReused):
+		utils.Fail(c, errors.AUTH_ERROR, "refresh token reused, please login again")
+		return
+	case stderrors.Is(err, service.ErrInvalidRefreshToken), stderrors.Is(err, service.ErrRefreshTokenExpired),
+		stderrors.Is(err, service.ErrUserNotFound):
+		utils.Fail(c, errors.AUTH_ERROR, err.Error())
+		return
+	case err != nil:
+		logger.Log.WithContext(c.Request.Context()).Errorf("refresh token err: %v", err)
+		utils.Fail(c, errors.AUTH_ERROR, "refresh token failed")
 		return
 	}
 
-	user := models.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hashedPassword),
+	utils.Success(c, toAuthResponse(tokens), "")
+}
+
+// Logout 退出登录：撤销当前访问令牌，以及请求中携带的刷新令牌所在的家族
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var validate utils.FieldValidateIF = req
+			msg := validate.Validate(err, req)
+			utils.Fail(c, errors.INVALID_PARAMETER, msg)
+			return
+		}
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		logger.Log.Infof("create user err: %v", err)
-		utils.Error(c, "create user fail")
+	err := h.users.Logout(c.Request.Context(), c.GetUint64("user_id"), c.GetString("jti"),
+		c.GetTime("token_expires_at"), req.RefreshToken)
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Errorf("revoke token err: %v", err)
+		utils.Error(c, "logout failed")
 		return
 	}
 
-	token, err := utils.GenerateToken(uint64(user.ID), user.Username)
+	utils.Success(c, "", "logout success")
+}
 
-	if err != nil {
-		logger.Log.Errorf("generate token err: %v", err)
-		utils.Fail(c, errors.AUTH_ERROR, "generate token failed")
+// LogoutAll 退出所有会话：撤销该用户此前签发的全部访问令牌和刷新令牌
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.users.LogoutAll(c.Request.Context(), c.GetUint64("user_id")); err != nil {
+		logger.Log.WithContext(c.Request.Context()).Errorf("revoke user tokens err: %v", err)
+		utils.Error(c, "logout failed")
 		return
 	}
-	utils.Success(c, &AuthResponse{
-		Username: user.Username,
-		Token:    token,
-	}, "register success")
-	return
+	utils.Success(c, "", "logout success")
+}
+
+func toAuthResponse(tokens *service.Tokens) *AuthResponse {
+	return &AuthResponse{
+		Username:              tokens.Username,
+		Token:                 tokens.Token,
+		TokenExpiresAt:        tokens.TokenExpiresAt,
+		RefreshToken:          tokens.RefreshToken,
+		RefreshTokenExpiresAt: tokens.RefreshTokenExpiresAt,
+	}
 }
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterRequiresMatchingPasswords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repos := repository.NewMemoryRepositories()
+	router := gin.New()
+	router.POST("/register", NewAuthHandler(service.New(repos, nil, nil, nil).Users).Register)
+
+	cases := []struct {
+		name    string
+		repeat  string
+		code    int
+		created bool
+	}{
+		{"mismatch", "secret2", errors.AUTH_ERROR, false},
+		{"match", "secret1", 0, true},
+	}
+	for _, tc := range cases {
+		body := `{"username":"alice","email":"alice@example.com","password":"secret1","repeat_password":"` + tc.repeat + `"}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body)))
+		var resp utils.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v: %s", tc.name, err, w.Body)
+		}
+		if resp.Code != tc.code {
+			t.Errorf("%s: code = %d (%s), want %d", tc.name, resp.Code, resp.Msg, tc.code)
+		}
+		_, err := repos.Users.FindByUsername(context.Background(), "alice")
+		if created := err == nil; created != tc.created {
+			t.Errorf("%s: user created = %v, want %v", tc.name, created, tc.created)
+		}
+	}
+}
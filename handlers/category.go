@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	taxonomy service.TaxonomyService
+}
+
+func NewCategoryHandler(taxonomy service.TaxonomyService) *CategoryHandler {
+	return &CategoryHandler{taxonomy: taxonomy}
+}
+
+type CreateCategoryRequest struct {
+	*utils.FieldValidate
+	Name     string  `json:"name" binding:"required,min=1,max=50" label:"分类名"`
+	ParentID *uint64 `json:"parent_id"`
+}
+
+type UpdateCategoryRequest struct {
+	*utils.FieldValidate
+	ID   uint   `json:"id" binding:"required"`
+	Name string `json:"name" binding:"required,min=1,max=50" label:"分类名"`
+	// 不传保持原上级，传 0 改为顶级分类
+	ParentID *uint64 `json:"parent_id"`
+}
+
+// ListCategories 返回分类树
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	categories, err := h.taxonomy.ListCategories(c.Request.Context())
+	if err != nil {
+		failTaxonomy(c, err, errors.CATEGORY_ERROR, "查询失败")
+		return
+	}
+	utils.Success(c, buildCategoryTree(categories), "")
+}
+
+func (h *CategoryHandler) AddCategory(c *gin.Context) {
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	category, err := h.taxonomy.CreateCategory(c.Request.Context(), currentActor(c), service.CategoryInput{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+	})
+	if err != nil {
+		failTaxonomy(c, err, errors.CATEGORY_ERROR, "添加分类失败")
+		return
+	}
+	utils.Success(c, category, "添加成功")
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	category, err := h.taxonomy.UpdateCategory(c.Request.Context(), currentActor(c), service.CategoryInput{
+		ID:       uint64(req.ID),
+		Name:     req.Name,
+		ParentID: req.ParentID,
+	})
+	if err != nil {
+		failTaxonomy(c, err, errors.CATEGORY_ERROR, "修改分类失败")
+		return
+	}
+	utils.Success(c, category, "修改成功")
+}
+
+// DeleteCategory 删除分类：子分类上移到被删除分类的上级，文章的分类置空
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	if err := h.taxonomy.DeleteCategory(c.Request.Context(), currentActor(c), paramID(c)); err != nil {
+		failTaxonomy(c, err, errors.CATEGORY_ERROR, "删除失败")
+		return
+	}
+	utils.Success(c, "", "删除成功")
+}
+
+// buildCategoryTree 将平铺的分类组装成树
+func buildCategoryTree(categories []models.Category) []models.Category {
+	children := make(map[uint64][]int)
+	var roots []int
+	for i, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, i)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], i)
+		}
+	}
+
+	var build func(i int, depth int) models.Category
+	build = func(i int, depth int) models.Category {
+		node := categories[i]
+		// 防御脏数据形成的环
+		if depth > len(categories) {
+			return node
+		}
+		for _, child := range children[uint64(node.ID)] {
+			node.Children = append(node.Children, build(child, depth+1))
+		}
+		return node
+	}
+
+	tree := make([]models.Category, 0, len(roots))
+	for _, i := range roots {
+		tree = append(tree, build(i, 0))
+	}
+	return tree
+}
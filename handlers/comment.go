@@ -1,20 +1,38 @@
 package handlers
 
 import (
-	"github.com/gavin/blog/config"
+	stderrors "errors"
+
 	"github.com/gavin/blog/errors"
 	"github.com/gavin/blog/logger"
 	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
 	"github.com/gavin/blog/utils"
 	"github.com/gin-gonic/gin"
 )
 
-type CommentHandle struct{}
+type CommentHandle struct {
+	comments service.CommentService
+}
+
+func NewCommentHandle(comments service.CommentService) *CommentHandle {
+	return &CommentHandle{comments: comments}
+}
 
 type CreateCommentRequest struct {
 	*utils.FieldValidate
 	PostID  uint64 `json:"post_id" binding:"required"`
 	Content string `json:"content" binding:"required,min=1"`
+	// 蜜罐字段，前端隐藏，正常用户不会填写
+	Website string `json:"website"`
+}
+
+type ReplyCommentRequest struct {
+	*utils.FieldValidate
+	ParentID uint64 `json:"parent_id" binding:"required"`
+	Content  string `json:"content" binding:"required,min=1"`
+	// 蜜罐字段，前端隐藏，正常用户不会填写
+	Website string `json:"website"`
 }
 
 type UpdateCommentRequest struct {
@@ -22,6 +40,8 @@ type UpdateCommentRequest struct {
 	ID      int    `json:"id" binding:"required"`
 	PostID  uint64 `json:"post_id" binding:"required"`
 	Content string `json:"content" binding:"required,min=1"`
+	// 客户端读取时的版本号，也可以通过 If-Match 请求头传递
+	Version int `json:"version"`
 }
 
 type QueryCommentsRequest struct {
@@ -29,8 +49,24 @@ type QueryCommentsRequest struct {
 	utils.Pagination
 	PostID uint64 `json:"post_id"`
 	UserId int    `json:"user_id"`
+	// 返回格式：flat（默认，平铺列表）或 tree（顶层评论分页，内嵌前 N 条回复）
+	Mode string `json:"mode" binding:"omitempty,oneof=flat tree"`
+	// tree 模式下每条顶层评论内嵌的回复数，默认 3
+	Replies int `json:"replies" binding:"omitempty,min=1,max=20"`
+}
+
+// QueryRepliesRequest 加载更多回复（GET /comment/:id/replies?cursor=&limit=）
+type QueryRepliesRequest struct {
+	Cursor uint64 `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
 }
 
+const (
+	commentModeTree        = "tree"
+	defaultEmbeddedReplies = 3
+	defaultRepliesLimit    = 10
+)
+
 func (h *CommentHandle) GetPageComments(c *gin.Context) {
 	// 获取分页
 	var req QueryCommentsRequest
@@ -41,47 +77,109 @@ func (h *CommentHandle) GetPageComments(c *gin.Context) {
 		return
 	}
 
-	var posts []models.Comment
-	query := config.DB.Model(&models.Comment{})
-	if req.UserId > 0 {
-		query.Where("user_id = ?", req.UserId)
+	actor := currentActor(c)
+	// 树形模式只对顶层评论分页，回复内嵌在各自的上级评论里
+	tree := req.Mode == commentModeTree
+	comments, paginatedResult, err := h.comments.List(c.Request.Context(), actor, service.CommentQuery{
+		Pagination: req.Pagination,
+		PostID:     req.PostID,
+		UserID:     uint64(req.UserId),
+		TopLevel:   tree,
+	})
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.COMMENT_ERROR, "查询失败")
+		return
 	}
-	if req.PostID > 0 {
-		query.Where("post_id = ?", req.PostID)
+	if tree {
+		replies := req.Replies
+		if replies <= 0 {
+			replies = defaultEmbeddedReplies
+		}
+		threads, err := h.comments.Threads(c.Request.Context(), actor, comments, replies)
+		if err != nil {
+			logger.Log.WithContext(c.Request.Context()).Error(err)
+			utils.Fail(c, errors.COMMENT_ERROR, "查询失败")
+			return
+		}
+		paginatedResult.Data = toCommentNodes(actor, threads)
+		utils.Success(c, paginatedResult, "")
+		return
+	}
+	paginatedResult.Data = toCommentResponses(actor, comments)
+	utils.Success(c, paginatedResult, "")
+	return
+}
+
+// GetPostComments 分页查询某篇文章的评论（GET /post/:id/comments?page=1&page_size=10）
+func (h *CommentHandle) GetPostComments(c *gin.Context) {
+	var pagination utils.Pagination
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		utils.Fail(c, errors.INVALID_PARAMETER, "分页参数错误")
+		return
 	}
-	paginatedResult, err := utils.GetPaginatedData(query, &posts, &req.Pagination)
 
+	actor := currentActor(c)
+	comments, paginatedResult, err := h.comments.ListByPost(c.Request.Context(), actor, paramID(c), pagination)
+	if stderrors.Is(err, service.ErrPostNotFound) {
+		utils.Fail(c, errors.COMMENT_ERROR, "文章不存在")
+		return
+	}
 	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
 		utils.Fail(c, errors.COMMENT_ERROR, "查询失败")
 		return
 	}
-	paginatedResult.Data = posts
+	paginatedResult.Data = toCommentResponses(actor, comments)
 	utils.Success(c, paginatedResult, "")
-	return
+}
+
+// GetReplies 按游标加载某条评论的直接回复
+func (h *CommentHandle) GetReplies(c *gin.Context) {
+	var req QueryRepliesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.Fail(c, errors.INVALID_PARAMETER, "cursor 或 limit 参数错误")
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultRepliesLimit
+	}
+
+	actor := currentActor(c)
+	threads, nextCursor, err := h.comments.Replies(c.Request.Context(), actor, paramID(c), req.Cursor, req.Limit)
+	if err != nil {
+		failComment(c, err, "查询失败")
+		return
+	}
+	utils.Success(c, gin.H{"data": toCommentNodes(actor, threads), "next_cursor": nextCursor}, "")
 }
 
 func (h *CommentHandle) GetUserComment(c *gin.Context) {
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.COMMENT_ERROR, "用户未登录")
 		return
 	}
-	var posts []models.Comment
-	config.DB.Where("user_id", userId).Preload("Comments").Find(&posts)
+	comments, err := h.comments.ListByUser(c.Request.Context(), actor.UserID)
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.COMMENT_ERROR, "查询失败")
+		return
+	}
 
-	utils.Success(c, posts, "")
+	utils.Success(c, toCommentResponses(actor, comments), "")
 	return
 }
 
 func (h *CommentHandle) GetComment(c *gin.Context) {
-	id := c.Param("id")
-	var post models.Comment
-	if err := config.DB.Where("id", id).Preload("Post").First(&post).Error; err != nil {
-		utils.Fail(c, errors.COMMENT_ERROR, "评论没找到")
+	actor := currentActor(c)
+	comment, err := h.comments.Get(c.Request.Context(), actor, paramID(c))
+	if err != nil {
+		failComment(c, err, "查询失败")
 		return
 	}
-	utils.Success(c, post, "")
-	return
+	utils.SetETag(c, comment.Version)
+	utils.Success(c, toCommentResponse(actor, comment), "")
 }
 
 func (h *CommentHandle) AddComment(c *gin.Context) {
@@ -93,28 +191,37 @@ func (h *CommentHandle) AddComment(c *gin.Context) {
 		utils.Fail(c, errors.INVALID_PARAMETER, msg)
 		return
 	}
-	userId, exists := c.Get("user_id")
-	if !exists {
-		utils.Fail(c, errors.COMMENT_ERROR, "用户未登录")
-		return
-	}
-	var existPost models.Post
-	if err := config.DB.First(&existPost, req.PostID).Error; err != nil {
-		utils.Fail(c, errors.COMMENT_ERROR, "文章不存在")
+	h.createComment(c, service.CommentInput{PostID: req.PostID, Content: req.Content, Honeypot: req.Website}, "添加评论失败")
+}
+
+// ReplyComment 回复评论，回复与上级评论属于同一篇文章，嵌套层数不超过 MaxCommentDepth
+func (h *CommentHandle) ReplyComment(c *gin.Context) {
+	var req ReplyCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
 		return
 	}
+	h.createComment(c, service.CommentInput{ParentID: req.ParentID, Content: req.Content, Honeypot: req.Website}, "回复失败")
+}
 
-	comment := &models.Comment{
-		Content: req.Content,
-		UserID:  userId.(uint64),
-		PostID:  req.PostID,
+// createComment 发表评论或回复，附带垃圾检测需要的请求信息
+func (h *CommentHandle) createComment(c *gin.Context, input service.CommentInput, message string) {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
+		utils.Fail(c, errors.COMMENT_ERROR, "用户未登录")
+		return
 	}
-	if err := config.DB.Create(&comment).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.COMMENT_ERROR, "添加评论失败")
+	input.IP = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
+	input.Referrer = c.Request.Referer()
+	comment, err := h.comments.Create(c.Request.Context(), actor, input)
+	if err != nil {
+		failComment(c, err, message)
 		return
 	}
-	utils.Success(c, "", "添加成功")
+	utils.Success(c, gin.H{"id": comment.ID, "status": commentStatus(actor, comment)}, commentCreatedMessage(comment))
 }
 
 func (h *CommentHandle) UpdateComment(c *gin.Context) {
@@ -127,53 +234,88 @@ func (h *CommentHandle) UpdateComment(c *gin.Context) {
 		return
 	}
 
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.COMMENT_ERROR, "用户未登录")
 		return
 	}
 
-	var existPost models.Post
-	if err := config.DB.First(&existPost, req.PostID).Error; err != nil {
-		utils.Fail(c, errors.COMMENT_ERROR, "文章不存在")
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
 		return
 	}
 
-	var existComment models.Comment
-
-	if err := config.DB.Where("user_id", userId).Where("post_id", req.PostID).First(&existComment, "id = ?", req.ID).Error; err != nil {
-		utils.Fail(c, errors.COMMENT_ERROR, "评论不存在")
+	// 拥有 comment:moderate 权限的用户可以修改任意评论
+	comment, err := h.comments.Update(c.Request.Context(), actor, service.UpdateCommentInput{
+		ID:        uint64(req.ID),
+		PostID:    req.PostID,
+		Content:   req.Content,
+		Version:   version,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Request.Referer(),
+	})
+	if err != nil {
+		failComment(c, err, "修改评论失败")
 		return
 	}
-
-	existComment.Content = req.Content
-
-	if err := config.DB.Save(&existComment).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.COMMENT_ERROR, "修改评论失败")
-		return
+	utils.SetETag(c, comment.Version)
+	message := "修改评论成功"
+	if comment.Status == models.CommentStatusPending || comment.Status == models.CommentStatusSpam {
+		message = "评论已修改，等待审核"
 	}
-	utils.Success(c, "", "修改评论成功")
+	utils.Success(c, gin.H{"id": comment.ID, "version": comment.Version, "status": commentStatus(actor, comment)}, message)
 }
 
 func (h *CommentHandle) DeleteComment(c *gin.Context) {
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.COMMENT_ERROR, "用户未登录")
 		return
 	}
-	id := c.Param("id")
-	var existComment models.Comment
-	if err := config.DB.Where("user_id", userId).Where("id", id).First(&existComment).Error; err != nil {
-		utils.Fail(c, errors.COMMENT_ERROR, "评论没找到")
-		return
-	}
-	if err := config.DB.Delete(&existComment).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.COMMENT_ERROR, "删除失败")
+	// 还有回复时只清空内容保留占位，不删除其他用户的回复
+	if _, err := h.comments.Delete(c.Request.Context(), actor, paramID(c)); err != nil {
+		failComment(c, err, "删除失败")
 		return
 	}
 
 	utils.Success(c, "", "删除成功")
 	return
 }
+
+// failComment 按评论服务返回的错误写入响应，其他错误记录日志并返回 message
+func failComment(c *gin.Context, err error, message string) {
+	var conflict *service.ConflictError
+	switch {
+	case stderrors.As(err, &conflict):
+		utils.SetETag(c, conflict.Current)
+		utils.FailWithData(c, errors.VERSION_CONFLICT, "评论已被他人修改，请刷新后重试", gin.H{
+			"id":              conflict.ID,
+			"current_version": conflict.Current,
+		})
+	case stderrors.Is(err, service.ErrPermissionDenied):
+		utils.Fail(c, errors.PERMISSION_DENIED, err.Error())
+	case stderrors.Is(err, service.ErrPostNotFound):
+		utils.Fail(c, errors.COMMENT_ERROR, "文章不存在")
+	case stderrors.Is(err, service.ErrCommentNotFound):
+		utils.Fail(c, errors.COMMENT_ERROR, "评论不存在")
+	case stderrors.Is(err, service.ErrPostNotPublished):
+		utils.Fail(c, errors.COMMENT_ERROR, "文章未发布")
+	case stderrors.Is(err, service.ErrCommentNotApproved):
+		utils.Fail(c, errors.COMMENT_ERROR, "评论未通过审核")
+	case stderrors.Is(err, service.ErrReplyTooDeep):
+		utils.Fail(c, errors.COMMENT_ERROR, "回复层数已达上限")
+	default:
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.COMMENT_ERROR, message)
+	}
+}
+
+// commentCreatedMessage 新评论的提示信息，待审核时告知用户
+func commentCreatedMessage(comment *models.Comment) string {
+	// 垃圾评论同样提示等待审核，不暴露检测结果
+	if comment.Status == models.CommentStatusPending || comment.Status == models.CommentStatusSpam {
+		return "评论已提交，等待审核"
+	}
+	return "添加成功"
+}
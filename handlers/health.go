@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gavin/blog/health"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct{}
+
+// Live 存活探针：进程能处理请求即返回 200，不检查数据库等依赖，避免依赖故障时被反复重启
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready 就绪探针：启动完成前和退出过程中直接返回 503，否则执行已注册的检查，任一失败返回 503 和各项详情
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if !health.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, health.Report{Status: health.StatusNotReady, Checks: []health.Result{}})
+		return
+	}
+	report := health.Default.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
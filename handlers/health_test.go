@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavin/blog/health"
+	"github.com/gin-gonic/gin"
+)
+
+func TestReady(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", (&HealthHandler{}).Ready)
+
+	old := health.Default
+	health.Default = health.NewRegistry()
+	t.Cleanup(func() {
+		health.Default = old
+		health.SetReady(false)
+	})
+	var failing error
+	health.Register("db", 0, func(context.Context) error { return failing })
+
+	cases := []struct {
+		name    string
+		ready   bool
+		failing error
+		status  int
+		checks  int
+	}{
+		{"starting", false, nil, http.StatusServiceUnavailable, 0},
+		{"ready", true, nil, http.StatusOK, 1},
+		{"check failed", true, errors.New("down"), http.StatusServiceUnavailable, 1},
+	}
+	for _, tc := range cases {
+		health.SetReady(tc.ready)
+		failing = tc.failing
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report health.Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v: %s", tc.name, err, w.Body)
+		}
+		if w.Code != tc.status || len(report.Checks) != tc.checks {
+			t.Errorf("%s: %d with %d checks, want %d with %d", tc.name, w.Code, len(report.Checks), tc.status, tc.checks)
+		}
+	}
+}
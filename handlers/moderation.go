@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"io"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	comments service.CommentService
+}
+
+func NewModerationHandler(comments service.CommentService) *ModerationHandler {
+	return &ModerationHandler{comments: comments}
+}
+
+// UpdateModerationSettingRequest 不传的字段保持不变
+type UpdateModerationSettingRequest struct {
+	*utils.FieldValidate
+	HoldAll            *bool `json:"hold_all"`
+	HoldFirstTime      *bool `json:"hold_first_time"`
+	AutoApproveTrusted *bool `json:"auto_approve_trusted"`
+	TrustedThreshold   *int  `json:"trusted_threshold" binding:"omitempty,min=0,max=1000"`
+}
+
+type QueryModerationRequest struct {
+	*utils.FieldValidate
+	utils.Pagination
+	// 默认查询待审核的评论
+	Status string `json:"status" binding:"omitempty,oneof=pending approved rejected spam"`
+	PostID uint64 `json:"post_id"`
+}
+
+type ModerateCommentsRequest struct {
+	*utils.FieldValidate
+	IDs []uint64 `json:"ids" binding:"required,min=1,max=100,dive,gt=0" label:"评论ID"`
+}
+
+type RecountCommentsRequest struct {
+	*utils.FieldValidate
+	PostIDs []uint64 `json:"post_ids" binding:"omitempty,max=1000,dive,gt=0" label:"文章ID"`
+}
+
+// GetSettings 查询全局评论审核设置
+func (h *ModerationHandler) GetSettings(c *gin.Context) {
+	setting, err := h.comments.ModerationSetting(c.Request.Context(), currentActor(c))
+	if err != nil {
+		failComment(c, err, "查询审核设置失败")
+		return
+	}
+	utils.Success(c, setting, "")
+}
+
+// UpdateSettings 修改全局评论审核设置
+func (h *ModerationHandler) UpdateSettings(c *gin.Context) {
+	var req UpdateModerationSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	setting, err := h.comments.UpdateModerationSetting(c.Request.Context(), currentActor(c), service.ModerationSettingInput{
+		HoldAll:            req.HoldAll,
+		HoldFirstTime:      req.HoldFirstTime,
+		AutoApproveTrusted: req.AutoApproveTrusted,
+		TrustedThreshold:   req.TrustedThreshold,
+	})
+	if err != nil {
+		failComment(c, err, "修改审核设置失败")
+		return
+	}
+	utils.Success(c, setting, "修改成功")
+}
+
+// QueryComments 审核队列，按状态分页查询评论
+func (h *ModerationHandler) QueryComments(c *gin.Context) {
+	var req QueryModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	actor := currentActor(c)
+	comments, paginatedResult, err := h.comments.ModerationQueue(c.Request.Context(), actor, service.ModerationQuery{
+		Pagination: req.Pagination,
+		Status:     req.Status,
+		PostID:     req.PostID,
+	})
+	if err != nil {
+		failComment(c, err, "查询失败")
+		return
+	}
+	paginatedResult.Data = toCommentResponses(actor, comments)
+	utils.Success(c, paginatedResult, "")
+}
+
+// Approve 批量通过评论
+func (h *ModerationHandler) Approve(c *gin.Context) {
+	h.moderateComments(c, models.CommentStatusApproved)
+}
+
+// Reject 批量拒绝评论
+func (h *ModerationHandler) Reject(c *gin.Context) {
+	h.moderateComments(c, models.CommentStatusRejected)
+}
+
+// MarkSpam 批量标记为垃圾评论
+func (h *ModerationHandler) MarkSpam(c *gin.Context) {
+	h.moderateComments(c, models.CommentStatusSpam)
+}
+
+// moderateComments 批量修改评论状态
+func (h *ModerationHandler) moderateComments(c *gin.Context, status string) {
+	var req ModerateCommentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	updated, err := h.comments.Moderate(c.Request.Context(), currentActor(c), req.IDs, status)
+	if err != nil {
+		failComment(c, err, "审核失败")
+		return
+	}
+	utils.Success(c, gin.H{"status": status, "updated": updated}, "审核成功")
+}
+
+// RecountComments 按评论表重新统计文章评论数，post_ids 为空时统计全部文章
+func (h *ModerationHandler) RecountComments(c *gin.Context) {
+	var req RecountCommentsRequest
+	// 允许不带请求体
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+	updated, err := h.comments.Recount(c.Request.Context(), req.PostIDs...)
+	if err != nil {
+		failComment(c, err, "重新统计失败")
+		return
+	}
+	logger.Log.WithContext(c.Request.Context()).Infof("comment counts recomputed | posts: %d, user_id: %v", updated, c.GetUint64("user_id"))
+	utils.Success(c, gin.H{"updated": updated}, "重新统计成功")
+}
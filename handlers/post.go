@@ -1,21 +1,34 @@
 package handlers
 
 import (
-	"github.com/gavin/blog/config"
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
 	"github.com/gavin/blog/errors"
 	"github.com/gavin/blog/logger"
 	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
 	"github.com/gavin/blog/utils"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-type PostHandler struct{}
+type PostHandler struct {
+	posts service.PostService
+}
+
+func NewPostHandler(posts service.PostService) *PostHandler {
+	return &PostHandler{posts: posts}
+}
 
 type CreatePostRequest struct {
 	*utils.FieldValidate
-	Title   string `json:"title" binding:"required,min=1,max=200"`
-	Content string `json:"content" binding:"required,min=1"`
+	Title      string   `json:"title" binding:"required,min=1,max=200"`
+	Content    string   `json:"content" binding:"required,min=1"`
+	Tags       []string `json:"tags" binding:"omitempty,max=10,dive,max=50"`
+	CategoryID *uint64  `json:"category_id"`
 }
 
 type UpdatePostRequest struct {
@@ -23,12 +36,36 @@ type UpdatePostRequest struct {
 	ID      int    `json:"id" binding:"required"`
 	Title   string `json:"title" binding:"required,min=1,max=200"`
 	Content string `json:"content" binding:"required,min=1"`
+	// 不传表示不修改标签，传空数组表示清空
+	Tags []string `json:"tags" binding:"omitempty,max=10,dive,max=50"`
+	// 不传或 null 表示不修改分类，传 0 表示清空
+	CategoryID *uint64 `json:"category_id"`
+	// 客户端读取时的版本号，也可以通过 If-Match 请求头传递
+	Version int `json:"version"`
+}
+
+type SchedulePostRequest struct {
+	*utils.FieldValidate
+	PublishAt time.Time `json:"publish_at" binding:"required" label:"发布时间"`
+}
+
+type CommentModerationRequest struct {
+	*utils.FieldValidate
+	// inherit 使用全局设置，open 评论直接通过，hold 评论需要审核
+	Mode string `json:"mode" binding:"required,oneof=inherit open hold" label:"审核方式"`
 }
 
 type QueryPostsRequest struct {
 	*utils.FieldValidate
 	utils.Pagination
-	UserId int `json:"user_id"`
+	UserId int    `json:"user_id"`
+	Status string `json:"status" binding:"omitempty,oneof=draft pending_review published archived"`
+	// 按标签（名称或 slug）过滤
+	Tag string `json:"tag"`
+	// 按分类过滤，包含子分类
+	CategoryID uint64 `json:"category_id"`
+	// 排序：latest（默认，最新创建）或 most_discussed（评论最多）
+	Sort string `json:"sort" binding:"omitempty,oneof=latest most_discussed"`
 }
 
 func (h *PostHandler) GetPagePosts(c *gin.Context) {
@@ -41,48 +78,68 @@ func (h *PostHandler) GetPagePosts(c *gin.Context) {
 		return
 	}
 
-	var posts []models.Post
-	query := config.DB.Model(&models.Post{})
-	if req.UserId > 0 {
-		query.Where("user_id = ?", req.UserId)
-	}
-	paginatedResult, err := utils.GetPaginatedData(query, &posts, &req.Pagination)
-
+	actor := currentActor(c)
+	posts, paginatedResult, err := h.posts.List(c.Request.Context(), actor, service.PostQuery{
+		Pagination: req.Pagination,
+		UserID:     uint64(req.UserId),
+		Status:     req.Status,
+		Tag:        req.Tag,
+		CategoryID: req.CategoryID,
+		Sort:       req.Sort,
+	})
 	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
 		utils.Fail(c, errors.POST_ERROR, "查询失败")
 		return
 	}
-	paginatedResult.Data = posts
+	paginatedResult.Data = toPostResponses(actor, posts)
 	utils.Success(c, paginatedResult, "")
 	return
 }
 
 func (h *PostHandler) GetUserPost(c *gin.Context) {
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.POST_ERROR, "用户未登录")
 		return
 	}
-	var posts []models.Post
-	config.DB.Where("user_id", userId).Preload("Comments", func(db *gorm.DB) *gorm.DB {
-		return db.Order("ID desc").Limit(10) // 限制只加载 10 条关联数据
-	}).Find(&posts)
+	posts, err := h.posts.ListByUser(c.Request.Context(), actor)
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.POST_ERROR, "查询失败")
+		return
+	}
 
-	utils.Success(c, posts, "")
+	utils.Success(c, toPostResponses(actor, posts), "")
 	return
 }
 
 func (h *PostHandler) GetPost(c *gin.Context) {
-	id := c.Param("id")
-	var post models.Post
-	if err := config.DB.Where("id", id).Preload("Comments", func(db *gorm.DB) *gorm.DB {
-		return db.Limit(10) // 限制只加载 10 条关联数据
-	}).First(&post).Error; err != nil {
+	post, err := h.posts.Get(c.Request.Context(), currentActor(c), paramID(c))
+	h.renderPost(c, post, err)
+}
+
+// GetPostBySlug 按 slug 查询文章，旧 slug 301 跳转到当前 slug
+func (h *PostHandler) GetPostBySlug(c *gin.Context) {
+	post, redirect, err := h.posts.GetBySlug(c.Request.Context(), currentActor(c), c.Param("slug"))
+	if redirect != "" {
+		c.Redirect(http.StatusMovedPermanently, "/post/slug/"+url.PathEscape(redirect))
+		return
+	}
+	h.renderPost(c, post, err)
+}
+
+// renderPost 按访问者返回单篇文章（附带前 10 条评论）
+func (h *PostHandler) renderPost(c *gin.Context, post *models.Post, err error) {
+	if err != nil {
+		if !stderrors.Is(err, service.ErrPostNotFound) {
+			logger.Log.WithContext(c.Request.Context()).Error(err)
+		}
 		utils.Fail(c, errors.POST_ERROR, "文章没找到")
 		return
 	}
-	utils.Success(c, post, "")
-	return
+	utils.SetETag(c, post.Version)
+	utils.Success(c, toPostResponse(currentActor(c), post), "")
 }
 
 func (h *PostHandler) AddPost(c *gin.Context) {
@@ -94,22 +151,23 @@ func (h *PostHandler) AddPost(c *gin.Context) {
 		utils.Fail(c, errors.INVALID_PARAMETER, msg)
 		return
 	}
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.POST_ERROR, "用户未登录")
 		return
 	}
-	post := &models.Post{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  userId.(uint64),
-	}
-	if err := config.DB.Create(post).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.POST_ERROR, "添加文章失败")
+	// 新建文章默认为草稿，需要通过发布接口上线
+	post, err := h.posts.Create(c.Request.Context(), actor, service.PostInput{
+		Title:      req.Title,
+		Content:    req.Content,
+		Tags:       req.Tags,
+		CategoryID: req.CategoryID,
+	})
+	if err != nil {
+		failPost(c, err, "添加文章失败")
 		return
 	}
-	utils.Success(c, "", "添加成功")
+	utils.Success(c, gin.H{"id": post.ID, "slug": post.Slug}, "添加成功")
 }
 
 func (h *PostHandler) UpdatePost(c *gin.Context) {
@@ -122,48 +180,187 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	userId, exists := c.Get("user_id")
-	if !exists {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.POST_ERROR, "用户未登录")
 		return
 	}
 
-	var existPost models.Post
+	version, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	// 拥有 post:edit 权限的用户（编辑、管理员）可以修改任意文章
+	post, err := h.posts.Update(c.Request.Context(), actor, service.PostInput{
+		ID:         uint64(req.ID),
+		Title:      req.Title,
+		Content:    req.Content,
+		Tags:       req.Tags,
+		CategoryID: req.CategoryID,
+		Version:    version,
+	})
+	if err != nil {
+		failPost(c, err, "修改文章失败")
+		return
+	}
+	utils.SetETag(c, post.Version)
+	utils.Success(c, gin.H{"id": post.ID, "slug": post.Slug, "version": post.Version}, "修改文章成功")
+}
 
-	if err := config.DB.Where("user_id", userId).First(&existPost, "id = ?", req.ID).Error; err != nil {
-		utils.Fail(c, errors.POST_ERROR, "文章不存在")
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+	if err := h.posts.Delete(c.Request.Context(), actor, paramID(c)); err != nil {
+		failPost(c, err, "删除失败")
 		return
 	}
 
-	existPost.Title = req.Title
-	existPost.Content = req.Content
+	utils.Success(c, "", "删除成功")
+	return
+}
+
+// SubmitPost 提交审核：draft -> pending_review
+func (h *PostHandler) SubmitPost(c *gin.Context) {
+	h.transitionPost(c, models.PostActionSubmit)
+}
+
+// PublishPost 发布：draft / pending_review / archived -> published
+func (h *PostHandler) PublishPost(c *gin.Context) {
+	h.transitionPost(c, models.PostActionPublish)
+}
+
+// UnpublishPost 撤回为草稿：published / pending_review -> draft
+func (h *PostHandler) UnpublishPost(c *gin.Context) {
+	h.transitionPost(c, models.PostActionUnpublish)
+}
 
-	if err := config.DB.Save(&existPost).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.POST_ERROR, "修改文章失败")
+// ArchivePost 归档：draft / published -> archived
+func (h *PostHandler) ArchivePost(c *gin.Context) {
+	h.transitionPost(c, models.PostActionArchive)
+}
+
+// SchedulePost 设置定时发布时间，到点由后台调度器发布
+func (h *PostHandler) SchedulePost(c *gin.Context) {
+	var req SchedulePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+
+	post, err := h.posts.Schedule(c.Request.Context(), actor, paramID(c), req.PublishAt)
+	if stderrors.Is(err, service.ErrInvalidTransition) {
+		utils.Fail(c, errors.POST_STATUS_ERROR, "当前状态不允许定时发布: "+post.Status)
 		return
 	}
-	utils.Success(c, "", "修改文章成功")
+	if err != nil {
+		failPost(c, err, "设置定时发布失败")
+		return
+	}
+	utils.Success(c, gin.H{"id": post.ID, "scheduled_at": req.PublishAt}, "设置定时发布成功")
 }
 
-func (h *PostHandler) DeletePost(c *gin.Context) {
-	userId, exists := c.Get("user_id")
-	if !exists {
+// UnschedulePost 取消定时发布
+func (h *PostHandler) UnschedulePost(c *gin.Context) {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
 		utils.Fail(c, errors.POST_ERROR, "用户未登录")
 		return
 	}
-	id := c.Param("id")
-	var existPost models.Post
-	if err := config.DB.Where("user_id", userId).Where("id", id).First(&existPost).Error; err != nil {
-		utils.Fail(c, errors.POST_ERROR, "文章没找到")
+	if err := h.posts.Unschedule(c.Request.Context(), actor, paramID(c)); err != nil {
+		failPost(c, err, "取消定时发布失败")
 		return
 	}
-	if err := config.DB.Delete(&existPost).Error; err != nil {
-		logger.Log.Error(err)
-		utils.Fail(c, errors.POST_ERROR, "删除失败")
+	utils.Success(c, "", "取消定时发布成功")
+}
+
+// SetCommentModeration 设置文章的评论审核方式
+func (h *PostHandler) SetCommentModeration(c *gin.Context) {
+	var req CommentModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
 		return
 	}
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+	id := paramID(c)
+	mode, err := h.posts.SetCommentModeration(c.Request.Context(), actor, id, req.Mode)
+	if err != nil {
+		failPost(c, err, "设置评论审核方式失败")
+		return
+	}
+	utils.Success(c, gin.H{"id": id, "comment_moderation": mode}, "设置成功")
+}
 
-	utils.Success(c, "", "删除成功")
-	return
+// transitionPost 校验权限后流转文章状态
+// 作者可以操作自己的文章（发布需要 post:publish），拥有 post:edit 的编辑可以操作任意文章
+func (h *PostHandler) transitionPost(c *gin.Context, action string) {
+	actor := currentActor(c)
+	if actor.IsAnonymous() {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+
+	post, err := h.posts.Transition(c.Request.Context(), actor, paramID(c), action)
+	if stderrors.Is(err, service.ErrInvalidTransition) {
+		utils.Fail(c, errors.POST_STATUS_ERROR, "当前状态不允许该操作: "+post.Status)
+		return
+	}
+	if err != nil {
+		failPost(c, err, "修改文章状态失败")
+		return
+	}
+	utils.Success(c, gin.H{"id": post.ID, "status": post.Status, "published_at": post.PublishedAt}, "")
+}
+
+// failPost 按文章服务返回的错误写入响应，其他错误记录日志并返回 message
+func failPost(c *gin.Context, err error, message string) {
+	var conflict *service.ConflictError
+	switch {
+	case stderrors.As(err, &conflict):
+		// 版本冲突时返回服务端当前版本
+		utils.SetETag(c, conflict.Current)
+		utils.FailWithData(c, errors.VERSION_CONFLICT, "文章已被他人修改，请刷新后重试", gin.H{
+			"id":              conflict.ID,
+			"current_version": conflict.Current,
+		})
+	case stderrors.Is(err, service.ErrPermissionDenied):
+		utils.Fail(c, errors.PERMISSION_DENIED, err.Error())
+	case stderrors.Is(err, service.ErrPostNotFound):
+		utils.Fail(c, errors.POST_ERROR, "文章没找到")
+	case stderrors.Is(err, service.ErrCategoryNotFound):
+		utils.Fail(c, errors.CATEGORY_ERROR, "分类不存在")
+	case stderrors.Is(err, service.ErrRevisionNotFound):
+		utils.Fail(c, errors.REVISION_ERROR, "修订不存在")
+	case stderrors.Is(err, service.ErrStatusChanged):
+		utils.Fail(c, errors.POST_STATUS_ERROR, "文章状态已变更，请刷新后重试")
+	case stderrors.Is(err, service.ErrSlugConflict):
+		utils.Fail(c, errors.POST_ERROR, "文章链接冲突，请稍后重试")
+	case stderrors.Is(err, service.ErrPublishTimePassed):
+		utils.Fail(c, errors.INVALID_PARAMETER, "发布时间必须晚于当前时间")
+	default:
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.POST_ERROR, message)
+	}
+}
+
+// paramID 路径参数 id，格式错误时返回 0，按记录不存在处理
+func paramID(c *gin.Context) uint64 {
+	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	return id
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"time"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PostResponse 返回给客户端的文章字段（不包含删除时间等内部字段）
+// 作者和编辑额外返回 PostOwnerFields
+type PostResponse struct {
+	ID           uint              `json:"id"`
+	Title        string            `json:"title"`
+	Slug         string            `json:"slug"`
+	Content      string            `json:"content"`
+	ContentHTML  string            `json:"content_html"`
+	TOC          models.TOC        `json:"toc"`
+	Excerpt      string            `json:"excerpt"`
+	UserID       uint64            `json:"user_id"`
+	Status       string            `json:"status"`
+	PublishedAt  *time.Time        `json:"published_at"`
+	Tags         []TagResponse     `json:"tags"`
+	Category     *CategoryResponse `json:"category"`
+	CommentCount int               `json:"comment_count"`
+	Comments     []CommentResponse `json:"comments,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	*PostOwnerFields
+}
+
+// PostOwnerFields 只返回给作者和拥有 post:edit 的编辑
+type PostOwnerFields struct {
+	// 乐观锁版本号，修改时作为 version / If-Match 传回
+	Version     int        `json:"version"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	// 评论审核方式：空表示使用全局设置
+	CommentModeration string `json:"comment_moderation"`
+}
+
+type TagResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type CategoryResponse struct {
+	ID       uint    `json:"id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	ParentID *uint64 `json:"parent_id"`
+}
+
+// CommentResponse 返回给客户端的评论字段，评论者本人和审核员额外返回 CommentOwnerFields
+type CommentResponse struct {
+	ID          uint      `json:"id"`
+	Content     string    `json:"content"`
+	ContentHTML string    `json:"content_html"`
+	UserID      uint64    `json:"user_id"`
+	PostID      uint64    `json:"post_id"`
+	ParentID    *uint64   `json:"parent_id"`
+	Depth       int       `json:"depth"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	*CommentOwnerFields
+}
+
+// CommentOwnerFields 只返回给评论者本人和拥有 comment:moderate 的审核员，垃圾评论得分只有审核员可见
+type CommentOwnerFields struct {
+	Version     int     `json:"version"`
+	SpamScore   float64 `json:"spam_score,omitempty"`
+	SpamReasons string  `json:"spam_reasons,omitempty"`
+}
+
+// CommentNode 树形评论节点，Replies 只包含前 N 条直接回复，
+// NextCursor 非空时可通过 GET /comment/:id/replies?cursor= 加载更多
+type CommentNode struct {
+	CommentResponse
+	ReplyCount int64         `json:"reply_count"`
+	Replies    []CommentNode `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// RevisionResponse 文章修订，修订号用 revision 表示，与文章的乐观锁版本号 version 区分
+// 列表中不返回正文
+type RevisionResponse struct {
+	ID        uint      `json:"id"`
+	PostID    uint64    `json:"post_id"`
+	Revision  int       `json:"revision"`
+	UserID    uint64    `json:"user_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content,omitempty"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func toPostResponse(actor service.Actor, post *models.Post) PostResponse {
+	resp := PostResponse{
+		ID:           post.ID,
+		Title:        post.Title,
+		Slug:         post.Slug,
+		Content:      post.Content,
+		ContentHTML:  post.ContentHTML,
+		TOC:          post.TOC,
+		Excerpt:      post.Excerpt,
+		UserID:       post.UserID,
+		Status:       post.Status,
+		PublishedAt:  post.PublishedAt,
+		CommentCount: post.CommentCount,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+	}
+	if !actor.IsAnonymous() && (post.UserID == actor.UserID || actor.Can(models.PermPostEdit)) {
+		resp.PostOwnerFields = &PostOwnerFields{
+			Version:           post.Version,
+			ScheduledAt:       post.ScheduledAt,
+			CommentModeration: post.CommentModeration,
+		}
+	}
+	resp.Tags = make([]TagResponse, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		resp.Tags = append(resp.Tags, TagResponse{ID: tag.ID, Name: tag.Name, Slug: tag.Slug})
+	}
+	if post.Category != nil {
+		resp.Category = &CategoryResponse{
+			ID:       post.Category.ID,
+			Name:     post.Category.Name,
+			Slug:     post.Category.Slug,
+			ParentID: post.Category.ParentID,
+		}
+	}
+	if len(post.Comments) > 0 {
+		resp.Comments = toCommentResponses(actor, post.Comments)
+	}
+	return resp
+}
+
+func toPostResponses(actor service.Actor, posts []models.Post) []PostResponse {
+	resp := make([]PostResponse, 0, len(posts))
+	for i := range posts {
+		resp = append(resp, toPostResponse(actor, &posts[i]))
+	}
+	return resp
+}
+
+func toCommentResponse(actor service.Actor, comment *models.Comment) CommentResponse {
+	resp := CommentResponse{
+		ID:          comment.ID,
+		Content:     comment.Content,
+		ContentHTML: comment.ContentHTML,
+		UserID:      comment.UserID,
+		PostID:      comment.PostID,
+		ParentID:    comment.ParentID,
+		Depth:       comment.Depth,
+		Status:      commentStatus(actor, comment),
+		CreatedAt:   comment.CreatedAt,
+		UpdatedAt:   comment.UpdatedAt,
+	}
+	moderator := actor.Can(models.PermCommentModerate)
+	if !actor.IsAnonymous() && (comment.UserID == actor.UserID || moderator) {
+		resp.CommentOwnerFields = &CommentOwnerFields{Version: comment.Version}
+		if moderator {
+			resp.SpamScore = comment.SpamScore
+			resp.SpamReasons = comment.SpamReasons
+		}
+	}
+	return resp
+}
+
+// commentStatus 返回给 actor 的评论状态，只有审核员能看到 spam，其他人看到的是 pending，不暴露检测结果
+func commentStatus(actor service.Actor, comment *models.Comment) string {
+	if comment.Status == models.CommentStatusSpam && !actor.Can(models.PermCommentModerate) {
+		return models.CommentStatusPending
+	}
+	return comment.Status
+}
+
+func toCommentResponses(actor service.Actor, comments []models.Comment) []CommentResponse {
+	resp := make([]CommentResponse, 0, len(comments))
+	for i := range comments {
+		resp = append(resp, toCommentResponse(actor, &comments[i]))
+	}
+	return resp
+}
+
+func toCommentNodes(actor service.Actor, threads []service.CommentThread) []CommentNode {
+	nodes := make([]CommentNode, 0, len(threads))
+	for i := range threads {
+		nodes = append(nodes, CommentNode{
+			CommentResponse: toCommentResponse(actor, &threads[i].Comment),
+			ReplyCount:      threads[i].ReplyCount,
+			Replies:         toCommentNodes(actor, threads[i].Replies),
+			NextCursor:      threads[i].NextCursor,
+		})
+	}
+	return nodes
+}
+
+// currentActor 当前请求的用户，匿名访问时 UserID 为 0
+func currentActor(c *gin.Context) service.Actor {
+	return service.Actor{
+		UserID:      c.GetUint64("user_id"),
+		Username:    c.GetString("username"),
+		Permissions: c.GetStringSlice("permissions"),
+	}
+}
+
+// expectedVersion 客户端期望的版本号（If-Match 或请求体中的 version），失败时已写入响应
+func expectedVersion(c *gin.Context, bodyVersion int) (int, bool) {
+	version, err := utils.ExpectedVersion(c, bodyVersion)
+	switch {
+	case stderrors.Is(err, utils.ErrInvalidIfMatch):
+		utils.Fail(c, errors.INVALID_PRECONDITION, "If-Match 格式错误，应为响应头 ETag 的值")
+		return 0, false
+	case err != nil:
+		utils.Fail(c, errors.INVALID_PARAMETER, "version 不能为空")
+		return 0, false
+	}
+	return version, true
+}
+
+// isAnonymous 当前请求是否为匿名访问（OptionalAuthMiddleware 未写入 user_id）
+func isAnonymous(c *gin.Context) bool {
+	_, exists := c.Get("user_id")
+	return !exists
+}
+
+func toRevisionResponse(revision *models.PostRevision) RevisionResponse {
+	return RevisionResponse{
+		ID:        revision.ID,
+		PostID:    revision.PostID,
+		Revision:  revision.Version,
+		UserID:    revision.UserID,
+		Title:     revision.Title,
+		Content:   revision.Content,
+		Note:      revision.Note,
+		CreatedAt: revision.CreatedAt,
+	}
+}
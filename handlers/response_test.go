@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+)
+
+func TestPostResponseOwnerFields(t *testing.T) {
+	post := &models.Post{Title: "t", UserID: 1, Version: 3, CommentModeration: models.PostModerationHold}
+	cases := []struct {
+		name  string
+		actor service.Actor
+		owner bool
+	}{
+		{"anonymous", service.Actor{}, false},
+		{"other user", service.Actor{UserID: 2}, false},
+		{"author", service.Actor{UserID: 1}, true},
+		{"editor", service.Actor{UserID: 3, Permissions: []string{models.PermPostEdit}}, true},
+	}
+	for _, tc := range cases {
+		body, err := json.Marshal(toPostResponse(tc.actor, post))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(body), `"version":3`); got != tc.owner {
+			t.Errorf("%s: version exposed = %v, want %v: %s", tc.name, got, tc.owner, body)
+		}
+		// 所有访问者拿到的都是 snake_case 字段
+		if strings.Contains(string(body), `"Title"`) || !strings.Contains(string(body), `"title"`) {
+			t.Errorf("%s: unexpected keys: %s", tc.name, body)
+		}
+	}
+}
+
+func TestCommentResponseSpamFieldsForModerators(t *testing.T) {
+	comment := &models.Comment{UserID: 1, Version: 2, SpamScore: 0.7, SpamReasons: "links"}
+
+	author := toCommentResponse(service.Actor{UserID: 1}, comment)
+	if author.CommentOwnerFields == nil || author.Version != 2 || author.SpamScore != 0 {
+		t.Errorf("author response = %+v", author.CommentOwnerFields)
+	}
+	moderator := toCommentResponse(service.Actor{UserID: 9, Permissions: []string{models.PermCommentModerate}}, comment)
+	if moderator.CommentOwnerFields == nil || moderator.SpamScore != 0.7 {
+		t.Errorf("moderator response = %+v", moderator.CommentOwnerFields)
+	}
+	if other := toCommentResponse(service.Actor{UserID: 5}, comment); other.CommentOwnerFields != nil {
+		t.Errorf("other user should not see owner fields: %+v", other.CommentOwnerFields)
+	}
+}
+
+func TestCommentResponseHidesSpamStatus(t *testing.T) {
+	comment := &models.Comment{UserID: 1, Status: models.CommentStatusSpam}
+	if got := toCommentResponse(service.Actor{UserID: 1}, comment).Status; got != models.CommentStatusPending {
+		t.Errorf("author sees status %q, want pending", got)
+	}
+	moderator := service.Actor{UserID: 9, Permissions: []string{models.PermCommentModerate}}
+	if got := toCommentResponse(moderator, comment).Status; got != models.CommentStatusSpam {
+		t.Errorf("moderator sees status %q, want spam", got)
+	}
+}
+
+func TestRevisionResponseUsesRevisionKey(t *testing.T) {
+	body, err := json.Marshal(toRevisionResponse(&models.PostRevision{PostID: 1, Version: 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 修订号不能与文章的乐观锁版本号同名，避免客户端误用作 If-Match
+	if !strings.Contains(string(body), `"revision":4`) || strings.Contains(string(body), `"version"`) {
+		t.Errorf("revision response = %s", body)
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type RevisionHandler struct {
+	posts service.PostService
+}
+
+func NewRevisionHandler(posts service.PostService) *RevisionHandler {
+	return &RevisionHandler{posts: posts}
+}
+
+// DiffResponse 两个修订的差异，from、to 为修订号
+type DiffResponse struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Title   []utils.DiffLine `json:"title"`
+	Lines   []utils.DiffLine `json:"lines"`
+	Unified string           `json:"unified"`
+	Added   int              `json:"added"`
+	Removed int              `json:"removed"`
+}
+
+// ListRevisions 文章的修订列表（不含正文），按修订号倒序
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	if isAnonymous(c) {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+	revisions, err := h.posts.Revisions(c.Request.Context(), currentActor(c), paramID(c))
+	if err != nil {
+		failPost(c, err, "查询失败")
+		return
+	}
+	resp := make([]RevisionResponse, 0, len(revisions))
+	for i := range revisions {
+		resp = append(resp, toRevisionResponse(&revisions[i]))
+	}
+	utils.Success(c, resp, "")
+}
+
+// GetRevision 查看某个修订的完整内容
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	number, ok := revisionNumber(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	revision, ok := h.findRevision(c, number)
+	if !ok {
+		return
+	}
+	utils.Success(c, toRevisionResponse(revision), "")
+}
+
+// DiffRevisions 比较两个修订（GET /post/:id/diff?from=1&to=2），to 不传时与最新修订比较
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	fromNumber, ok := revisionNumber(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	toNumber := 0
+	if c.Query("to") != "" {
+		if toNumber, ok = revisionNumber(c, c.Query("to")); !ok {
+			return
+		}
+	}
+	from, ok := h.findRevision(c, fromNumber)
+	if !ok {
+		return
+	}
+	to, ok := h.findRevision(c, toNumber)
+	if !ok {
+		return
+	}
+
+	title, err := utils.LineDiff(from.Title, to.Title)
+	if err != nil {
+		failDiff(c, err)
+		return
+	}
+	lines, err := utils.LineDiff(from.Content, to.Content)
+	if err != nil {
+		failDiff(c, err)
+		return
+	}
+	resp := DiffResponse{
+		From:    from.Version,
+		To:      to.Version,
+		Title:   title,
+		Lines:   lines,
+		Unified: utils.UnifiedDiff(lines, fmt.Sprintf("r%d", from.Version), fmt.Sprintf("r%d", to.Version), 3),
+	}
+	for _, line := range resp.Lines {
+		switch line.Op {
+		case utils.DiffInsert:
+			resp.Added++
+		case utils.DiffDelete:
+			resp.Removed++
+		}
+	}
+	utils.Success(c, resp, "")
+}
+
+// RestoreRevision 将文章恢复到某个修订，恢复本身作为一个新修订记录
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	if isAnonymous(c) {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return
+	}
+	number, ok := revisionNumber(c, c.Param("revision"))
+	if !ok {
+		return
+	}
+	post, err := h.posts.Restore(c.Request.Context(), currentActor(c), paramID(c), number)
+	if err != nil {
+		failPost(c, err, "恢复修订失败")
+		return
+	}
+	utils.Success(c, gin.H{"id": post.ID, "slug": post.Slug}, "恢复修订成功")
+}
+
+// findRevision 按路径参数 id 和修订号查找当前用户可操作的文章的修订，修订号为 0 时取最新修订，失败时已写入响应
+func (h *RevisionHandler) findRevision(c *gin.Context, number int) (*models.PostRevision, bool) {
+	if isAnonymous(c) {
+		utils.Fail(c, errors.POST_ERROR, "用户未登录")
+		return nil, false
+	}
+	revision, err := h.posts.Revision(c.Request.Context(), currentActor(c), paramID(c), number)
+	if err != nil {
+		failPost(c, err, "查询失败")
+		return nil, false
+	}
+	return revision, true
+}
+
+// revisionNumber 解析修订号，失败时已写入响应
+func revisionNumber(c *gin.Context, number string) (int, bool) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		utils.Fail(c, errors.INVALID_PARAMETER, "修订号错误")
+		return 0, false
+	}
+	return n, true
+}
+
+// failDiff 比较修订失败，内容过大时提示无法比较
+func failDiff(c *gin.Context, err error) {
+	if stderrors.Is(err, utils.ErrDiffTooLarge) {
+		utils.Fail(c, errors.REVISION_ERROR, fmt.Sprintf("内容超过 %d 行，无法比较", utils.MaxDiffLines))
+		return
+	}
+	logger.Log.WithContext(c.Request.Context()).Error(err)
+	utils.Fail(c, errors.REVISION_ERROR, "比较修订失败")
+}
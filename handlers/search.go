@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/search"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	index    search.Index
+	taxonomy service.TaxonomyService
+}
+
+func NewSearchHandler(index search.Index, taxonomy service.TaxonomyService) *SearchHandler {
+	return &SearchHandler{index: index, taxonomy: taxonomy}
+}
+
+type SearchRequest struct {
+	*utils.FieldValidate
+	utils.Pagination
+	Keyword  string `form:"q" json:"q" binding:"required,min=1,max=100" label:"关键词"`
+	Type     string `form:"type" json:"type" binding:"omitempty,oneof=post comment"`
+	AuthorID uint64 `form:"author_id" json:"author_id"`
+	Tag      string `form:"tag" json:"tag"`
+	// 包含子分类下的文章及其评论
+	CategoryID uint64    `form:"category_id" json:"category_id"`
+	From       time.Time `form:"from" json:"from" time_format:"2006-01-02"`
+	To         time.Time `form:"to" json:"to" time_format:"2006-01-02"`
+}
+
+// Search 全文搜索已发布的文章和评论（GET /search?q=关键词&type=post&tag=go&category_id=1&from=2024-01-01&to=2024-12-31）
+func (h *SearchHandler) Search(c *gin.Context) {
+	var req SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	// 分页默认值
+	utils.Paginate(&req.Pagination)
+	query := search.Query{
+		Keyword:  req.Keyword,
+		Type:     req.Type,
+		AuthorID: req.AuthorID,
+		Tag:      req.Tag,
+		From:     req.From,
+		Offset:   (req.Page - 1) * req.PageSize,
+		Limit:    req.PageSize,
+	}
+	if req.CategoryID > 0 {
+		categoryIds, err := h.taxonomy.CategoryDescendantIDs(c.Request.Context(), req.CategoryID)
+		if err != nil {
+			failTaxonomy(c, err, errors.SEARCH_ERROR, "搜索失败")
+			return
+		}
+		query.CategoryIDs = categoryIds
+	}
+	// 结束日期包含当天
+	if !req.To.IsZero() {
+		query.To = req.To.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	result, err := h.index.Search(query)
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Errorf("search err: %v", err)
+		utils.Fail(c, errors.SEARCH_ERROR, "搜索失败")
+		return
+	}
+
+	totalPages := (result.Total + req.PageSize - 1) / req.PageSize
+	utils.Success(c, &utils.PageResult{
+		Data:       result.Hits,
+		Total:      int64(result.Total),
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		TotalPages: totalPages,
+		HasMore:    req.Page < totalPages,
+	}, "")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	stderrors "errors"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	taxonomy service.TaxonomyService
+}
+
+func NewTagHandler(taxonomy service.TaxonomyService) *TagHandler {
+	return &TagHandler{taxonomy: taxonomy}
+}
+
+type CreateTagRequest struct {
+	*utils.FieldValidate
+	Name string `json:"name" binding:"required,min=1,max=50" label:"标签名"`
+}
+
+type UpdateTagRequest struct {
+	*utils.FieldValidate
+	ID   uint   `json:"id" binding:"required"`
+	Name string `json:"name" binding:"required,min=1,max=50" label:"标签名"`
+}
+
+// ListTags 标签列表，附带每个标签下已发布的文章数
+func (h *TagHandler) ListTags(c *gin.Context) {
+	tags, err := h.taxonomy.ListTags(c.Request.Context())
+	if err != nil {
+		failTaxonomy(c, err, errors.TAG_ERROR, "查询失败")
+		return
+	}
+	utils.Success(c, tags, "")
+}
+
+func (h *TagHandler) AddTag(c *gin.Context) {
+	var req CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	tag, err := h.taxonomy.CreateTag(c.Request.Context(), currentActor(c), req.Name)
+	if err != nil {
+		failTaxonomy(c, err, errors.TAG_ERROR, "添加标签失败")
+		return
+	}
+	utils.Success(c, tag, "添加成功")
+}
+
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	var req UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	tag, err := h.taxonomy.UpdateTag(c.Request.Context(), currentActor(c), uint64(req.ID), req.Name)
+	if err != nil {
+		failTaxonomy(c, err, errors.TAG_ERROR, "修改标签失败")
+		return
+	}
+	utils.Success(c, tag, "修改成功")
+}
+
+// DeleteTag 删除标签，同时解除与文章的关联
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	if err := h.taxonomy.DeleteTag(c.Request.Context(), currentActor(c), paramID(c)); err != nil {
+		failTaxonomy(c, err, errors.TAG_ERROR, "删除失败")
+		return
+	}
+	utils.Success(c, "", "删除成功")
+}
+
+// failTaxonomy 按标签、分类服务返回的错误写入响应，其他错误记录日志并返回 code、message
+func failTaxonomy(c *gin.Context, err error, code int, message string) {
+	switch {
+	case stderrors.Is(err, service.ErrPermissionDenied):
+		utils.Fail(c, errors.PERMISSION_DENIED, err.Error())
+	case stderrors.Is(err, service.ErrTagNotFound):
+		utils.Fail(c, errors.TAG_ERROR, "标签不存在")
+	case stderrors.Is(err, service.ErrTagExists):
+		utils.Fail(c, errors.ALREADY_EXISTS, "标签已存在")
+	case stderrors.Is(err, service.ErrCategoryNotFound):
+		utils.Fail(c, errors.CATEGORY_ERROR, "分类不存在")
+	case stderrors.Is(err, service.ErrParentCategoryNotFound):
+		utils.Fail(c, errors.CATEGORY_ERROR, "上级分类不存在")
+	case stderrors.Is(err, service.ErrCategoryExists):
+		utils.Fail(c, errors.CATEGORY_ERROR, "分类已存在")
+	case stderrors.Is(err, service.ErrCategoryCycle):
+		utils.Fail(c, errors.CATEGORY_ERROR, "上级分类不能是自己或子分类")
+	default:
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, code, message)
+	}
+}
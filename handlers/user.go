@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	stderrors "errors"
+
+	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/service"
+	"github.com/gavin/blog/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	users service.UserService
+}
+
+func NewUserHandler(users service.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
+type UpdateRoleRequest struct {
+	*utils.FieldValidate
+	UserID      uint64   `json:"user_id" binding:"required" label:"用户ID"`
+	Role        string   `json:"role" binding:"required,oneof=admin editor author reader" label:"角色"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRole 修改用户角色和额外权限，修改后撤销该用户现有会话使新权限立即生效
+func (h *UserHandler) UpdateRole(c *gin.Context) {
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validate utils.FieldValidateIF = req
+		msg := validate.Validate(err, req)
+		utils.Fail(c, errors.INVALID_PARAMETER, msg)
+		return
+	}
+
+	err := h.users.UpdateRole(c.Request.Context(), req.UserID, req.Role, req.Permissions)
+	if stderrors.Is(err, service.ErrInvalidRole) || stderrors.Is(err, service.ErrInvalidPermission) {
+		utils.Fail(c, errors.INVALID_PARAMETER, "角色或权限不存在: "+err.Error())
+		return
+	}
+	if stderrors.Is(err, service.ErrUserNotFound) {
+		utils.Fail(c, errors.USER_ERROR, "用户不存在")
+		return
+	}
+	if err != nil {
+		logger.Log.WithContext(c.Request.Context()).Error(err)
+		utils.Fail(c, errors.USER_ERROR, "修改角色失败")
+		return
+	}
+
+	utils.Success(c, "", "修改角色成功")
+}
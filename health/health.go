@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 检查结果状态
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+// DefaultTimeout 注册时未指定超时的检查使用的超时
+const DefaultTimeout = 2 * time.Second
+
+// ready 实例是否可以接收流量：启动完成后置为就绪，退出时最先置为未就绪，让负载均衡停止转发新请求
+var ready atomic.Bool
+
+// SetReady 修改就绪状态
+func SetReady(v bool) {
+	ready.Store(v)
+}
+
+// IsReady 当前是否就绪
+func IsReady() bool {
+	return ready.Load()
+}
+
+// Check 检查一个依赖组件，返回 nil 表示健康，需要在 ctx 结束时尽快返回
+type Check func(ctx context.Context) error
+
+// Result 单项检查结果
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report 就绪检查报告，任一检查失败时 Status 为 not ready
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Ready 是否所有检查都通过
+func (r *Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+type namedCheck struct {
+	name    string
+	timeout time.Duration
+	check   Check
+}
+
+// Registry 就绪检查注册表，各组件在启动时注册自己的检查
+type Registry struct {
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// Default 全局注册表，GET /readyz 使用
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register 注册检查，同名检查会被替换，timeout 为 0 时使用 DefaultTimeout
+func (r *Registry) Register(name string, timeout time.Duration, check Check) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = namedCheck{name: name, timeout: timeout, check: check}
+			return
+		}
+	}
+	r.checks = append(r.checks, namedCheck{name: name, timeout: timeout, check: check})
+}
+
+// Run 并发执行所有检查，每项检查单独计时，结果按注册顺序返回
+func (r *Registry) Run(ctx context.Context) *Report {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := &Report{Status: StatusReady, Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusNotReady
+			break
+		}
+	}
+	return report
+}
+
+// run 执行单项检查，超时后不再等待（检查本身忽略 ctx 时协程会在其返回后退出）
+func run(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.New("timeout after " + c.timeout.String())
+	}
+
+	result := Result{Name: c.name, Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Register 向全局注册表注册检查
+func Register(name string, timeout time.Duration, check Check) {
+	Default.Register(name, timeout, check)
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error { return nil }
+
+func TestRunAggregatesChecks(t *testing.T) {
+	r := NewRegistry()
+	if report := r.Run(context.Background()); !report.Ready() || len(report.Checks) != 0 {
+		t.Fatalf("empty registry report = %+v, want ready", report)
+	}
+
+	r.Register("db", 0, ok)
+	r.Register("search", 0, func(context.Context) error { return errors.New("index not built") })
+	r.Register("cache", 0, ok)
+	report := r.Run(context.Background())
+	if report.Ready() || report.Status != StatusNotReady {
+		t.Errorf("status = %s, want not ready", report.Status)
+	}
+	// 按注册顺序返回
+	want := []struct{ name, status, err string }{
+		{"db", StatusOK, ""},
+		{"search", StatusFail, "index not built"},
+		{"cache", StatusOK, ""},
+	}
+	for i, w := range want {
+		got := report.Checks[i]
+		if got.Name != w.name || got.Status != w.status || got.Error != w.err || got.Duration == "" {
+			t.Errorf("check %d = %+v, want %s %s %q", i, got, w.name, w.status, w.err)
+		}
+	}
+
+	// 同名检查被替换，不新增
+	r.Register("search", 0, ok)
+	report = r.Run(context.Background())
+	if !report.Ready() || len(report.Checks) != 3 || report.Checks[1].Name != "search" {
+		t.Errorf("after replacing = %+v, want 3 ready checks", report)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := NewRegistry()
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	// 忽略 ctx 的检查也按超时返回
+	r.Register("stuck", 20*time.Millisecond, func(context.Context) error {
+		<-block
+		return nil
+	})
+	r.Register("slow", 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r.Register("fast", time.Second, ok)
+
+	start := time.Now()
+	report := r.Run(context.Background())
+	// 并发执行，总耗时约为最长的单项超时
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Run took %v", elapsed)
+	}
+	if report.Ready() {
+		t.Fatal("report ready despite timeouts")
+	}
+	for _, result := range report.Checks[:2] {
+		if result.Status != StatusFail || result.Error != "timeout after 20ms" {
+			t.Errorf("%s = %+v, want timeout", result.Name, result)
+		}
+	}
+	if report.Checks[2].Status != StatusOK {
+		t.Errorf("fast = %+v, want ok", report.Checks[2])
+	}
+}
+
+func TestRunCanceledContext(t *testing.T) {
+	r := NewRegistry()
+	r.Register("db", time.Second, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := r.Run(ctx)
+	if report.Ready() || report.Checks[0].Error != context.Canceled.Error() {
+		t.Errorf("report = %+v, want canceled failure", report)
+	}
+}
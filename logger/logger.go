@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"context"
 	"io"
 )
 
-var Log Logger
+// Log 全局日志，InitLogger 之前为 NopLogger
+// 请求和后台任务中用 Log.WithContext(ctx) 输出，直接调用 Log 的日志（启动、退出等）不带 request_id、trace_id
+var Log Logger = NopLogger{}
 
 // Logger 是一个通用日志接口，类似于 fmt 接口风格
 
@@ -22,6 +25,11 @@ type Logger interface {
 	Warn(args ...interface{})
 	Error(args ...interface{})
 
+	// With 返回附带字段的子日志，参数为成对的键和值，如 With("post_id", 1, "user_id", 2)
+	With(keysAndValues ...interface{}) Logger
+	// WithContext 返回附带 ctx 中日志字段（request_id、user_id、route 等）和 trace_id、span_id 的子日志
+	WithContext(ctx context.Context) Logger
+
 	Close()
 
 	// 将 Gin 框架的输出重定向到 Zap
@@ -31,6 +39,20 @@ type Logger interface {
 	Init()
 }
 
+type fieldsKey struct{}
+
+// NewContext 返回追加了日志字段的 context，之后 WithContext(ctx) 输出的日志都会带上这些字段
+func NewContext(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields := append(append([]interface{}(nil), FieldsFromContext(ctx)...), keysAndValues...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// FieldsFromContext ctx 中的日志字段
+func FieldsFromContext(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	return fields
+}
+
 func InitLogger() {
 	// 使用Zap日志
 	Log = &ZapLogger{}
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NopLogger 丢弃所有日志，InitLogger 之前 Log 默认为它，单独使用服务层（如测试）时不需要先初始化日志
+// Panicf、Fatalf 仍然 panic、退出进程
+type NopLogger struct{}
+
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+func (NopLogger) Infof(format string, args ...interface{})  {}
+func (NopLogger) Warnf(format string, args ...interface{})  {}
+func (NopLogger) Errorf(format string, args ...interface{}) {}
+
+func (NopLogger) Panicf(format string, args ...interface{}) {
+	panic(fmt.Sprintf(format, args...))
+}
+
+func (NopLogger) Fatalf(format string, args ...interface{}) {
+	os.Exit(1)
+}
+
+func (NopLogger) Debug(args ...interface{}) {}
+func (NopLogger) Info(args ...interface{})  {}
+func (NopLogger) Warn(args ...interface{})  {}
+func (NopLogger) Error(args ...interface{}) {}
+
+func (l NopLogger) With(keysAndValues ...interface{}) Logger { return l }
+func (l NopLogger) WithContext(ctx context.Context) Logger   { return l }
+
+func (NopLogger) Close() {}
+
+func (NopLogger) GetIoWriter() io.Writer {
+	return io.Discard
+}
+
+func (NopLogger) Init() {}
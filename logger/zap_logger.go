@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"os"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -58,6 +60,24 @@ func (l *ZapLogger) Error(args ...interface{}) {
 	l.sugaredLogger.Error(args...)
 }
 
+func (l *ZapLogger) With(keysAndValues ...interface{}) Logger {
+	if len(keysAndValues) == 0 {
+		return l
+	}
+	return &ZapLogger{
+		sugaredLogger: l.sugaredLogger.With(keysAndValues...),
+		ioWriter:      l.ioWriter,
+	}
+}
+
+func (l *ZapLogger) WithContext(ctx context.Context) Logger {
+	fields := FieldsFromContext(ctx)
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields[:len(fields):len(fields)], "trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+	}
+	return l.With(fields...)
+}
+
 func (l *ZapLogger) Close() {
 	l.sugaredLogger.Sync()
 }
@@ -0,0 +1,213 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// 摘要长度（字符数）
+const excerptLength = 200
+
+// TOCItem 目录项
+type TOCItem struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// Rendered 文章渲染结果
+type Rendered struct {
+	HTML    string
+	TOC     []TOCItem
+	Excerpt string
+}
+
+var (
+	// 文章：CommonMark + GFM（表格、删除线、任务列表、自动链接）+ 脚注
+	postMarkdown = goldmark.New(
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+			extension.Footnote,
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+	// 评论：只支持基础语法 + 删除线、自动链接，不解析标题、表格等
+	commentMarkdown = goldmark.New(
+		goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+	)
+
+	postPolicy    = newPostPolicy()
+	commentPolicy = newCommentPolicy()
+)
+
+// RenderPost 渲染文章 Markdown，返回净化后的 HTML、目录和纯文本摘要
+func RenderPost(source string) (*Rendered, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := postMarkdown.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := postMarkdown.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+
+	rendered := &Rendered{
+		HTML:    postPolicy.Sanitize(buf.String()),
+		TOC:     []TOCItem{},
+		Excerpt: excerpt(doc, src),
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			id, _ := heading.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			rendered.TOC = append(rendered.TOC, TOCItem{
+				Level: heading.Level,
+				ID:    string(idBytes),
+				Text:  plainText(heading, src),
+			})
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return rendered, nil
+}
+
+// RenderComment 渲染评论 Markdown（受限子集），返回净化后的 HTML
+func RenderComment(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := commentMarkdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return commentPolicy.Sanitize(buf.String()), nil
+}
+
+func newPostPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// 代码块语言 class，供前端语法高亮（highlight.js / Prism）使用
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	// 脚注
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(footnote-ref|footnote-backref)$`)).OnElements("a")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes$`)).OnElements("div")
+	// GFM 任务列表
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	// 表格对齐
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.RequireNoFollowOnLinks(true)
+	return p
+}
+
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// excerpt 取文章开头段落的纯文本作为摘要
+func excerpt(doc ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || utf8.RuneCountInString(b.String()) >= excerptLength {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.Paragraph:
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(plainText(n, src))
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *ast.Heading, *east.FootnoteList:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	runes := []rune(b.String())
+	if len(runes) > excerptLength {
+		return string(runes[:excerptLength]) + "..."
+	}
+	return string(runes)
+}
+
+// plainText 提取节点下的纯文本
+func plainText(node ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(src))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// headingIDs 生成标题锚点：英文数字转小写连字符，其他字符（如中文标题）使用 section-N
+type headingIDs struct {
+	used  map[string]bool
+	count int
+}
+
+func newHeadingIDs() parser.IDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	s.count++
+	var b strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(string(util.TrimLeftSpace(util.TrimRightSpace(value)))) {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+	id := strings.Trim(b.String(), "-")
+	if id == "" {
+		id = "section-" + strconv.Itoa(s.count)
+	}
+	base := id
+	for i := 1; s.used[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	s.used[id] = true
+	return []byte(id)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
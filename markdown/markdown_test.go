@@ -0,0 +1,160 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderPostSanitizes(t *testing.T) {
+	cases := []struct {
+		name    string
+		source  string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "script tag",
+			source:  "hello <script>alert(1)</script>\n\n<script>alert(2)</script>",
+			want:    []string{"hello"},
+			notWant: []string{"<script", "alert(2)"},
+		},
+		{
+			name:    "onerror attribute",
+			source:  `<img src="x.png" onerror="alert(1)">` + "\n\n![ok](/a.png)",
+			want:    []string{`<img src="/a.png" alt="ok"`},
+			notWant: []string{"onerror"},
+		},
+		{
+			name:    "javascript url",
+			source:  "[click](javascript:alert(1)) [ok](https://example.com)",
+			want:    []string{`href="https://example.com"`, `rel="nofollow"`},
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:   "language class",
+			source: "```go\nfmt.Println()\n```\n\n```c++\nx\n```",
+			want:   []string{`class="language-go"`, `class="language-c++"`},
+		},
+		{
+			name:    "other classes",
+			source:  `<span class="evil">x</span> <code class="evil">y</code>`,
+			notWant: []string{"evil"},
+		},
+		{
+			name:   "task list and table",
+			source: "- [x] done\n\n| a | b |\n|:-:|--:|\n| 1 | 2 |",
+			want:   []string{`type="checkbox"`, `checked`, `align="center"`, `align="right"`},
+		},
+		{
+			name:   "footnote",
+			source: "text[^1]\n\n[^1]: note",
+			want:   []string{`class="footnote-ref"`, `class="footnote-backref"`, `class="footnotes"`, "note"},
+		},
+	}
+	for _, tc := range cases {
+		rendered, err := RenderPost(tc.source)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for _, s := range tc.want {
+			if !strings.Contains(rendered.HTML, s) {
+				t.Errorf("%s: missing %q in\n%s", tc.name, s, rendered.HTML)
+			}
+		}
+		for _, s := range tc.notWant {
+			if strings.Contains(rendered.HTML, s) {
+				t.Errorf("%s: unexpected %q in\n%s", tc.name, s, rendered.HTML)
+			}
+		}
+	}
+}
+
+func TestRenderPostTOC(t *testing.T) {
+	rendered, err := RenderPost("# Hello World\n\n## 安装\n\n## Hello World\n\n### Step `1`")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TOCItem{
+		{Level: 1, ID: "hello-world", Text: "Hello World"},
+		{Level: 2, ID: "section-2", Text: "安装"},
+		{Level: 2, ID: "hello-world-1", Text: "Hello World"},
+		{Level: 3, ID: "step-1", Text: "Step 1"},
+	}
+	if !reflect.DeepEqual(rendered.TOC, want) {
+		t.Errorf("TOC = %+v, want %+v", rendered.TOC, want)
+	}
+	for _, item := range want {
+		if !strings.Contains(rendered.HTML, `id="`+item.ID+`"`) {
+			t.Errorf("heading id %q missing in\n%s", item.ID, rendered.HTML)
+		}
+	}
+}
+
+func TestRenderPostExcerpt(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"skips headings and code", "# Title\n\nFirst **bold** [link](/x).\n\n```\ncode\n```\n\nSecond", "First bold link. Second"},
+		{"skips footnotes", "Body[^1]\n\n[^1]: footnote text", "Body"},
+		{"no raw html", "a <b>b</b> c", "a b c"},
+		{"truncates runes", strings.Repeat("字", excerptLength+10), strings.Repeat("字", excerptLength) + "..."},
+	}
+	for _, tc := range cases {
+		rendered, err := RenderPost(tc.source)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if rendered.Excerpt != tc.want {
+			t.Errorf("%s: excerpt = %q, want %q", tc.name, rendered.Excerpt, tc.want)
+		}
+	}
+}
+
+func TestCommentPolicyStricterThanPost(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		// 文章保留、评论去掉的内容
+		postOnly string
+	}{
+		{"heading", "# title", "<h1"},
+		{"image", "![a](/a.png)", "<img"},
+		{"table", "| a |\n|---|\n| 1 |", "<table"},
+		{"code class", "```go\nx\n```", `class="language-go"`},
+		{"task list", "- [x] done", `type="checkbox"`},
+	}
+	for _, tc := range cases {
+		post, err := RenderPost(tc.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		comment, err := RenderComment(tc.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(post.HTML, tc.postOnly) {
+			t.Errorf("%s: post missing %q in\n%s", tc.name, tc.postOnly, post.HTML)
+		}
+		if strings.Contains(comment, tc.postOnly) {
+			t.Errorf("%s: comment kept %q in\n%s", tc.name, tc.postOnly, comment)
+		}
+	}
+
+	comment, err := RenderComment("**hi** ~~old~~ https://example.com <script>alert(1)</script> [x](javascript:alert(1))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"<strong>hi</strong>", "<del>old</del>", `href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`} {
+		if !strings.Contains(comment, s) {
+			t.Errorf("comment missing %q in\n%s", s, comment)
+		}
+	}
+	for _, s := range []string{"<script", "javascript:"} {
+		if strings.Contains(comment, s) {
+			t.Errorf("comment kept %q in\n%s", s, comment)
+		}
+	}
+}
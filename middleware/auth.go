@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"context"
+
 	"github.com/gavin/blog/errors"
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/store"
 	"github.com/gavin/blog/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -18,30 +22,84 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 解析Bearer令牌
-		var tokenString string
-		parts := []rune(authHeader)
-		if len(parts) > 7 && string(parts[:7]) == "Bearer " {
-			tokenString = string(parts[7:])
-		} else {
-			utils.Fail(c, errors.AUTH_ERROR, "invalid token format (expected Bearer <token>)")
+		claims, code, msg := authenticate(c.Request.Context(), authHeader)
+		if claims == nil {
+			utils.Fail(c, code, msg)
 			c.Abort()
 			return
 		}
 
-		// 验证令牌
-		claims, err := utils.ParseToken(tokenString)
-		if err != nil {
-			utils.Fail(c, errors.AUTH_ERROR, "invalid token: "+err.Error())
-			c.Abort()
-			return
+		setClaims(c, claims)
+
+		// 继续处理请求
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware 可选鉴权：携带有效令牌时写入用户信息，
+// 没有令牌或令牌无效时按匿名用户继续处理，不拒绝请求
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader != "" {
+			if claims, _, _ := authenticate(c.Request.Context(), authHeader); claims != nil {
+				setClaims(c, claims)
+			}
 		}
+		c.Next()
+	}
+}
 
-		// 将用户信息存入上下文，供后续接口使用
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
+// authenticate 解析并校验 Authorization 头，失败时返回错误码和提示
+func authenticate(ctx context.Context, authHeader string) (*utils.CustomClaims, int, string) {
+	// 解析Bearer令牌
+	var tokenString string
+	parts := []rune(authHeader)
+	if len(parts) > 7 && string(parts[:7]) == "Bearer " {
+		tokenString = string(parts[7:])
+	} else {
+		return nil, errors.AUTH_ERROR, "invalid token format (expected Bearer <token>)"
+	}
 
-		// 继续处理请求
+	// 验证令牌
+	claims, err := utils.ParseToken(tokenString)
+	if err != nil {
+		return nil, errors.AUTH_ERROR, "invalid token: " + err.Error()
+	}
+
+	// 检查令牌是否已被撤销（退出登录、退出所有会话）
+	revoked, err := store.IsRevoked(store.Revocations, claims.ID, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		logger.Log.WithContext(ctx).Errorf("check token revocation err: %v", err)
+		return nil, errors.SYSTEM_ERROR, "system error"
+	}
+	if revoked {
+		return nil, errors.AUTH_ERROR, "token has been revoked"
+	}
+	return claims, 0, ""
+}
+
+// setClaims 将用户信息存入上下文，供后续接口使用
+func setClaims(c *gin.Context, claims *utils.CustomClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	c.Set("permissions", claims.Permissions)
+	c.Set("jti", claims.ID)
+	c.Set("token_expires_at", claims.ExpiresAt.Time)
+	c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), "user_id", claims.UserID))
+}
+
+// RequirePermission 要求当前用户拥有全部指定权限，需放在 JWTAuthMiddleware 之后
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if !utils.HasPermission(c, permission) {
+				utils.Fail(c, errors.PERMISSION_DENIED, "permission denied: "+permission)
+				c.Abort()
+				return
+			}
+		}
 		c.Next()
 	}
 }
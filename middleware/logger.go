@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/gavin/blog/errors"
@@ -22,9 +24,9 @@ func GinLogMiddleware() gin.HandlerFunc {
 		if c.Request.Body != nil {
 			bodyBytes, _ = ioutil.ReadAll(c.Request.Body)
 		}
-		//  避免打印大请求体
-		bodySnippet := string(bodyBytes)
-		if len(bodyBytes) > 1024 {
+		// 隐去密码、令牌等敏感字段，并避免打印大请求体
+		bodySnippet := redactBody(bodyBytes)
+		if len(bodySnippet) > 1024 {
 			bodySnippet = bodySnippet[:1024] + "...(truncated)"
 		}
 		// 把 body 放回 request，后续 handler 才能继续读取
@@ -45,7 +47,7 @@ func GinLogMiddleware() gin.HandlerFunc {
 		userAgent := c.Request.UserAgent()
 
 		// 记录请求日志
-		logger.Log.Infof("HTTP request | status_code: %d, latency: %v, client_ip: %s, method: %s, path: %s, user_agent: %s, query_params: %s, body_params: %s",
+		logger.Log.WithContext(c.Request.Context()).Infof("HTTP request | status_code: %d, latency: %v, client_ip: %s, method: %s, path: %s, user_agent: %s, query_params: %s, body_params: %s",
 			statusCode,
 			latency,
 			clientIP,
@@ -53,11 +55,66 @@ func GinLogMiddleware() gin.HandlerFunc {
 			path,
 			userAgent,
 			c.Request.URL.RawQuery,
-			string(bodyBytes),
+			bodySnippet,
 		)
 	}
 }
 
+// sensitiveMarkers 字段名（忽略大小写）包含其中任一片段即视为敏感字段，
+// 如 password、repeat_password、old_password、refresh_token
+var sensitiveMarkers = []string{"password", "token", "secret"}
+
+// sensitiveField 字段名是否敏感
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range sensitiveMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+const redacted = "[REDACTED]"
+
+// redactBody 将 JSON 请求体中的敏感字段替换为 [REDACTED]
+// 无法解析为 JSON 时，只要包含敏感字段名片段就整体隐去
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		if sensitiveField(string(body)) {
+			return redacted
+		}
+		return string(body)
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return redacted
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if sensitiveField(k) {
+				value[k] = redacted
+			} else {
+				value[k] = redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+	}
+	return v
+}
+
 // GinRecoveryWithLogger 是带有日志记录的恢复中间件
 func GinRecoveryWithLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -71,7 +128,7 @@ func GinRecoveryWithLogger() gin.HandlerFunc {
 
 				// 将堆栈信息转换为字符串 (注意：只取有效长度 n)
 				stack := string(buf[:n])
-				logger.Log.Errorf("Panic recovered | error: %v, method: %s, path: %s, client_ip: %s, stack: %s",
+				logger.Log.WithContext(c.Request.Context()).Errorf("Panic recovered | error: %v, method: %s, path: %s, client_ip: %s, stack: %s",
 					err,
 					c.Request.Method,
 					c.Request.URL.Path,
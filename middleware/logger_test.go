@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{"refresh token", `{"refresh_token":"abc.def"}`, []string{`"refresh_token":"[REDACTED]"`}, []string{"abc.def"}},
+		{"login", `{"username":"bob","password":"hunter22"}`, []string{`"username":"bob"`}, []string{"hunter22"}},
+		{"nested", `{"user":{"Password":"x1y2"}}`, []string{"[REDACTED]"}, []string{"x1y2"}},
+		{"not json", `password=hunter22`, []string{"[REDACTED]"}, []string{"hunter22"}},
+		{"register", `{"username":"bob","email":"b@x.io","password":"hunter22","repeat_password":"hunter22"}`,
+			[]string{`"repeat_password":"[REDACTED]"`, `"email":"b@x.io"`}, []string{"hunter22"}},
+		{"access token", `{"Access_Token":"t0k3n"}`, []string{"[REDACTED]"}, []string{"t0k3n"}},
+		{"plain", `{"title":"hi"}`, []string{`"title":"hi"`}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := redactBody([]byte(tc.body))
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("redactBody(%s) = %s, want contains %s", tc.body, got, w)
+				}
+			}
+			for _, w := range tc.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("redactBody(%s) = %s, leaked %s", tc.body, got, w)
+				}
+			}
+		})
+	}
+}
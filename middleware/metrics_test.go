@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavin/blog/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMethodLabel(t *testing.T) {
+	cases := map[string]string{
+		"GET":     "GET",
+		"DELETE":  "DELETE",
+		"OPTIONS": "OPTIONS",
+		"get":     "OTHER",
+		"PURGE":   "OTHER",
+		"X-RAND1": "OTHER",
+	}
+	for method, want := range cases {
+		if got := methodLabel(method); got != want {
+			t.Errorf("methodLabel(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+// requestCounts 按 method 标签汇总 blog_http_requests_total 中 route 为 route 的请求数
+func requestCounts(t *testing.T, route string) map[string]float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "blog_http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["route"] == route {
+				counts[labels["method"]] += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return counts
+}
+
+func TestMetricsMiddlewareGroupsUnknownMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/metrics-test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, method := range []string{"FOO1", "FOO2", "FOO3", http.MethodGet} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/metrics-test", nil))
+	}
+	// 不同的未知方法名只产生一个 OTHER 标签值
+	counts := requestCounts(t, "unmatched")
+	for method := range counts {
+		if methodLabel(method) != method {
+			t.Errorf("unexpected method label %q", method)
+		}
+	}
+	if counts["OTHER"] < 3 {
+		t.Errorf("OTHER requests = %v, want at least 3", counts["OTHER"])
+	}
+	if got := requestCounts(t, "/metrics-test")[http.MethodGet]; got != 1 {
+		t.Errorf("GET requests = %v, want 1", got)
+	}
+}
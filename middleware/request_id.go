@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// 上游传入的请求 ID 最大长度，超出或含有其他字符时重新生成
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware 读取上游的 X-Request-ID，没有或不合法时生成一个
+// 请求 ID 写入响应头和 gin 上下文（request_id），并和路由一起加入日志字段
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		fields := []interface{}{"request_id", requestID}
+		if route := c.FullPath(); route != "" {
+			fields = append(fields, "route", route)
+		}
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), fields...))
+		c.Next()
+	}
+}
+
+// validRequestID 只接受字母、数字和 - _ . : 组成的 ID，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand 在受支持的平台上不会失败
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	var inContext string
+	var fields []interface{}
+	router.GET("/posts/:id", func(c *gin.Context) {
+		inContext = c.GetString("request_id")
+		fields = logger.FieldsFromContext(c.Request.Context())
+	})
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	cases := []struct {
+		name     string
+		incoming string
+		reuse    bool
+	}{
+		{"missing", "", false},
+		{"upstream", "lb-1:2f0c.9_a", true},
+		{"max length", strings.Repeat("a", maxRequestIDLength), true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"log injection", "abc\ninjected=1", false},
+		{"spaces", "a b", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+		if tc.incoming != "" {
+			req.Header.Set(RequestIDHeader, tc.incoming)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		echoed := w.Header().Get(RequestIDHeader)
+		if tc.reuse && echoed != tc.incoming {
+			t.Errorf("%s: response id = %q, want incoming %q", tc.name, echoed, tc.incoming)
+		}
+		if !tc.reuse && !generated.MatchString(echoed) {
+			t.Errorf("%s: response id = %q, want generated", tc.name, echoed)
+		}
+		if inContext != echoed {
+			t.Errorf("%s: context id = %q, response id = %q", tc.name, inContext, echoed)
+		}
+		want := fmt.Sprint([]interface{}{"request_id", echoed, "route", "/posts/:id"})
+		if got := fmt.Sprint(fields); got != want {
+			t.Errorf("%s: log fields = %s, want %s", tc.name, got, want)
+		}
+	}
+}
+
+func TestNewRequestIDUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newRequestID()
+		if seen[id] || !validRequestID(id) {
+			t.Fatalf("id %q duplicated or invalid", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,168 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 初始表结构的快照，之后模型的变更写在新的迁移中，这里不再修改
+// 字段、关联名与当时的模型一致，索引、外键名也就一致；post_tags 的列名显式指定，不随快照类型名变化
+
+type initialUser struct {
+	gorm.Model
+	Username    string           `gorm:"unique;not null"`
+	Password    string           `gorm:"not null"`
+	Email       string           `gorm:"unique;not null"`
+	Role        string           `gorm:"size:20;not null;default:author"`
+	Permissions string           `gorm:"size:255"`
+	Posts       []initialPost    `gorm:"foreignKey:UserID;"`
+	Comments    []initialComment `gorm:"foreignKey:UserID;"`
+}
+
+type initialTag struct {
+	gorm.Model
+	Name string `gorm:"size:50;uniqueIndex;not null"`
+	Slug string `gorm:"size:191;uniqueIndex;not null"`
+}
+
+type initialCategory struct {
+	gorm.Model
+	Name     string            `gorm:"size:50;not null"`
+	Slug     string            `gorm:"size:191;uniqueIndex;not null"`
+	ParentID *uint64           `gorm:"index"`
+	Children []initialCategory `gorm:"foreignKey:ParentID"`
+}
+
+type initialPost struct {
+	gorm.Model
+	Title             string `gorm:"not null"`
+	Slug              string `gorm:"size:191;uniqueIndex;default:null"`
+	Content           string `gorm:"not null"`
+	ContentHTML       string `gorm:"type:text"`
+	TOC               string `gorm:"type:text"`
+	Excerpt           string `gorm:"size:1024"`
+	UserID            uint64
+	Status            string `gorm:"size:20;not null;default:published;index"`
+	PublishedAt       *time.Time
+	ScheduledAt       *time.Time       `gorm:"index"`
+	CategoryID        *uint64          `gorm:"index"`
+	Category          *initialCategory `gorm:"foreignKey:CategoryID;"`
+	Tags              []initialTag     `gorm:"many2many:post_tags;joinForeignKey:PostID;joinReferences:TagID"`
+	Comments          []initialComment `gorm:"foreignKey:PostID;"`
+	CommentCount      int              `gorm:"not null;default:0;index"`
+	CommentModeration string           `gorm:"size:20;not null;default:''"`
+	Version           int              `gorm:"not null;default:1"`
+}
+
+type initialComment struct {
+	gorm.Model
+	Content     string `gorm:"not null"`
+	ContentHTML string `gorm:"type:text"`
+	UserID      uint64
+	PostID      uint64
+	Post        initialPost `gorm:"foreignKey:PostID;"`
+	ParentID    *uint64     `gorm:"index"`
+	Depth       int         `gorm:"not null;default:0"`
+	Status      string      `gorm:"size:20;not null;default:approved;index"`
+	SpamScore   float64     `gorm:"not null;default:0"`
+	SpamReasons string      `gorm:"type:text"`
+	Version     int         `gorm:"not null;default:1"`
+}
+
+type initialRefreshToken struct {
+	gorm.Model
+	UserID    uint64     `gorm:"index;not null"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"`
+	FamilyID  string     `gorm:"size:32;index;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time `gorm:"index"`
+}
+
+type initialRevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+type initialUserTokenRevocation struct {
+	gorm.Model
+	UserID    uint64    `gorm:"uniqueIndex;not null"`
+	RevokedAt time.Time `gorm:"not null"`
+}
+
+type initialPostSlugHistory struct {
+	gorm.Model
+	PostID uint64 `gorm:"index;not null"`
+	Slug   string `gorm:"size:191;uniqueIndex;not null"`
+}
+
+type initialPostRevision struct {
+	gorm.Model
+	PostID  uint64 `gorm:"uniqueIndex:idx_post_revision;not null"`
+	Version int    `gorm:"uniqueIndex:idx_post_revision;not null"`
+	UserID  uint64 `gorm:"not null"`
+	Title   string `gorm:"not null"`
+	Content string `gorm:"type:text;not null"`
+	Note    string `gorm:"size:255"`
+}
+
+type initialModerationSetting struct {
+	gorm.Model
+	HoldAll            bool `gorm:"not null"`
+	HoldFirstTime      bool `gorm:"not null"`
+	AutoApproveTrusted bool `gorm:"not null"`
+	TrustedThreshold   int  `gorm:"not null"`
+}
+
+func (initialUser) TableName() string                { return "users" }
+func (initialTag) TableName() string                 { return "tags" }
+func (initialCategory) TableName() string            { return "categories" }
+func (initialPost) TableName() string                { return "posts" }
+func (initialComment) TableName() string             { return "comments" }
+func (initialRefreshToken) TableName() string        { return "refresh_tokens" }
+func (initialRevokedToken) TableName() string        { return "revoked_tokens" }
+func (initialUserTokenRevocation) TableName() string { return "user_token_revocations" }
+func (initialPostSlugHistory) TableName() string     { return "post_slug_histories" }
+func (initialPostRevision) TableName() string        { return "post_revisions" }
+func (initialModerationSetting) TableName() string   { return "moderation_settings" }
+
+// 初始表结构，与之前启动时 AutoMigrate 的结果一致，已有数据库上执行不会丢数据
+func init() {
+	Register(&Migration{
+		Version: "20261018000001",
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			// 标签、分类需要先于文章创建（外键、中间表依赖）
+			return tx.AutoMigrate(
+				&initialUser{},
+				&initialTag{},
+				&initialCategory{},
+				&initialPost{},
+				&initialComment{},
+				&initialRefreshToken{},
+				&initialRevokedToken{},
+				&initialUserTokenRevocation{},
+				&initialPostSlugHistory{},
+				&initialPostRevision{},
+				&initialModerationSetting{},
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(
+				&initialModerationSetting{},
+				&initialPostRevision{},
+				&initialPostSlugHistory{},
+				&initialUserTokenRevocation{},
+				&initialRevokedToken{},
+				&initialRefreshToken{},
+				&initialComment{},
+				"post_tags",
+				&initialPost{},
+				&initialCategory{},
+				&initialTag{},
+				&initialUser{},
+			)
+		},
+	})
+}
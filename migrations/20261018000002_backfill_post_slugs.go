@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// 为还没有 slug 的旧文章生成 slug
+func init() {
+	Register(&Migration{
+		Version: "20261018000002",
+		Name:    "backfill_post_slugs",
+		Up: func(tx *gorm.DB) error {
+			var posts []models.Post
+			if err := tx.Unscoped().Where("slug IS NULL OR slug = ''").Find(&posts).Error; err != nil {
+				return err
+			}
+			for _, post := range posts {
+				slug, err := models.GenerateUniqueSlug(tx, post.Title, post.ID)
+				if err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("slug", slug).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// 为还没有渲染 HTML 的旧文章、评论生成 HTML
+func init() {
+	Register(&Migration{
+		Version: "20261018000003",
+		Name:    "backfill_rendered_content",
+		Up: func(tx *gorm.DB) error {
+			var posts []models.Post
+			if err := tx.Unscoped().Where("content_html IS NULL OR content_html = ''").Find(&posts).Error; err != nil {
+				return err
+			}
+			for _, post := range posts {
+				if err := post.Render(); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumns(map[string]interface{}{
+					"content_html": post.ContentHTML,
+					"toc":          post.TOC,
+					"excerpt":      post.Excerpt,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			var comments []models.Comment
+			if err := tx.Unscoped().Where("content_html IS NULL OR content_html = ''").Find(&comments).Error; err != nil {
+				return err
+			}
+			for _, comment := range comments {
+				if err := comment.Render(); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&models.Comment{}).Where("id = ?", comment.ID).
+					UpdateColumn("content_html", comment.ContentHTML).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
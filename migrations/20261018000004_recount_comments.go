@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// 之前 comment_count 从未更新过，按评论表统计一次
+func init() {
+	Register(&Migration{
+		Version: "20261018000004",
+		Name:    "recount_comments",
+		Up: func(tx *gorm.DB) error {
+			_, err := models.RecountComments(tx)
+			return err
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
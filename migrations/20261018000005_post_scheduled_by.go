@@ -0,0 +1,29 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// postScheduledBy 迁移时的文章表快照，只包含新增的列
+type postScheduledBy struct {
+	ScheduledBy *uint64
+}
+
+func (postScheduledBy) TableName() string {
+	return "posts"
+}
+
+// 记录设置定时发布的用户，调度器到点发布时重新检查其发布权限
+func init() {
+	Register(&Migration{
+		Version: "20261018000005",
+		Name:    "post_scheduled_by",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&postScheduledBy{}, "ScheduledBy") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&postScheduledBy{}, "ScheduledBy")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&postScheduledBy{}, "ScheduledBy")
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// commentContentHash 迁移时的评论表快照，只包含新增的列
+type commentContentHash struct {
+	ID          uint
+	Content     string
+	ContentHash string `gorm:"size:64;index"`
+}
+
+func (commentContentHash) TableName() string {
+	return "comments"
+}
+
+// 评论内容哈希及索引，重复评论检测不再按 TEXT 列全表比较；已有评论补算哈希
+func init() {
+	Register(&Migration{
+		Version: "20261018000006",
+		Name:    "comment_content_hash",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if !migrator.HasColumn(&commentContentHash{}, "ContentHash") {
+				if err := migrator.AddColumn(&commentContentHash{}, "ContentHash"); err != nil {
+					return err
+				}
+			}
+			if !migrator.HasIndex(&commentContentHash{}, "ContentHash") {
+				if err := migrator.CreateIndex(&commentContentHash{}, "ContentHash"); err != nil {
+					return err
+				}
+			}
+			var comments []commentContentHash
+			return tx.Unscoped().Select("id", "content").Where("content_hash IS NULL OR content_hash = ''").
+				FindInBatches(&comments, 500, func(*gorm.DB, int) error {
+					for _, comment := range comments {
+						if err := tx.Unscoped().Model(&commentContentHash{}).Where("id = ?", comment.ID).
+							UpdateColumn("content_hash", models.CommentContentHash(comment.Content)).Error; err != nil {
+							return err
+						}
+					}
+					return nil
+				}).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&commentContentHash{}, "ContentHash")
+		},
+	})
+}
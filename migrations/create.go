@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+const migrationTemplate = `package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(&Migration{
+		Version: "%s",
+		Name:    "%s",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
+
+// Create 在 dir 下生成新的迁移文件，返回文件路径
+func Create(dir, name string) (string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name is required")
+	}
+	version := time.Now().Format("20060102150405")
+	path := filepath.Join(dir, version+"_"+name+".go")
+	content := fmt.Sprintf(migrationTemplate, version, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
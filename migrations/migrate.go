@@ -0,0 +1,270 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次版本化的数据库变更，Version 为创建时间（20060102150405），按版本号顺序执行
+// 使用 Go 代码 + GORM Migrator 编写，保证 MySQL / PostgreSQL / SQLite 通用
+type Migration struct {
+	Version string
+	Name    string
+	Up      func(tx *gorm.DB) error
+	// Down 为空表示不可回滚
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   string `gorm:"primaryKey;size:32"`
+	Name      string `gorm:"size:191;not null"`
+	AppliedAt time.Time
+}
+
+// SchemaMigrationLock 迁移锁，表中最多一行，防止多个实例同时执行迁移
+type SchemaMigrationLock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// 数据库中有记录但代码中不存在的迁移
+	Unknown bool
+}
+
+const (
+	lockID = 1
+	// 超过该时间的锁视为执行迁移的实例已崩溃，可以被接管
+	staleLockAfter = 10 * time.Minute
+	// 持有锁期间刷新 locked_at 的间隔，远小于 staleLockAfter
+	lockRefreshInterval = staleLockAfter / 5
+)
+
+// LockTimeout 等待迁移锁的最长时间
+var LockTimeout = 2 * time.Minute
+
+var registry = map[string]*Migration{}
+
+// Register 注册迁移，通常在迁移文件的 init 中调用
+func Register(m *Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic("duplicate migration version: " + m.Version)
+	}
+	registry[m.Version] = m
+}
+
+// All 按版本号排序的全部迁移
+func All() []*Migration {
+	list := make([]*Migration, 0, len(registry))
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+// Up 执行所有未执行的迁移，target 不为空时只执行到该版本（包含）
+func Up(db *gorm.DB, target string) error {
+	if target != "" && registry[target] == nil {
+		return fmt.Errorf("unknown migration version: %s", target)
+	}
+	return withLock(db, func() error {
+		applied, err := appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range All() {
+			if target != "" && m.Version > target {
+				break
+			}
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(db, m, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移
+func Down(db *gorm.DB, steps int) error {
+	return withLock(db, func() error {
+		var records []SchemaMigration
+		if err := db.Order("version desc").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+		for _, record := range records {
+			m := registry[record.Version]
+			if m == nil {
+				return fmt.Errorf("migration %s_%s not found in code", record.Version, record.Name)
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %s_%s is irreversible", m.Version, m.Name)
+			}
+			if err := runMigration(db, m, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Pending 返回未执行的迁移，只读取 schema_migrations，不建表（就绪检查会频繁调用）
+// 迁移记录表不存在时所有迁移都未执行
+func Pending(db *gorm.DB) ([]*Migration, error) {
+	applied := map[string]SchemaMigration{}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		var err error
+		if applied, err = appliedVersions(db); err != nil {
+			return nil, err
+		}
+	}
+	var pending []*Migration
+	for _, m := range All() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// StatusList 返回全部迁移的执行状态
+func StatusList(db *gorm.DB) ([]Status, error) {
+	if err := ensureTables(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	var list []Status
+	for _, m := range All() {
+		status := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, m.Version)
+		}
+		list = append(list, status)
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		list = append(list, Status{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// runMigration 在事务中执行单个迁移并更新 schema_migrations
+// 注意 MySQL 的 DDL 会隐式提交，失败时可能需要手动处理
+func runMigration(db *gorm.DB, m *Migration, up bool) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if up {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		}
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+	})
+	if err != nil {
+		direction := "up"
+		if !up {
+			direction = "down"
+		}
+		return fmt.Errorf("migration %s_%s %s: %w", m.Version, m.Name, direction, err)
+	}
+	return nil
+}
+
+func appliedVersions(db *gorm.DB) (map[string]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// ensureTables 创建迁移记录表和锁表，多个实例同时创建时以表已存在为准
+func ensureTables(db *gorm.DB) error {
+	for _, table := range []interface{}{&SchemaMigration{}, &SchemaMigrationLock{}} {
+		if err := db.AutoMigrate(table); err != nil && !db.Migrator().HasTable(table) {
+			return err
+		}
+	}
+	return nil
+}
+
+// withLock 获取迁移锁后执行 fn
+func withLock(db *gorm.DB, fn func() error) error {
+	if err := ensureTables(db); err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		err := db.Create(&SchemaMigrationLock{ID: lockID, Owner: owner, LockedAt: time.Now()}).Error
+		if err == nil {
+			break
+		}
+		// 接管过期的锁
+		db.Where("id = ? AND locked_at < ?", lockID, time.Now().Add(-staleLockAfter)).Delete(&SchemaMigrationLock{})
+		if time.Now().After(deadline) {
+			var lock SchemaMigrationLock
+			db.First(&lock, lockID)
+			return errors.New("timeout waiting for migration lock held by " + lock.Owner)
+		}
+		time.Sleep(time.Second)
+	}
+	defer db.Where("id = ? AND owner = ?", lockID, owner).Delete(&SchemaMigrationLock{})
+	stop := refreshLock(db, owner, lockRefreshInterval)
+	defer stop()
+	return fn()
+}
+
+// refreshLock 定期刷新锁的 locked_at，耗时超过 staleLockAfter 的迁移不会被其他实例当作过期锁接管
+// 返回的 stop 停止刷新并等待刷新协程退出
+func refreshLock(db *gorm.DB, owner string, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				db.Model(&SchemaMigrationLock{}).Where("id = ? AND owner = ?", lockID, owner).Update("locked_at", time.Now())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
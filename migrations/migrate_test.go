@@ -0,0 +1,230 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接各自独立
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// useRegistry 测试期间只注册给定的迁移
+func useRegistry(t *testing.T, list ...*Migration) {
+	t.Helper()
+	old := registry
+	registry = map[string]*Migration{}
+	for _, m := range list {
+		Register(m)
+	}
+	t.Cleanup(func() { registry = old })
+}
+
+// tableMigration 创建、删除一张只有 id 列的表
+func tableMigration(version, table string) *Migration {
+	return &Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(table)
+		},
+	}
+}
+
+func pendingVersions(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	pending, err := Pending(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make([]string, 0, len(pending))
+	for _, m := range pending {
+		versions = append(versions, m.Version)
+	}
+	return strings.Join(versions, ",")
+}
+
+func TestUpDownPending(t *testing.T) {
+	useRegistry(t, tableMigration("3", "c"), tableMigration("1", "a"), tableMigration("2", "b"))
+	db := openSQLite(t)
+
+	// 迁移记录表不存在时全部未执行，且 Pending 不建表
+	if got := pendingVersions(t, db); got != "1,2,3" {
+		t.Fatalf("pending = %s, want 1,2,3", got)
+	}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Fatal("Pending created schema_migrations")
+	}
+
+	if err := Up(db, "9"); err == nil {
+		t.Error("Up to unknown version succeeded")
+	}
+	if err := Up(db, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingVersions(t, db); got != "3" {
+		t.Fatalf("pending after Up(2) = %s, want 3", got)
+	}
+	if err := Up(db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingVersions(t, db); got != "" {
+		t.Fatalf("pending after Up = %s, want none", got)
+	}
+	for _, table := range []string{"a", "b", "c"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s not created", table)
+		}
+	}
+
+	// 倒序回滚
+	if err := Down(db, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingVersions(t, db); got != "2,3" {
+		t.Fatalf("pending after Down(2) = %s, want 2,3", got)
+	}
+	if !db.Migrator().HasTable("a") || db.Migrator().HasTable("b") || db.Migrator().HasTable("c") {
+		t.Error("Down(2) did not drop exactly b and c")
+	}
+
+	// 执行完成后释放锁
+	var locks int64
+	db.Model(&SchemaMigrationLock{}).Count(&locks)
+	if locks != 0 {
+		t.Errorf("locks after migrations = %d, want 0", locks)
+	}
+}
+
+func TestUpFailureRollsBack(t *testing.T) {
+	failing := &Migration{
+		Version: "2",
+		Name:    "failing",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE partial (id INTEGER PRIMARY KEY)").Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO missing VALUES (1)").Error
+		},
+	}
+	useRegistry(t, tableMigration("1", "a"), failing, tableMigration("3", "c"))
+	db := openSQLite(t)
+
+	err := Up(db, "")
+	if err == nil || !strings.Contains(err.Error(), "2_failing up") {
+		t.Fatalf("Up err = %v, want failure of 2_failing", err)
+	}
+	if got := pendingVersions(t, db); got != "2,3" {
+		t.Errorf("pending = %s, want 2,3", got)
+	}
+	if db.Migrator().HasTable("partial") {
+		t.Error("failed migration was not rolled back")
+	}
+
+	// 不可回滚的迁移
+	useRegistry(t, &Migration{Version: "1", Name: "irreversible", Up: func(*gorm.DB) error { return nil }})
+	db = openSQLite(t)
+	if err := Up(db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := Down(db, 1); err == nil || !strings.Contains(err.Error(), "irreversible") {
+		t.Errorf("Down err = %v, want irreversible", err)
+	}
+}
+
+func TestLockTakeover(t *testing.T) {
+	useRegistry(t, tableMigration("1", "a"))
+	db := openSQLite(t)
+	if err := ensureTables(db); err != nil {
+		t.Fatal(err)
+	}
+	old := LockTimeout
+	LockTimeout = 0
+	t.Cleanup(func() { LockTimeout = old })
+
+	// 其他实例正在持有锁
+	if err := db.Create(&SchemaMigrationLock{ID: lockID, Owner: "other", LockedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(db, ""); err == nil || !strings.Contains(err.Error(), "held by other") {
+		t.Fatalf("Up err = %v, want lock timeout", err)
+	}
+	if got := pendingVersions(t, db); got != "1" {
+		t.Fatalf("pending = %s, want 1", got)
+	}
+
+	// 持有锁的实例崩溃，锁过期后被接管
+	if err := db.Model(&SchemaMigrationLock{}).Where("id = ?", lockID).
+		Update("locked_at", time.Now().Add(-staleLockAfter-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	LockTimeout = 5 * time.Second
+	if err := Up(db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingVersions(t, db); got != "" {
+		t.Errorf("pending after takeover = %s, want none", got)
+	}
+}
+
+func TestRefreshLock(t *testing.T) {
+	db := openSQLite(t)
+	if err := ensureTables(db); err != nil {
+		t.Fatal(err)
+	}
+	lockedAt := time.Now().Add(-time.Hour)
+	if err := db.Create(&SchemaMigrationLock{ID: lockID, Owner: "me", LockedAt: lockedAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+	stop := refreshLock(db, "me", 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+
+	var lock SchemaMigrationLock
+	if err := db.First(&lock, lockID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !lock.LockedAt.After(lockedAt.Add(time.Minute)) {
+		t.Errorf("locked_at not refreshed: %v", lock.LockedAt)
+	}
+}
+
+// 实际的迁移在 SQLite 上可以完整执行和回滚
+func TestRegisteredMigrationsOnSQLite(t *testing.T) {
+	db := openSQLite(t)
+	if err := Up(db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := pendingVersions(t, db); got != "" {
+		t.Fatalf("pending = %s, want none", got)
+	}
+	if err := Down(db, len(All())); err != nil {
+		t.Fatal(err)
+	}
+	if db.Migrator().HasTable("posts") || db.Migrator().HasTable("post_tags") {
+		t.Error("tables remain after rolling back all migrations")
+	}
+	if err := Up(db, ""); err != nil {
+		t.Fatalf("Up after full rollback: %v", err)
+	}
+}
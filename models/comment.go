@@ -1,13 +1,75 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gavin/blog/markdown"
+	"gorm.io/gorm"
+)
+
+// MaxCommentDepth 回复的最大嵌套层数，顶层评论为第 0 层
+const MaxCommentDepth = 5
 
 type Comment struct {
 	gorm.Model
 	Content string `gorm:"not null"`
-	UserID  uint64
-	PostID  uint64
-	Post    Post `gorm:"foreignKey:PostID;"`
+	// Markdown（受限子集）渲染并净化后的 HTML
+	ContentHTML string `gorm:"type:text"`
+	// 去掉首尾空白后内容的 SHA-256，检测重复评论时按它查询
+	ContentHash string `gorm:"size:64;index"`
+	UserID      uint64
+	PostID      uint64
+	Post        Post `gorm:"foreignKey:PostID;"`
+	// 回复的上级评论，顶层评论为空
+	ParentID *uint64 `gorm:"index"`
+	// 嵌套层数，顶层评论为 0
+	Depth int `gorm:"not null;default:0"`
+	// 审核状态，公开列表只展示 approved 和 deleted（占位）
+	Status string `gorm:"size:20;not null;default:approved;index"`
+	// 垃圾评论检测得分和原因（每行一条），供审核员参考
+	SpamScore   float64 `gorm:"not null;default:0"`
+	SpamReasons string  `gorm:"type:text"`
+	// 乐观锁版本号，每次修改内容加 1
+	Version int `gorm:"not null;default:1"`
 	// 映射查询User表会把用户的信息查不来，只取ID就好
 	//User    User `gorm:"foreignKey:UserID;"`
 }
+
+// Render 渲染评论 Markdown，同时更新内容哈希
+func (c *Comment) Render() error {
+	html, err := markdown.RenderComment(c.Content)
+	if err != nil {
+		return err
+	}
+	c.ContentHTML = html
+	c.ContentHash = CommentContentHash(c.Content)
+	return nil
+}
+
+// CommentContentHash 去掉首尾空白后计算评论内容的哈希
+func CommentContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CanReply 是否还能继续回复（已通过审核且未超过最大嵌套层数）
+func (c *Comment) CanReply() bool {
+	return c.IsApproved() && c.Depth < MaxCommentDepth
+}
+
+// IsApproved 是否已通过审核
+func (c *Comment) IsApproved() bool {
+	return c.Status == CommentStatusApproved
+}
+
+// IsDeleted 是否为已删除的占位评论
+func (c *Comment) IsDeleted() bool {
+	return c.Status == CommentStatusDeleted
+}
+
+// IsListed 是否公开展示：已通过审核的评论和已删除的占位评论
+func (c *Comment) IsListed() bool {
+	return c.IsApproved() || c.IsDeleted()
+}
@@ -0,0 +1,26 @@
+package models
+
+import "gorm.io/gorm"
+
+// AdjustCommentCount 在事务中增减文章的评论数（只统计已通过审核且未删除的评论）
+func AdjustCommentCount(tx *gorm.DB, postID uint64, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	return tx.Model(&Post{}).Where("id = ?", postID).
+		UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta)).Error
+}
+
+// RecountComments 按评论表重新统计文章评论数，postIDs 为空时统计全部文章，返回更新的文章数
+func RecountComments(db *gorm.DB, postIDs ...uint64) (int64, error) {
+	count := db.Model(&Comment{}).Select("COUNT(*)").
+		Where("comments.post_id = posts.id AND comments.status = ?", CommentStatusApproved)
+	query := db.Model(&Post{})
+	if len(postIDs) > 0 {
+		query = query.Where("id IN ?", postIDs)
+	} else {
+		query = query.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	result := query.UpdateColumn("comment_count", count)
+	return result.RowsAffected, result.Error
+}
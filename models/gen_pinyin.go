@@ -0,0 +1,72 @@
+//go:build ignore
+
+// 生成 pinyin_table.go：用 ICU 的 uconv（Han-Latin 转写）取 CJK 统一表意文字基本区每个汉字的常用读音
+// 用法：go generate ./models（需要安装 icu-devtools）
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	first = 0x4E00
+	last  = 0x9FFF
+)
+
+var syllable = regexp.MustCompile(`^[a-z]+$`)
+
+func main() {
+	var input strings.Builder
+	for r := rune(first); r <= last; r++ {
+		input.WriteRune(r)
+		input.WriteByte('\n')
+	}
+	cmd := exec.Command("uconv", "-x", "Han-Latin; Latin-ASCII; Lower")
+	cmd.Stdin = strings.NewReader(input.String())
+	out, err := cmd.Output()
+	if err != nil {
+		log.Fatalf("uconv: %v", err)
+	}
+
+	table := make(map[string][]rune)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for r := rune(first); r <= last && scanner.Scan(); r++ {
+		// 没有读音的字符原样输出，跳过
+		if py := strings.TrimSpace(scanner.Text()); syllable.MatchString(py) {
+			table[py] = append(table[py], r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	syllables := make([]string, 0, len(table))
+	for py := range table {
+		syllables = append(syllables, py)
+	}
+	sort.Strings(syllables)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_pinyin.go; DO NOT EDIT.\n\npackage models\n\n")
+	b.WriteString("// pinyinTable 读音 -> 该读音的汉字（U+4E00 - U+9FFF，多音字只取常用读音）\n")
+	b.WriteString("var pinyinTable = map[string]string{\n")
+	for _, py := range syllables {
+		fmt.Fprintf(&b, "\t%q: %q,\n", py, string(table[py]))
+	}
+	b.WriteString("}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("pinyin_table.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 评论状态
+const (
+	CommentStatusPending  = "pending"  // 待审核
+	CommentStatusApproved = "approved" // 已通过
+	CommentStatusRejected = "rejected" // 已拒绝
+	CommentStatusSpam     = "spam"     // 垃圾评论
+	CommentStatusDeleted  = "deleted"  // 已删除，内容已清空，只为保留其下的回复
+)
+
+// 文章的评论审核方式
+const (
+	PostModerationInherit = ""     // 使用全局设置
+	PostModerationOpen    = "open" // 评论直接通过
+	PostModerationHold    = "hold" // 评论全部需要审核（可信用户按全局设置处理）
+)
+
+// ModerationSetting 全局评论审核设置，表中只有一行
+type ModerationSetting struct {
+	gorm.Model
+	// 所有评论都需要审核
+	HoldAll bool `gorm:"not null"`
+	// 首次评论的用户（还没有通过审核的评论）需要审核
+	HoldFirstTime bool `gorm:"not null"`
+	// 可信用户的评论自动通过
+	AutoApproveTrusted bool `gorm:"not null"`
+	// 通过审核的评论数达到该值即视为可信用户，0 表示只有拥有 comment:moderate 权限的用户可信
+	TrustedThreshold int `gorm:"not null"`
+}
+
+// DefaultModerationSetting 默认：首次评论需要审核，可信用户自动通过
+func DefaultModerationSetting() ModerationSetting {
+	return ModerationSetting{
+		HoldFirstTime:      true,
+		AutoApproveTrusted: true,
+		TrustedThreshold:   3,
+	}
+}
+
+// LoadModerationSetting 读取全局审核设置，不存在时写入默认值
+func LoadModerationSetting(db *gorm.DB) (ModerationSetting, error) {
+	var setting ModerationSetting
+	err := db.Order("id asc").First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		setting = DefaultModerationSetting()
+		err = db.Create(&setting).Error
+	}
+	return setting, err
+}
+
+// Decide 根据文章设置和评论者情况决定新评论的状态
+func (s *ModerationSetting) Decide(postMode string, trusted, firstTime bool) string {
+	if postMode == PostModerationOpen {
+		return CommentStatusApproved
+	}
+	if trusted && s.AutoApproveTrusted {
+		return CommentStatusApproved
+	}
+	if postMode == PostModerationHold || s.HoldAll {
+		return CommentStatusPending
+	}
+	if firstTime && s.HoldFirstTime {
+		return CommentStatusPending
+	}
+	return CommentStatusApproved
+}
+
+// IsValidCommentStatus 判断评论状态是否合法
+func IsValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusRejected, CommentStatusSpam:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestModerationSettingDecide(t *testing.T) {
+	cases := []struct {
+		name      string
+		setting   ModerationSetting
+		postMode  string
+		trusted   bool
+		firstTime bool
+		want      string
+	}{
+		{"default first comment", DefaultModerationSetting(), PostModerationInherit, false, true, CommentStatusPending},
+		{"default returning user", DefaultModerationSetting(), PostModerationInherit, false, false, CommentStatusApproved},
+		{"default trusted", DefaultModerationSetting(), PostModerationInherit, true, false, CommentStatusApproved},
+		{"hold all", ModerationSetting{HoldAll: true}, PostModerationInherit, false, false, CommentStatusPending},
+		{"hold all trusted auto approve", ModerationSetting{HoldAll: true, AutoApproveTrusted: true}, PostModerationInherit, true, false, CommentStatusApproved},
+		{"hold all trusted without auto approve", ModerationSetting{HoldAll: true}, PostModerationInherit, true, false, CommentStatusPending},
+		{"post open overrides hold all", ModerationSetting{HoldAll: true, HoldFirstTime: true}, PostModerationOpen, false, true, CommentStatusApproved},
+		{"post hold", ModerationSetting{}, PostModerationHold, false, false, CommentStatusPending},
+		{"post hold trusted", ModerationSetting{AutoApproveTrusted: true}, PostModerationHold, true, false, CommentStatusApproved},
+		{"open setting", ModerationSetting{}, PostModerationInherit, false, true, CommentStatusApproved},
+	}
+	for _, c := range cases {
+		if got := c.setting.Decide(c.postMode, c.trusted, c.firstTime); got != c.want {
+			t.Errorf("%s: %s, want %s", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "sync"
+
+//go:generate go run gen_pinyin.go
+
+var (
+	pinyinOnce sync.Once
+	pinyinMap  map[rune]string
+)
+
+// Pinyin 汉字的不带声调拼音，多音字取常用读音，如 '重' -> "zhong"；不在表中的字符返回 false
+func Pinyin(r rune) (string, bool) {
+	pinyinOnce.Do(func() {
+		pinyinMap = make(map[rune]string, 21000)
+		for py, chars := range pinyinTable {
+			for _, c := range chars {
+				pinyinMap[c] = py
+			}
+		}
+	})
+	py, ok := pinyinMap[r]
+	return py, ok
+}
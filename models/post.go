@@ -1,14 +1,107 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/gavin/blog/markdown"
+	"gorm.io/gorm"
+)
+
+// 文章状态
+const (
+	PostStatusDraft         = "draft"
+	PostStatusPendingReview = "pending_review"
+	PostStatusPublished     = "published"
+	PostStatusArchived      = "archived"
+)
+
+// 文章状态流转动作
+const (
+	PostActionSubmit    = "submit"
+	PostActionPublish   = "publish"
+	PostActionUnpublish = "unpublish"
+	PostActionArchive   = "archive"
+)
+
+// 每个动作允许的起始状态和目标状态
+var postTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	PostActionSubmit:    {from: []string{PostStatusDraft}, to: PostStatusPendingReview},
+	PostActionPublish:   {from: []string{PostStatusDraft, PostStatusPendingReview, PostStatusArchived}, to: PostStatusPublished},
+	PostActionUnpublish: {from: []string{PostStatusPublished, PostStatusPendingReview}, to: PostStatusDraft},
+	PostActionArchive:   {from: []string{PostStatusDraft, PostStatusPublished}, to: PostStatusArchived},
+}
 
 type Post struct {
 	gorm.Model
-	Title        string `gorm:"not null"`
-	Content      string `gorm:"not null"`
-	UserID       uint64
-	Comments     []Comment `gorm:"foreignKey:PostID;"`
-	CommentCount int
+	Title string `gorm:"not null"`
+	// 唯一 slug，旧数据迁移时为 NULL，启动时回填
+	Slug    string `gorm:"size:191;uniqueIndex;default:null"`
+	Content string `gorm:"not null"`
+	// Markdown 渲染并净化后的 HTML、目录和摘要，保存时生成
+	ContentHTML string `gorm:"type:text"`
+	TOC         TOC    `gorm:"type:text"`
+	Excerpt     string `gorm:"size:1024"`
+	UserID      uint64
+	// 已有数据迁移后默认为已发布，新建文章由 AddPost 设为草稿
+	Status      string `gorm:"size:20;not null;default:published;index"`
+	PublishedAt *time.Time
+	// 定时发布时间，由后台调度器到点发布
+	ScheduledAt *time.Time `gorm:"index"`
+	// 设置定时发布的用户，到点发布时重新检查其发布权限；为空时检查作者
+	ScheduledBy *uint64
+	CategoryID  *uint64   `gorm:"index"`
+	Category    *Category `gorm:"foreignKey:CategoryID;"`
+	Tags        []Tag     `gorm:"many2many:post_tags;"`
+	Comments    []Comment `gorm:"foreignKey:PostID;"`
+	// 已通过审核且未删除的评论数，随评论增删、审核同步更新
+	CommentCount int `gorm:"not null;default:0;index"`
+	// 评论审核方式：空（使用全局设置）、open、hold
+	CommentModeration string `gorm:"size:20;not null;default:''"`
+	// 乐观锁版本号，每次修改内容加 1
+	Version int `gorm:"not null;default:1"`
 	// 映射查询User表会把用户的信息查不来，只取ID就好
 	//User        User `gorm:"foreignKey:UserID;"`
 }
+
+// Transition 按动作流转文章状态，不允许的流转返回 false
+func (p *Post) Transition(action string) bool {
+	transition, ok := postTransitions[action]
+	if !ok || !containsString(transition.from, p.Status) {
+		return false
+	}
+	p.Status = transition.to
+	// 手动流转会取消定时发布
+	p.ScheduledAt = nil
+	p.ScheduledBy = nil
+	// 首次发布时记录发布时间，重新发布保留原时间
+	if p.Status == PostStatusPublished && p.PublishedAt == nil {
+		now := time.Now()
+		p.PublishedAt = &now
+	}
+	return true
+}
+
+// IsPublished 文章是否对所有人可见
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
+}
+
+// CanSchedule 只有草稿和待审核的文章可以设置定时发布
+func (p *Post) CanSchedule() bool {
+	return p.Status == PostStatusDraft || p.Status == PostStatusPendingReview
+}
+
+// Render 渲染 Markdown 正文，生成 HTML、目录和摘要
+func (p *Post) Render() error {
+	rendered, err := markdown.RenderPost(p.Content)
+	if err != nil {
+		return err
+	}
+	p.ContentHTML = rendered.HTML
+	p.TOC = rendered.TOC
+	p.Excerpt = rendered.Excerpt
+	return nil
+}
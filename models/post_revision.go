@@ -0,0 +1,56 @@
+package models
+
+import "gorm.io/gorm"
+
+// PostRevision 文章修订记录，每次保存文章时记录一份完整快照
+type PostRevision struct {
+	gorm.Model
+	PostID  uint64 `gorm:"uniqueIndex:idx_post_revision;not null"`
+	Version int    `gorm:"uniqueIndex:idx_post_revision;not null"`
+	// 本次修订的操作人
+	UserID  uint64 `gorm:"not null"`
+	Title   string `gorm:"not null"`
+	Content string `gorm:"type:text;not null"`
+	// 修订说明，如 "restore from r3"（修订号）
+	Note string `gorm:"size:255"`
+}
+
+// CreatePostRevision 为文章当前内容创建新的修订记录，需在事务中调用
+// 旧文章还没有任何修订时，先把修改前的内容补记为第一个版本
+func CreatePostRevision(tx *gorm.DB, post *Post, before *Post, userID uint64, note string) (*PostRevision, error) {
+	var latest PostRevision
+	err := tx.Where("post_id = ?", post.ID).Order("version desc").Limit(1).Find(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+
+	version := latest.Version
+	if version == 0 && before != nil {
+		version++
+		initial := &PostRevision{
+			PostID:  uint64(post.ID),
+			Version: version,
+			UserID:  before.UserID,
+			Title:   before.Title,
+			Content: before.Content,
+			Note:    "initial",
+		}
+		initial.CreatedAt = before.UpdatedAt
+		if err := tx.Create(initial).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	revision := &PostRevision{
+		PostID:  uint64(post.ID),
+		Version: version + 1,
+		UserID:  userID,
+		Title:   post.Title,
+		Content: post.Content,
+		Note:    note,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostTransition(t *testing.T) {
+	statuses := []string{PostStatusDraft, PostStatusPendingReview, PostStatusPublished, PostStatusArchived}
+	want := map[string]map[string]string{
+		PostActionSubmit:    {PostStatusDraft: PostStatusPendingReview},
+		PostActionPublish:   {PostStatusDraft: PostStatusPublished, PostStatusPendingReview: PostStatusPublished, PostStatusArchived: PostStatusPublished},
+		PostActionUnpublish: {PostStatusPublished: PostStatusDraft, PostStatusPendingReview: PostStatusDraft},
+		PostActionArchive:   {PostStatusDraft: PostStatusArchived, PostStatusPublished: PostStatusArchived},
+		"unknown":           {},
+	}
+	for action, allowed := range want {
+		for _, from := range statuses {
+			post := Post{Status: from}
+			to, ok := allowed[from]
+			if got := post.Transition(action); got != ok {
+				t.Errorf("%s from %s = %v, want %v", action, from, got, ok)
+				continue
+			}
+			if !ok {
+				to = from
+			}
+			if post.Status != to {
+				t.Errorf("%s from %s: status %s, want %s", action, from, post.Status, to)
+			}
+		}
+	}
+}
+
+func TestPostTransitionSideEffects(t *testing.T) {
+	at := time.Now().Add(time.Hour)
+	by := uint64(1)
+	post := Post{Status: PostStatusDraft, ScheduledAt: &at, ScheduledBy: &by}
+	if !post.Transition(PostActionPublish) {
+		t.Fatal("publish draft")
+	}
+	// 手动流转取消定时发布，首次发布记录发布时间
+	if post.ScheduledAt != nil || post.ScheduledBy != nil {
+		t.Error("schedule not cleared")
+	}
+	if post.PublishedAt == nil {
+		t.Fatal("published_at not set")
+	}
+	first := *post.PublishedAt
+
+	post.Transition(PostActionUnpublish)
+	post.Transition(PostActionPublish)
+	if !post.PublishedAt.Equal(first) {
+		t.Errorf("republish changed published_at to %v, want %v", post.PublishedAt, first)
+	}
+}
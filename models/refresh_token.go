@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken 刷新令牌，只保存令牌的哈希值
+// 每次刷新都会轮换出新令牌，旧令牌被标记为已撤销；
+// 已撤销的令牌再次被使用视为泄露，整个家族（FamilyID）一并撤销
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint64     `gorm:"index;not null"`
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null"`
+	FamilyID  string     `gorm:"size:32;index;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time `gorm:"index"`
+}
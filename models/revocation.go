@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken 被撤销的访问令牌（按 jti 记录），过期后即可清理
+type RevokedToken struct {
+	gorm.Model
+	JTI       string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// UserTokenRevocation 用户级撤销：RevokedAt 之前签发的令牌全部失效（退出所有会话、修改密码）
+type UserTokenRevocation struct {
+	gorm.Model
+	UserID    uint64    `gorm:"uniqueIndex;not null"`
+	RevokedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import "strings"
+
+// 角色
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleReader = "reader"
+)
+
+// 注册用户的默认角色
+const DefaultRole = RoleAuthor
+
+// 权限
+const (
+	PermPostCreate      = "post:create"      // 发表文章
+	PermPostEdit        = "post:edit"        // 编辑任意文章
+	PermPostDelete      = "post:delete"      // 删除任意文章
+	PermPostPublish     = "post:publish"     // 发布文章
+	PermCommentCreate   = "comment:create"   // 发表评论
+	PermCommentModerate = "comment:moderate" // 编辑/删除任意评论
+	PermUserManage      = "user:manage"      // 管理用户角色
+	PermTaxonomyManage  = "taxonomy:manage"  // 管理标签和分类
+)
+
+// 所有权限，用于校验管理员额外授予的权限
+var allPermissions = []string{
+	PermPostCreate, PermPostEdit, PermPostDelete, PermPostPublish,
+	PermCommentCreate, PermCommentModerate, PermUserManage, PermTaxonomyManage,
+}
+
+// 角色拥有的权限
+var rolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermPostCreate, PermPostEdit, PermPostDelete, PermPostPublish,
+		PermCommentCreate, PermCommentModerate, PermUserManage, PermTaxonomyManage,
+	},
+	RoleEditor: {
+		PermPostCreate, PermPostEdit, PermPostDelete, PermPostPublish,
+		PermCommentCreate, PermCommentModerate, PermTaxonomyManage,
+	},
+	// 作者的文章需提交审核，由编辑发布；需要直接发布时由管理员额外授予 post:publish
+	RoleAuthor: {PermPostCreate, PermCommentCreate},
+	RoleReader: {PermCommentCreate},
+}
+
+// IsValidRole 判断角色是否存在
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsValidPermission 判断权限是否存在
+func IsValidPermission(permission string) bool {
+	return containsString(allPermissions, permission)
+}
+
+// ResolvePermissions 合并角色权限和用户额外授予的权限（逗号分隔）
+func ResolvePermissions(role string, extra string) []string {
+	permissions := append([]string{}, rolePermissions[role]...)
+	for _, p := range strings.Split(extra, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" && !containsString(permissions, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
+// PermissionList 用户拥有的全部权限
+func (u *User) PermissionList() []string {
+	return ResolvePermissions(u.Role, u.Permissions)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
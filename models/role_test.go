@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestAuthorCannotPublishByDefault(t *testing.T) {
+	author := &User{Role: RoleAuthor}
+	if containsString(author.PermissionList(), PermPostPublish) {
+		t.Fatal("author should not have post:publish by default")
+	}
+	author.Permissions = PermPostPublish
+	if !containsString(author.PermissionList(), PermPostPublish) {
+		t.Fatal("explicitly granted post:publish should be kept")
+	}
+}
+
+func TestIsValidPermission(t *testing.T) {
+	for _, p := range rolePermissions[RoleAdmin] {
+		if !IsValidPermission(p) {
+			t.Errorf("IsValidPermission(%q) = false", p)
+		}
+	}
+	for _, p := range []string{"", "post:*", "POST:PUBLISH", "root"} {
+		if IsValidPermission(p) {
+			t.Errorf("IsValidPermission(%q) = true", p)
+		}
+	}
+}
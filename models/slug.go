@@ -0,0 +1,154 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// slug 最大长度（按字符计）
+const maxSlugLength = 80
+
+// Transliterate 字符转写函数，默认把汉字转成拼音，可替换为其他词典；
+// 返回 false 时该字符无法转写，不写入 slug（见 Slugify）
+var Transliterate = Pinyin
+
+// PostSlugHistory 文章的历史 slug，标题修改后旧链接通过它 301 跳转到新 slug
+type PostSlugHistory struct {
+	gorm.Model
+	PostID uint64 `gorm:"index;not null"`
+	Slug   string `gorm:"size:191;uniqueIndex;not null"`
+}
+
+// Slugify 将标题转换为只含 a-z、0-9 和 "-" 的 slug
+// 拉丁字母去掉重音符号并转小写，汉字转为拼音，如 "Go 语言入门" -> "go-yu-yan-ru-men"，其余字符统一替换为 "-"；
+// 假名等无法转写的字符不写入 slug，改为追加标题的短哈希，如 "こんにちは" -> "5e6f7a8b"
+func Slugify(title string) string {
+	var b strings.Builder
+	lastDash := true
+	count := 0
+	dropped := false
+	write := func(s string) {
+		for _, r := range s {
+			if count >= maxSlugLength {
+				return
+			}
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				b.WriteRune(unicode.ToLower(r))
+				lastDash = false
+			} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				dropped = true
+				if !lastDash {
+					b.WriteByte('-')
+					lastDash = true
+				}
+			} else if !lastDash {
+				b.WriteByte('-')
+				lastDash = true
+			}
+			count++
+		}
+	}
+
+	// NFKD 分解后去掉组合符号：é -> e，全角字符 -> 半角
+	for _, r := range norm.NFKD.String(title) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if Transliterate != nil {
+			if s, ok := Transliterate(r); ok {
+				// 转写结果之间用 "-" 分隔，如 "你好" -> "ni-hao"
+				if !lastDash {
+					write("-")
+				}
+				write(s)
+				write("-")
+				continue
+			}
+		}
+		write(string(r))
+	}
+
+	slug := strings.Trim(b.String(), "-")
+	if dropped {
+		if slug != "" {
+			slug += "-"
+		}
+		return slug + titleHash(title)
+	}
+	if slug == "" {
+		return "post"
+	}
+	return slug
+}
+
+// titleHash 标题的 8 位十六进制哈希，同一标题总是得到相同的 slug，冲突时由 UniqueSlug 追加序号
+func titleHash(title string) string {
+	h := fnv.New32a()
+	h.Write([]byte(title))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// GenerateUniqueSlug 根据标题生成唯一 slug，冲突时追加 -2、-3 ...
+// 其他文章的当前 slug 和历史 slug 都视为冲突，本文章自己的历史 slug 可以复用
+func GenerateUniqueSlug(db *gorm.DB, title string, postID uint) (string, error) {
+	return UniqueSlug(title, func(slug string) (bool, error) {
+		return SlugTaken(db, slug, postID)
+	})
+}
+
+// UniqueSlug 根据标题生成 slug，taken 判断候选 slug 是否已被占用，冲突时追加 -2、-3 ...
+func UniqueSlug(title string, taken func(slug string) (bool, error)) (string, error) {
+	base := Slugify(title)
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		used, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no available slug for %q", base)
+}
+
+// SlugTaken slug 是否已被其他文章占用（包括已删除文章和历史 slug）
+func SlugTaken(db *gorm.DB, slug string, postID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&Post{}).Unscoped().Where("slug = ? AND id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := db.Model(&PostSlugHistory{}).Where("slug = ? AND post_id <> ?", slug, postID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ChangePostSlug 修改文章 slug 并把旧 slug 记入历史，需在事务中调用
+func ChangePostSlug(tx *gorm.DB, post *Post, newSlug string) error {
+	if post.Slug == newSlug {
+		return nil
+	}
+	if post.Slug != "" {
+		if err := tx.Create(&PostSlugHistory{PostID: uint64(post.ID), Slug: post.Slug}).Error; err != nil {
+			return err
+		}
+	}
+	// 改回以前用过的 slug 时删除对应的历史记录
+	if err := tx.Unscoped().Where("post_id = ? AND slug = ?", post.ID, newSlug).Delete(&PostSlugHistory{}).Error; err != nil {
+		return err
+	}
+	post.Slug = newSlug
+	return nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var asciiSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!":       "hello-world",
+		"  Café  au   lait ":  "cafe-au-lait",
+		"Ｇｏ　１．２５ 发布":          "go-1-25-fa-bu",
+		"こんにちは":               titleHash("こんにちは"),
+		"Go 言語 ガイド":           "go-yan-yu-" + titleHash("Go 言語 ガイド"),
+		"":                    "post",
+		"---":                 "post",
+		"C++ & C# in 2026":    "c-c-in-2026",
+		"Ünïcödé — dashes ––": "unicode-dashes",
+	}
+	for title, want := range cases {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestSlugifyIsASCII(t *testing.T) {
+	for _, title := range []string{"你好世界", "Go 语言入门", "こんにちは", "Привет мир", "😀 emoji"} {
+		got := Slugify(title)
+		if !asciiSlug.MatchString(got) {
+			t.Errorf("Slugify(%q) = %q, not an ASCII slug", title, got)
+		}
+	}
+	if Slugify("こんにちは") == Slugify("さようなら") {
+		t.Error("different untransliterated titles should get different slugs")
+	}
+}
+
+func TestSlugifyPinyin(t *testing.T) {
+	cases := map[string]string{
+		"你好世界":            "ni-hao-shi-jie",
+		"Go 语言入门":         "go-yu-yan-ru-men",
+		"重庆火锅：2026 年最新指南": "zhong-qing-huo-guo-2026-nian-zui-xin-zhi-nan",
+		"後端開發":            "hou-duan-kai-fa",
+		"绿色":              "lu-se",
+	}
+	for title, want := range cases {
+		if got := Slugify(title); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestSlugifyTransliterate(t *testing.T) {
+	defer func(old func(rune) (string, bool)) { Transliterate = old }(Transliterate)
+	Transliterate = func(r rune) (string, bool) {
+		switch r {
+		case '你':
+			return "ni", true
+		case '好':
+			return "hao", true
+		}
+		return "", false
+	}
+	if got := Slugify("你好 Go"); got != "ni-hao-go" {
+		t.Errorf("Slugify with transliteration = %q, want ni-hao-go", got)
+	}
+}
+
+func TestSlugifyMaxLength(t *testing.T) {
+	got := Slugify(strings.Repeat("a", 200))
+	if len(got) != maxSlugLength {
+		t.Errorf("len(Slugify) = %d, want %d", len(got), maxSlugLength)
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	taken := map[string]bool{"hello": true, "hello-2": true}
+	got, err := UniqueSlug("Hello", func(slug string) (bool, error) { return taken[slug], nil })
+	if err != nil || got != "hello-3" {
+		t.Errorf("UniqueSlug = %q, %v, want hello-3", got, err)
+	}
+}
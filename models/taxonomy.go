@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxPostTags 每篇文章最多的标签数
+const MaxPostTags = 10
+
+// 并发创建标签撞上唯一索引时的最大重试次数
+const tagRetries = 3
+
+// ErrTagExists 标签名已被其他标签使用（不区分大小写）
+var ErrTagExists = errors.New("tag already exists")
+
+// Tag 标签，与文章多对多
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"size:50;uniqueIndex;not null"`
+	Slug string `gorm:"size:191;uniqueIndex;not null"`
+}
+
+// TagWithCount 标签及其已发布文章数
+type TagWithCount struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	PostCount int64  `json:"post_count"`
+}
+
+// Category 分类，支持多级（ParentID 为空表示顶级分类）
+type Category struct {
+	gorm.Model
+	Name     string     `gorm:"size:50;not null"`
+	Slug     string     `gorm:"size:191;uniqueIndex;not null"`
+	ParentID *uint64    `gorm:"index"`
+	Children []Category `gorm:"foreignKey:ParentID" json:",omitempty"`
+}
+
+// CategoryDescendantIDs 返回分类自身及所有子孙分类的 ID
+func CategoryDescendantIDs(db *gorm.DB, rootID uint64) ([]uint64, error) {
+	var categories []Category
+	if err := db.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return DescendantCategoryIDs(categories, rootID), nil
+}
+
+// DescendantCategoryIDs 在给定的分类列表中查找 rootID 自身及所有子孙分类的 ID
+func DescendantCategoryIDs(categories []Category, rootID uint64) []uint64 {
+	children := make(map[uint64][]uint64)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], uint64(category.ID))
+		}
+	}
+
+	ids := []uint64{rootID}
+	visited := map[uint64]bool{rootID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// FindOrCreateTags 按名称查找标签（不区分大小写），不存在则创建，返回顺序与名称一致
+func FindOrCreateTags(db *gorm.DB, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		tag, err := FindTagByName(db, name, 0)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag, err = CreateTag(db, name)
+			if errors.Is(err, ErrTagExists) {
+				// 其他请求刚创建了同名标签
+				tag, err = FindTagByName(db, name, 0)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, nil
+}
+
+// FindTagByName 按名称查找标签，不区分大小写，excludeID 不为 0 时排除该标签
+func FindTagByName(db *gorm.DB, name string, excludeID uint) (*Tag, error) {
+	var tag Tag
+	err := db.Where("LOWER(name) = ? AND id <> ?", strings.ToLower(name), excludeID).Take(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag 创建标签，名称已存在时返回 ErrTagExists；slug 冲突时追加序号（"C++"、"C#" 分别为 c、c-2）
+// 在保存点中插入，撞上唯一索引时不影响外层事务
+func CreateTag(db *gorm.DB, name string) (*Tag, error) {
+	for i := 0; i < tagRetries; i++ {
+		if _, err := FindTagByName(db, name, 0); err == nil {
+			return nil, ErrTagExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		slug, err := UniqueTagSlug(db, name, 0)
+		if err != nil {
+			return nil, err
+		}
+		tag := &Tag{Name: name, Slug: slug}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(tag).Error
+		})
+		if err == nil {
+			return tag, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, err
+		}
+	}
+	return nil, ErrTagExists
+}
+
+// UniqueTagSlug 根据名称生成其他标签未使用的 slug
+func UniqueTagSlug(db *gorm.DB, name string, tagID uint) (string, error) {
+	return UniqueSlug(name, func(slug string) (bool, error) {
+		var count int64
+		err := db.Model(&Tag{}).Unscoped().Where("slug = ? AND id <> ?", slug, tagID).Count(&count).Error
+		return count > 0, err
+	})
+}
+
+// NormalizeTagNames 去掉首尾空格、空值和重复标签，最多保留 MaxPostTags 个
+func NormalizeTagNames(names []string) []string {
+	result := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		result = append(result, name)
+	}
+	if len(result) > MaxPostTags {
+		result = result[:MaxPostTags]
+	}
+	return result
+}
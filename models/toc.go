@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gavin/blog/markdown"
+)
+
+// TOC 文章目录，以 JSON 存储
+type TOC []markdown.TOCItem
+
+func (t TOC) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (t *TOC) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = TOC{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported toc type: %T", value)
+	}
+	if len(data) == 0 {
+		*t = TOC{}
+		return nil
+	}
+	return json.Unmarshal(data, t)
+}
@@ -4,9 +4,12 @@ import "gorm.io/gorm"
 
 type User struct {
 	gorm.Model
-	Username string    `gorm:"unique;not null"`
-	Password string    `gorm:"not null"`
-	Email    string    `gorm:"unique;not null"`
-	Posts    []Post    `gorm:"foreignKey:UserID;"`
-	Comments []Comment `gorm:"foreignKey:UserID;"`
+	Username string `gorm:"unique;not null"`
+	Password string `gorm:"not null"`
+	Email    string `gorm:"unique;not null"`
+	Role     string `gorm:"size:20;not null;default:author"`
+	// 在角色之外额外授予的权限，逗号分隔，如 "comment:moderate"
+	Permissions string    `gorm:"size:255"`
+	Posts       []Post    `gorm:"foreignKey:UserID;"`
+	Comments    []Comment `gorm:"foreignKey:UserID;"`
 }
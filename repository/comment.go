@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+)
+
+// CommentFilter 评论列表查询条件，零值字段不过滤，结果按 ID 升序
+type CommentFilter struct {
+	Visibility Visibility
+	// PostVisibility 不为空时只包含可见文章下的评论
+	PostVisibility *Visibility
+	PostID         uint64
+	UserID         uint64
+	// ParentID 不为空时只包含它的直接回复，TopLevel 为 true 时只包含顶层评论
+	ParentID *uint64
+	TopLevel bool
+	// Status 只包含该状态的评论
+	Status string
+	// AfterID 只包含 ID 大于它的评论，用于游标分页
+	AfterID uint64
+	// Desc 按 ID 倒序
+	Desc bool
+}
+
+// CommentRepository 评论存储，新增、删除、审核时同步维护文章的评论数
+type CommentRepository interface {
+	// FindByID 查询评论，附带所属文章
+	FindByID(ctx context.Context, id uint64) (*models.Comment, error)
+	// Find 按条件查询，limit 小于等于 0 时不限制条数
+	Find(ctx context.Context, filter CommentFilter, limit int) ([]models.Comment, error)
+	Page(ctx context.Context, filter CommentFilter, page *utils.Pagination) ([]models.Comment, int64, error)
+	// FindReplies 一次查出每条评论按 ID 升序的前 limit 条可见直接回复，按上级评论 ID 分组
+	FindReplies(ctx context.Context, parentIDs []uint64, visibility Visibility, limit int) (map[uint64][]models.Comment, error)
+	// CountReplies 统计每条评论可见的直接回复数
+	CountReplies(ctx context.Context, parentIDs []uint64, visibility Visibility) (map[uint64]int64, error)
+	// CountApproved 用户已通过审核的评论数
+	CountApproved(ctx context.Context, userID uint64) (int64, error)
+
+	// Create 保存评论，已通过审核时文章评论数加 1
+	Create(ctx context.Context, comment *models.Comment) error
+	// UpdateContent 按版本号条件保存评论内容、审核状态和垃圾检测结果，版本号加 1，版本不一致返回 ErrConflict；
+	// 状态改变时同一事务内调整文章评论数
+	UpdateContent(ctx context.Context, comment *models.Comment, version int) error
+	// Delete 删除评论，还有回复时只清空内容并标记为 deleted 作为占位，回复保留；
+	// 上级是占位评论且已没有其他回复时一并删除。扣除已通过审核的评论数，返回被删除或清空的评论 ID
+	Delete(ctx context.Context, comment *models.Comment) ([]uint64, error)
+	// Moderate 逐条按原状态条件修改评论状态，已是该状态的评论和已删除的占位评论跳过，
+	// 并发审核同一条评论时只有一次生效；同一事务内调整文章评论数，返回实际修改的评论 ID
+	Moderate(ctx context.Context, ids []uint64, status string) ([]uint64, error)
+	// Recount 按评论重新统计文章评论数，postIDs 为空时统计全部文章
+	Recount(ctx context.Context, postIDs ...uint64) (int64, error)
+
+	// ModerationSetting 全局评论审核设置
+	ModerationSetting(ctx context.Context) (*models.ModerationSetting, error)
+	// SaveModerationSetting 保存 ModerationSetting 返回的全局审核设置
+	SaveModerationSetting(ctx context.Context, setting *models.ModerationSetting) error
+}
+
+// approvedDelta 状态从 from 改为 to 时文章评论数的变化
+func approvedDelta(from, to string) int {
+	switch {
+	case from != models.CommentStatusApproved && to == models.CommentStatusApproved:
+		return 1
+	case from == models.CommentStatusApproved && to != models.CommentStatusApproved:
+		return -1
+	}
+	return 0
+}
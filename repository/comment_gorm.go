@@ -0,0 +1,314 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+	"gorm.io/gorm"
+)
+
+// GormCommentRepository 基于 GORM 的评论存储
+type GormCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewGormCommentRepository(db *gorm.DB) *GormCommentRepository {
+	return &GormCommentRepository{db: db}
+}
+
+func (r *GormCommentRepository) FindByID(ctx context.Context, id uint64) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).Preload("Post").First(&comment, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &comment, nil
+}
+
+func (r *GormCommentRepository) Find(ctx context.Context, filter CommentFilter, limit int) ([]models.Comment, error) {
+	query := r.query(ctx, filter)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var comments []models.Comment
+	if err := query.Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *GormCommentRepository) Page(ctx context.Context, filter CommentFilter, page *utils.Pagination) ([]models.Comment, int64, error) {
+	var comments []models.Comment
+	result, err := utils.GetPaginatedData(r.query(ctx, filter), &comments, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	return comments, result.Total, nil
+}
+
+func (r *GormCommentRepository) query(ctx context.Context, filter CommentFilter) *gorm.DB {
+	db := r.db.WithContext(ctx)
+	query := db.Model(&models.Comment{}).Scopes(commentScope(filter.Visibility))
+	if filter.PostVisibility != nil {
+		query = query.Where("post_id IN (?)", db.Model(&models.Post{}).Select("id").Scopes(postScope(*filter.PostVisibility)))
+	}
+	if filter.PostID > 0 {
+		query = query.Where("post_id = ?", filter.PostID)
+	}
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	} else if filter.TopLevel {
+		query = query.Where("parent_id IS NULL")
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.AfterID > 0 {
+		query = query.Where("id > ?", filter.AfterID)
+	}
+	if filter.Desc {
+		return query.Order("id desc")
+	}
+	return query.Order("id asc")
+}
+
+func (r *GormCommentRepository) FindReplies(ctx context.Context, parentIDs []uint64, visibility Visibility, limit int) (map[uint64][]models.Comment, error) {
+	replies := make(map[uint64][]models.Comment, len(parentIDs))
+	if len(parentIDs) == 0 || limit <= 0 {
+		return replies, nil
+	}
+	db := r.db.WithContext(ctx)
+	// 按上级评论分组编号，外层只取每组的前 limit 条
+	// 窗口函数需要 MySQL 8.0+、SQLite 3.25+，PostgreSQL 均支持
+	ranked := db.Model(&models.Comment{}).Scopes(commentScope(visibility)).
+		Select("comments.*, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY id) AS reply_rank").
+		Where("parent_id IN ?", parentIDs)
+	var comments []models.Comment
+	// 子查询中已排除软删除的记录
+	if err := db.Unscoped().Table("(?) AS ranked", ranked).
+		Where("reply_rank <= ?", limit).Order("id asc").
+		Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		replies[*comment.ParentID] = append(replies[*comment.ParentID], comment)
+	}
+	return replies, nil
+}
+
+func (r *GormCommentRepository) CountReplies(ctx context.Context, parentIDs []uint64, visibility Visibility) (map[uint64]int64, error) {
+	counts := make(map[uint64]int64, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		ParentID uint64
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Comment{}).Scopes(commentScope(visibility)).
+		Select("parent_id, COUNT(*) AS count").
+		Where("parent_id IN ?", parentIDs).
+		Group("parent_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *GormCommentRepository) CountApproved(ctx context.Context, userID uint64) (int64, error) {
+	var approved int64
+	err := r.db.WithContext(ctx).Model(&models.Comment{}).
+		Where("user_id = ? AND status = ?", userID, models.CommentStatusApproved).
+		Count(&approved).Error
+	return approved, err
+}
+
+func (r *GormCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		if comment.IsApproved() {
+			return models.AdjustCommentCount(tx, comment.PostID, 1)
+		}
+		return nil
+	})
+}
+
+func (r *GormCommentRepository) UpdateContent(ctx context.Context, comment *models.Comment, version int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stored models.Comment
+		err := tx.Select("id", "status").Where("id = ? AND version = ?", comment.ID, version).Take(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		// 状态也作为条件，避免与同时进行的审核操作互相覆盖
+		result := tx.Model(&models.Comment{}).
+			Where("id = ? AND version = ? AND status = ?", comment.ID, version, stored.Status).
+			Updates(map[string]interface{}{
+				"content":      comment.Content,
+				"content_html": comment.ContentHTML,
+				"content_hash": comment.ContentHash,
+				"status":       comment.Status,
+				"spam_score":   comment.SpamScore,
+				"spam_reasons": comment.SpamReasons,
+				"version":      gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+		comment.Version = version + 1
+		return models.AdjustCommentCount(tx, comment.PostID, approvedDelta(stored.Status, comment.Status))
+	})
+}
+
+func (r *GormCommentRepository) Delete(ctx context.Context, comment *models.Comment) ([]uint64, error) {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids = []uint64{uint64(comment.ID)}
+		approved := 0
+		if comment.IsApproved() {
+			approved = 1
+		}
+		replies, err := r.countChildren(tx, comment.ID)
+		if err != nil {
+			return err
+		}
+		if replies > 0 {
+			// 其他用户的回复保留，评论本身只留下占位
+			if err := tx.Model(&models.Comment{}).Where("id = ?", comment.ID).Updates(map[string]interface{}{
+				"status":       models.CommentStatusDeleted,
+				"content":      "",
+				"content_html": "",
+				"content_hash": "",
+				"spam_reasons": "",
+				"version":      gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			return models.AdjustCommentCount(tx, comment.PostID, -approved)
+		}
+		if err := tx.Delete(&models.Comment{}, comment.ID).Error; err != nil {
+			return err
+		}
+		// 上级是占位评论且已没有其他回复时一并删除
+		for parentID := comment.ParentID; parentID != nil; {
+			var parent models.Comment
+			err := tx.Select("id", "parent_id").
+				Where("id = ? AND status = ?", *parentID, models.CommentStatusDeleted).
+				Take(&parent).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if replies, err = r.countChildren(tx, parent.ID); err != nil {
+				return err
+			}
+			if replies > 0 {
+				break
+			}
+			if err := tx.Delete(&models.Comment{}, parent.ID).Error; err != nil {
+				return err
+			}
+			ids = append(ids, uint64(parent.ID))
+			parentID = parent.ParentID
+		}
+		return models.AdjustCommentCount(tx, comment.PostID, -approved)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// countChildren 统计直接回复数，包括未通过审核的回复
+func (r *GormCommentRepository) countChildren(tx *gorm.DB, id uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Comment{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *GormCommentRepository) Moderate(ctx context.Context, ids []uint64, status string) ([]uint64, error) {
+	var updated []uint64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updated = nil
+		var comments []models.Comment
+		if err := tx.Select("id", "post_id", "status").
+			// 已删除的占位评论没有内容，不参与审核
+			Where("id IN ? AND status NOT IN ?", ids, []string{status, models.CommentStatusDeleted}).
+			Find(&comments).Error; err != nil {
+			return err
+		}
+		deltas := make(map[uint64]int)
+		for _, comment := range comments {
+			result := tx.Model(&models.Comment{}).
+				Where("id = ? AND status = ?", comment.ID, comment.Status).
+				Update("status", status)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			updated = append(updated, uint64(comment.ID))
+			deltas[comment.PostID] += approvedDelta(comment.Status, status)
+		}
+		for postId, delta := range deltas {
+			if err := models.AdjustCommentCount(tx, postId, delta); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *GormCommentRepository) Recount(ctx context.Context, postIDs ...uint64) (int64, error) {
+	return models.RecountComments(r.db.WithContext(ctx), postIDs...)
+}
+
+func (r *GormCommentRepository) ModerationSetting(ctx context.Context) (*models.ModerationSetting, error) {
+	setting, err := models.LoadModerationSetting(r.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func (r *GormCommentRepository) SaveModerationSetting(ctx context.Context, setting *models.ModerationSetting) error {
+	return r.db.WithContext(ctx).Save(setting).Error
+}
+
+// 公开展示的评论状态
+var listedCommentStatuses = []string{models.CommentStatusApproved, models.CommentStatusDeleted}
+
+// commentScope 评论的可见范围：已通过审核的评论和占位评论 + 自己的评论
+func commentScope(v Visibility) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if v.All {
+			return db
+		}
+		if v.UserID == 0 {
+			return db.Where("status IN ?", listedCommentStatuses)
+		}
+		return db.Where("status IN ? OR user_id = ?", listedCommentStatuses, v.UserID)
+	}
+}
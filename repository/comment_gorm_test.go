@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gavin/blog/models"
+)
+
+func TestGormFindRepliesLimitsPerParent(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := NewGormCommentRepository(db)
+
+	post := &models.Post{Title: "t", Content: "c", Status: models.PostStatusPublished}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatal(err)
+	}
+	add := func(parentID uint64, userID uint64, status string) *models.Comment {
+		comment := &models.Comment{Content: "c", PostID: uint64(post.ID), UserID: userID, Status: status}
+		if parentID > 0 {
+			comment.ParentID = &parentID
+			comment.Depth = 1
+		}
+		if err := db.Create(comment).Error; err != nil {
+			t.Fatal(err)
+		}
+		return comment
+	}
+	first := uint64(add(0, 1, models.CommentStatusApproved).ID)
+	second := uint64(add(0, 1, models.CommentStatusApproved).ID)
+	empty := uint64(add(0, 1, models.CommentStatusApproved).ID)
+
+	// 别人待审核的回复、已删除的回复不占名额
+	add(first, 2, models.CommentStatusPending)
+	deleted := add(first, 1, models.CommentStatusApproved)
+	if err := db.Delete(deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	var want []uint
+	for i := 0; i < 4; i++ {
+		want = append(want, add(first, 1, models.CommentStatusApproved).ID)
+	}
+	own := add(second, 3, models.CommentStatusPending)
+
+	replies, err := repo.FindReplies(ctx, []uint64{first, second, empty}, Visibility{UserID: 3}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint
+	for _, reply := range replies[first] {
+		got = append(got, reply.ID)
+	}
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("first replies = %v, want %v", got, want[:2])
+	}
+	// 自己待审核的回复可见
+	if len(replies[second]) != 1 || replies[second][0].ID != own.ID {
+		t.Errorf("second replies = %+v, want own pending reply", replies[second])
+	}
+	if len(replies[empty]) != 0 {
+		t.Errorf("empty replies = %+v", replies[empty])
+	}
+}
@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+)
+
+// MemoryCommentRepository 基于内存的评论存储
+type MemoryCommentRepository struct {
+	data *memoryData
+}
+
+// SetModerationSetting 修改全局审核设置，测试时用它准备数据
+func (r *MemoryCommentRepository) SetModerationSetting(setting models.ModerationSetting) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	r.data.setting = &setting
+}
+
+func (r *MemoryCommentRepository) FindByID(ctx context.Context, id uint64) (*models.Comment, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	comment, ok := r.data.comments[uint(id)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	// 文章已删除时与 Preload 一样得到零值
+	comment.Post = r.data.posts[uint(comment.PostID)]
+	comment.Post.Tags = append([]models.Tag(nil), comment.Post.Tags...)
+	return &comment, nil
+}
+
+func (r *MemoryCommentRepository) Find(ctx context.Context, filter CommentFilter, limit int) ([]models.Comment, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	comments := r.filter(filter)
+	if limit > 0 && len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+func (r *MemoryCommentRepository) Page(ctx context.Context, filter CommentFilter, page *utils.Pagination) ([]models.Comment, int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	comments, total := pageOf(r.filter(filter), page)
+	return comments, total, nil
+}
+
+// filter 按条件筛选并排序，调用方需持有锁
+func (r *MemoryCommentRepository) filter(filter CommentFilter) []models.Comment {
+	ids := sortedIDs(r.data.comments)
+	if filter.Desc {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	}
+	comments := make([]models.Comment, 0)
+	for _, id := range ids {
+		comment := r.data.comments[id]
+		if !filter.Visibility.visible(comment.IsListed(), comment.UserID) ||
+			(filter.PostID > 0 && comment.PostID != filter.PostID) ||
+			(filter.UserID > 0 && comment.UserID != filter.UserID) ||
+			(filter.ParentID != nil && (comment.ParentID == nil || *comment.ParentID != *filter.ParentID)) ||
+			(filter.ParentID == nil && filter.TopLevel && comment.ParentID != nil) ||
+			(filter.Status != "" && comment.Status != filter.Status) ||
+			uint64(comment.ID) <= filter.AfterID {
+			continue
+		}
+		if filter.PostVisibility != nil {
+			post, ok := r.data.posts[uint(comment.PostID)]
+			if !ok || !filter.PostVisibility.visible(post.IsPublished(), post.UserID) {
+				continue
+			}
+		}
+		comments = append(comments, comment)
+	}
+	return comments
+}
+
+func (r *MemoryCommentRepository) FindReplies(ctx context.Context, parentIDs []uint64, visibility Visibility, limit int) (map[uint64][]models.Comment, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	replies := make(map[uint64][]models.Comment, len(parentIDs))
+	if limit <= 0 {
+		return replies, nil
+	}
+	wanted := make(map[uint64]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		wanted[id] = true
+	}
+	for _, id := range sortedIDs(r.data.comments) {
+		comment := r.data.comments[id]
+		if comment.ParentID == nil || !wanted[*comment.ParentID] ||
+			!visibility.visible(comment.IsListed(), comment.UserID) ||
+			len(replies[*comment.ParentID]) >= limit {
+			continue
+		}
+		replies[*comment.ParentID] = append(replies[*comment.ParentID], comment)
+	}
+	return replies, nil
+}
+
+func (r *MemoryCommentRepository) CountReplies(ctx context.Context, parentIDs []uint64, visibility Visibility) (map[uint64]int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	counts := make(map[uint64]int64, len(parentIDs))
+	wanted := make(map[uint64]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		wanted[id] = true
+	}
+	for _, comment := range r.data.comments {
+		if comment.ParentID != nil && wanted[*comment.ParentID] &&
+			visibility.visible(comment.IsListed(), comment.UserID) {
+			counts[*comment.ParentID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *MemoryCommentRepository) CountApproved(ctx context.Context, userID uint64) (int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	var approved int64
+	for _, comment := range r.data.comments {
+		if comment.UserID == userID && comment.IsApproved() {
+			approved++
+		}
+	}
+	return approved, nil
+}
+
+func (r *MemoryCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	if comment.Status == "" {
+		comment.Status = models.CommentStatusApproved
+	}
+	if comment.Version == 0 {
+		comment.Version = 1
+	}
+	r.data.stamp(&comment.Model)
+	stored := *comment
+	stored.Post = models.Post{}
+	r.data.comments[comment.ID] = stored
+	if comment.IsApproved() {
+		r.adjustCount(comment.PostID, 1)
+	}
+	return nil
+}
+
+func (r *MemoryCommentRepository) UpdateContent(ctx context.Context, comment *models.Comment, version int) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	stored, ok := r.data.comments[comment.ID]
+	if !ok || stored.Version != version {
+		return ErrConflict
+	}
+	stored.Content = comment.Content
+	stored.ContentHTML = comment.ContentHTML
+	stored.ContentHash = comment.ContentHash
+	r.adjustCount(stored.PostID, approvedDelta(stored.Status, comment.Status))
+	stored.Status = comment.Status
+	stored.SpamScore = comment.SpamScore
+	stored.SpamReasons = comment.SpamReasons
+	stored.Version = version + 1
+	stored.UpdatedAt = time.Now()
+	r.data.comments[comment.ID] = stored
+	comment.Version = stored.Version
+	return nil
+}
+
+func (r *MemoryCommentRepository) Delete(ctx context.Context, comment *models.Comment) ([]uint64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	approved := 0
+	if comment.IsApproved() {
+		approved = 1
+	}
+	ids := []uint64{uint64(comment.ID)}
+	if r.hasChildren(comment.ID) {
+		stored := r.data.comments[comment.ID]
+		stored.Status = models.CommentStatusDeleted
+		stored.Content = ""
+		stored.ContentHTML = ""
+		stored.ContentHash = ""
+		stored.SpamReasons = ""
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		r.data.comments[comment.ID] = stored
+		r.adjustCount(comment.PostID, -approved)
+		return ids, nil
+	}
+	delete(r.data.comments, comment.ID)
+	for parentID := comment.ParentID; parentID != nil; {
+		parent, ok := r.data.comments[uint(*parentID)]
+		if !ok || !parent.IsDeleted() || r.hasChildren(parent.ID) {
+			break
+		}
+		delete(r.data.comments, parent.ID)
+		ids = append(ids, uint64(parent.ID))
+		parentID = parent.ParentID
+	}
+	r.adjustCount(comment.PostID, -approved)
+	return ids, nil
+}
+
+// hasChildren 是否还有直接回复，调用方需持有锁
+func (r *MemoryCommentRepository) hasChildren(id uint) bool {
+	for _, comment := range r.data.comments {
+		if comment.ParentID != nil && *comment.ParentID == uint64(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryCommentRepository) Recount(ctx context.Context, postIDs ...uint64) (int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	counts := make(map[uint64]int)
+	for _, comment := range r.data.comments {
+		if comment.IsApproved() {
+			counts[comment.PostID]++
+		}
+	}
+	if len(postIDs) == 0 {
+		for id := range r.data.posts {
+			postIDs = append(postIDs, uint64(id))
+		}
+	}
+	var updated int64
+	for _, id := range postIDs {
+		post, ok := r.data.posts[uint(id)]
+		if !ok {
+			continue
+		}
+		post.CommentCount = counts[id]
+		r.data.posts[uint(id)] = post
+		updated++
+	}
+	return updated, nil
+}
+
+func (r *MemoryCommentRepository) Moderate(ctx context.Context, ids []uint64, status string) ([]uint64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	var updated []uint64
+	for _, id := range ids {
+		stored, ok := r.data.comments[uint(id)]
+		if !ok || stored.Status == status || stored.IsDeleted() {
+			continue
+		}
+		r.adjustCount(stored.PostID, approvedDelta(stored.Status, status))
+		stored.Status = status
+		stored.UpdatedAt = time.Now()
+		r.data.comments[uint(id)] = stored
+		updated = append(updated, id)
+	}
+	return updated, nil
+}
+
+// adjustCount 调整文章评论数，调用方需持有锁
+func (r *MemoryCommentRepository) adjustCount(postID uint64, delta int) {
+	if post, ok := r.data.posts[uint(postID)]; ok {
+		post.CommentCount += delta
+		r.data.posts[uint(postID)] = post
+	}
+}
+
+func (r *MemoryCommentRepository) ModerationSetting(ctx context.Context) (*models.ModerationSetting, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	if r.data.setting == nil {
+		setting := models.DefaultModerationSetting()
+		r.data.setting = &setting
+	}
+	setting := *r.data.setting
+	return &setting, nil
+}
+
+func (r *MemoryCommentRepository) SaveModerationSetting(ctx context.Context, setting *models.ModerationSetting) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	stored := *setting
+	stored.UpdatedAt = time.Now()
+	r.data.setting = &stored
+	return nil
+}
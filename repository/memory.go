@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+	"gorm.io/gorm"
+)
+
+// memoryData 内存仓储共享的数据，所有读写都持有同一把锁，相当于串行化的事务
+type memoryData struct {
+	mu         sync.Mutex
+	nextID     uint
+	users      map[uint]models.User
+	tokens     map[uint]models.RefreshToken
+	posts      map[uint]models.Post
+	revisions  []models.PostRevision
+	oldSlugs   map[string]uint
+	tags       map[uint]models.Tag
+	categories map[uint]models.Category
+	comments   map[uint]models.Comment
+	setting    *models.ModerationSetting
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		users:      make(map[uint]models.User),
+		tokens:     make(map[uint]models.RefreshToken),
+		posts:      make(map[uint]models.Post),
+		oldSlugs:   make(map[string]uint),
+		tags:       make(map[uint]models.Tag),
+		categories: make(map[uint]models.Category),
+		comments:   make(map[uint]models.Comment),
+	}
+}
+
+// stamp 为新记录分配 ID 并设置创建时间，ID 在所有表之间递增
+func (d *memoryData) stamp(m *gorm.Model) {
+	d.nextID++
+	now := time.Now()
+	m.ID = d.nextID
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
+
+// sortedIDs 按 ID 升序返回 map 的键
+func sortedIDs[T any](m map[uint]T) []uint {
+	ids := make([]uint, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// pageOf 对已排序的结果做内存分页
+func pageOf[T any](items []T, page *utils.Pagination) ([]T, int64) {
+	total := int64(len(items))
+	offset := page.Offset()
+	if offset >= len(items) {
+		return []T{}, total
+	}
+	end := offset + page.PageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end], total
+}
+
+// visible 记录是否在可见范围内，public 表示记录本身是公开的
+func (v Visibility) visible(public bool, ownerID uint64) bool {
+	return v.All || public || (v.UserID != 0 && v.UserID == ownerID)
+}
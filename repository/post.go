@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+)
+
+// PostFilter 文章列表查询条件，零值字段不过滤
+type PostFilter struct {
+	Visibility Visibility
+	UserID     uint64
+	Status     string
+	// 标签名称或 slug
+	Tag         string
+	CategoryIDs []uint64
+	// MostDiscussed 按评论数倒序，默认按 ID 倒序
+	MostDiscussed bool
+}
+
+// PostRepository 文章存储，返回的文章都附带标签和分类
+type PostRepository interface {
+	FindByID(ctx context.Context, id uint64) (*models.Post, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Post, error)
+	// FindByOldSlug 按历史 slug 查找文章
+	FindByOldSlug(ctx context.Context, slug string) (*models.Post, error)
+	FindByUser(ctx context.Context, userID uint64) ([]models.Post, error)
+	Page(ctx context.Context, filter PostFilter, page *utils.Pagination) ([]models.Post, int64, error)
+	// SlugTaken slug 是否已被其他文章占用（包括历史 slug）
+	SlugTaken(ctx context.Context, slug string, postID uint) (bool, error)
+	CategoryExists(ctx context.Context, id uint64) (bool, error)
+	// CategoryDescendantIDs 分类自身及所有子孙分类的 ID
+	CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error)
+
+	// Revisions 文章的修订记录（不含正文），按修订号倒序
+	Revisions(ctx context.Context, postID uint64) ([]models.PostRevision, error)
+	// FindRevision 按修订号查找修订，number 为 0 时返回最新的修订
+	FindRevision(ctx context.Context, postID uint64, number int) (*models.PostRevision, error)
+
+	// Create 保存新文章、第一个修订和标签
+	Create(ctx context.Context, post *models.Post, tags []string) error
+	// Save 按 before.Version 条件保存文章内容并记录修订，版本号加 1
+	// slug 变化时旧 slug 记入历史；tags 为 nil 时不修改标签；版本不一致返回 ErrConflict
+	Save(ctx context.Context, post *models.Post, before *models.Post, userID uint64, note string, tags []string) error
+	// UpdateStatus 按原状态条件保存状态、发布时间和定时发布时间，状态已变更返回 ErrConflict
+	UpdateStatus(ctx context.Context, post *models.Post, from string) error
+	// Schedule 按原状态条件设置定时发布时间和设置人，状态已变更返回 ErrConflict
+	Schedule(ctx context.Context, id uint, status string, at time.Time, by uint64) error
+	Unschedule(ctx context.Context, id uint) error
+	// ClaimScheduled 认领最多 limit 篇到期的定时文章（草稿、待审核）并清除其定时发布时间，按计划时间升序
+	// 返回的文章保留原定时发布时间和设置人；多实例同时认领时每篇文章只会被一个实例认领
+	ClaimScheduled(ctx context.Context, now time.Time, limit int) ([]models.Post, error)
+	SetCommentModeration(ctx context.Context, id uint, mode string) error
+	Delete(ctx context.Context, id uint) error
+}
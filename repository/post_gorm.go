@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormPostRepository 基于 GORM 的文章存储
+type GormPostRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPostRepository(db *gorm.DB) *GormPostRepository {
+	return &GormPostRepository{db: db}
+}
+
+func (r *GormPostRepository) FindByID(ctx context.Context, id uint64) (*models.Post, error) {
+	return r.first(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *GormPostRepository) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	return r.first(r.db.WithContext(ctx).Where("slug = ?", slug))
+}
+
+func (r *GormPostRepository) FindByOldSlug(ctx context.Context, slug string) (*models.Post, error) {
+	db := r.db.WithContext(ctx)
+	var history models.PostSlugHistory
+	if err := db.Where("slug = ?", slug).First(&history).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return r.first(db.Where("id = ?", history.PostID))
+}
+
+func (r *GormPostRepository) first(query *gorm.DB) (*models.Post, error) {
+	var post models.Post
+	if err := query.Preload("Tags").Preload("Category").First(&post).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &post, nil
+}
+
+func (r *GormPostRepository) FindByUser(ctx context.Context, userID uint64) ([]models.Post, error) {
+	var posts []models.Post
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Preload("Tags").Preload("Category").Order("id desc").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (r *GormPostRepository) Page(ctx context.Context, filter PostFilter, page *utils.Pagination) ([]models.Post, int64, error) {
+	db := r.db.WithContext(ctx)
+	query := db.Model(&models.Post{}).Scopes(postScope(filter.Visibility))
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", db.Table("post_tags").Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ? OR tags.slug = ?", filter.Tag, filter.Tag))
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.MostDiscussed {
+		query = query.Order("comment_count desc")
+	}
+	query = query.Order("id desc")
+
+	var posts []models.Post
+	result, err := utils.GetPaginatedData(query, &posts, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadTaxonomy(db, posts); err != nil {
+		return nil, 0, err
+	}
+	return posts, result.Total, nil
+}
+
+// loadTaxonomy 为分页查询出的文章补充标签和分类（分页的 Count 不能带 Preload）
+func (r *GormPostRepository) loadTaxonomy(db *gorm.DB, posts []models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	var loaded []models.Post
+	if err := db.Select("id", "category_id").Preload("Tags").Preload("Category").
+		Where("id IN ?", ids).Find(&loaded).Error; err != nil {
+		return err
+	}
+	byId := make(map[uint]models.Post, len(loaded))
+	for _, post := range loaded {
+		byId[post.ID] = post
+	}
+	for i := range posts {
+		posts[i].Tags = byId[posts[i].ID].Tags
+		posts[i].Category = byId[posts[i].ID].Category
+	}
+	return nil
+}
+
+func (r *GormPostRepository) SlugTaken(ctx context.Context, slug string, postID uint) (bool, error) {
+	return models.SlugTaken(r.db.WithContext(ctx), slug, postID)
+}
+
+func (r *GormPostRepository) CategoryExists(ctx context.Context, id uint64) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Category{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *GormPostRepository) CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	return models.CategoryDescendantIDs(r.db.WithContext(ctx), id)
+}
+
+func (r *GormPostRepository) Revisions(ctx context.Context, postID uint64) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	if err := r.db.WithContext(ctx).Select("id", "post_id", "version", "user_id", "title", "note", "created_at").
+		Where("post_id = ?", postID).Order("version desc").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *GormPostRepository) FindRevision(ctx context.Context, postID uint64, number int) (*models.PostRevision, error) {
+	query := r.db.WithContext(ctx).Where("post_id = ?", postID)
+	if number > 0 {
+		query = query.Where("version = ?", number)
+	}
+	var revision models.PostRevision
+	if err := query.Order("version desc").First(&revision).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &revision, nil
+}
+
+func (r *GormPostRepository) Create(ctx context.Context, post *models.Post, tags []string) error {
+	return duplicate(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if _, err := models.CreatePostRevision(tx, post, nil, post.UserID, "created"); err != nil {
+			return err
+		}
+		return replaceTags(tx, post, tags)
+	}))
+}
+
+func (r *GormPostRepository) Save(ctx context.Context, post *models.Post, before *models.Post, userID uint64, note string, tags []string) error {
+	return duplicate(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if post.Slug != before.Slug {
+			slug := post.Slug
+			post.Slug = before.Slug
+			if err := models.ChangePostSlug(tx, post, slug); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&models.Post{}).
+			Where("id = ? AND version = ?", post.ID, before.Version).
+			Updates(map[string]interface{}{
+				"title":        post.Title,
+				"content":      post.Content,
+				"content_html": post.ContentHTML,
+				"toc":          post.TOC,
+				"excerpt":      post.Excerpt,
+				"slug":         post.Slug,
+				"category_id":  post.CategoryID,
+				"version":      gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+		post.Version = before.Version + 1
+		// 标题和内容都没变化时（如只修改标签）不产生新的修订
+		if post.Title != before.Title || post.Content != before.Content {
+			if _, err := models.CreatePostRevision(tx, post, before, userID, note); err != nil {
+				return err
+			}
+		}
+		if tags != nil {
+			return replaceTags(tx, post, tags)
+		}
+		return nil
+	}))
+}
+
+// replaceTags 用给定的标签名替换文章的标签，不存在的标签自动创建
+func replaceTags(tx *gorm.DB, post *models.Post, names []string) error {
+	tags, err := models.FindOrCreateTags(tx, names)
+	if err != nil {
+		return err
+	}
+	post.Tags = tags
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
+
+func (r *GormPostRepository) UpdateStatus(ctx context.Context, post *models.Post, from string) error {
+	result := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ? AND status = ?", post.ID, from).
+		Updates(map[string]interface{}{
+			"status":       post.Status,
+			"published_at": post.PublishedAt,
+			"scheduled_at": post.ScheduledAt,
+			"scheduled_by": post.ScheduledBy,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *GormPostRepository) Schedule(ctx context.Context, id uint, status string, at time.Time, by uint64) error {
+	result := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ? AND status = ?", id, status).
+		Updates(map[string]interface{}{"scheduled_at": at, "scheduled_by": by})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *GormPostRepository) Unschedule(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"scheduled_at": nil, "scheduled_by": nil}).Error
+}
+
+func (r *GormPostRepository) ClaimScheduled(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	var claimed []models.Post
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var posts []models.Post
+		if err := dueScheduled(tx, now, limit).Find(&posts).Error; err != nil {
+			return err
+		}
+		for _, post := range posts {
+			// 不支持 SKIP LOCKED 时以清除定时发布时间为准，只有清除成功的实例认领这篇文章
+			result := tx.Model(&models.Post{}).Where("id = ? AND scheduled_at IS NOT NULL", post.ID).
+				Updates(map[string]interface{}{"scheduled_at": nil, "scheduled_by": nil})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				claimed = append(claimed, post)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// dueScheduled 到期的定时文章；MySQL 8 / PostgreSQL 加 FOR UPDATE SKIP LOCKED，其他实例跳过已被锁定的行
+func dueScheduled(tx *gorm.DB, now time.Time, limit int) *gorm.DB {
+	query := tx.Where("scheduled_at IS NOT NULL AND scheduled_at <= ? AND status IN ?", now,
+		[]string{models.PostStatusDraft, models.PostStatusPendingReview}).
+		Order("scheduled_at asc").
+		Limit(limit)
+	switch tx.Dialector.Name() {
+	case "mysql", "postgres":
+		query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	}
+	return query
+}
+
+func (r *GormPostRepository) SetCommentModeration(ctx context.Context, id uint, mode string) error {
+	return r.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).Update("comment_moderation", mode).Error
+}
+
+func (r *GormPostRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Post{}, id).Error
+}
+
+// postScope 文章的可见范围：已发布的文章 + 自己的文章
+func postScope(v Visibility) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if v.All {
+			return db
+		}
+		if v.UserID == 0 {
+			return db.Where("status = ?", models.PostStatusPublished)
+		}
+		return db.Where("status = ? OR user_id = ?", models.PostStatusPublished, v.UserID)
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openSQLite 内存 SQLite 数据库，建好文章相关的表
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接各自独立
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Tag{}, &models.Category{}, &models.Post{},
+		&models.Comment{}, &models.PostRevision{}, &models.PostSlugHistory{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestGormClaimScheduled(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := NewGormPostRepository(db)
+	now := time.Now()
+	by := uint64(7)
+
+	schedule := func(title, status string, at time.Time) *models.Post {
+		post := &models.Post{Title: title, Content: "c", Status: status, ScheduledAt: &at, ScheduledBy: &by}
+		if err := db.Create(post).Error; err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+	later := schedule("later", models.PostStatusPendingReview, now.Add(-time.Minute))
+	earlier := schedule("earlier", models.PostStatusDraft, now.Add(-time.Hour))
+	schedule("future", models.PostStatusDraft, now.Add(time.Hour))
+	schedule("published", models.PostStatusPublished, now.Add(-time.Hour))
+
+	claimed, err := repo.ClaimScheduled(ctx, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != earlier.ID || claimed[1].ID != later.ID {
+		t.Fatalf("claimed = %+v, want earlier, later", claimed)
+	}
+	// 返回的文章保留原定时信息，数据库中已清除
+	if claimed[0].ScheduledAt == nil || claimed[0].ScheduledBy == nil || *claimed[0].ScheduledBy != by {
+		t.Errorf("claimed post lost its schedule: %+v", claimed[0])
+	}
+	stored, err := repo.FindByID(ctx, uint64(earlier.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ScheduledAt != nil || stored.ScheduledBy != nil {
+		t.Errorf("stored schedule = %v by %v, want cleared", stored.ScheduledAt, stored.ScheduledBy)
+	}
+
+	// 已认领的文章不会被再次认领
+	if claimed, err = repo.ClaimScheduled(ctx, now, 10); err != nil || len(claimed) != 0 {
+		t.Errorf("second claim = %d posts, %v; want none", len(claimed), err)
+	}
+}
+
+func TestDueScheduledSkipsLockedRows(t *testing.T) {
+	dialectors := map[string]gorm.Dialector{
+		"mysql":    mysql.New(mysql.Config{DSN: "blog@tcp(127.0.0.1:3306)/blog", SkipInitializeWithVersion: true}),
+		"postgres": postgres.Open("host=127.0.0.1 dbname=blog"),
+		"sqlite":   sqlite.Open("file::memory:"),
+	}
+	for name, dialector := range dialectors {
+		// DryRun 只生成 SQL，不连接数据库
+		db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return dueScheduled(tx, time.Now(), 100).Find(&[]models.Post{})
+		})
+		if got, want := strings.Contains(sql, "FOR UPDATE SKIP LOCKED"), name != "sqlite"; got != want {
+			t.Errorf("%s: SKIP LOCKED = %v, want %v\n%s", name, got, want, sql)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/utils"
+)
+
+// MemoryPostRepository 基于内存的文章存储
+type MemoryPostRepository struct {
+	data *memoryData
+}
+
+// AddCategory 添加分类，内存存储没有分类接口，测试时用它准备数据
+func (r *MemoryPostRepository) AddCategory(category *models.Category) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	r.data.stamp(&category.Model)
+	r.data.categories[category.ID] = *category
+}
+
+func (r *MemoryPostRepository) FindByID(ctx context.Context, id uint64) (*models.Post, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	return r.find(uint(id))
+}
+
+func (r *MemoryPostRepository) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	for _, id := range sortedIDs(r.data.posts) {
+		if r.data.posts[id].Slug == slug {
+			return r.find(id)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPostRepository) FindByOldSlug(ctx context.Context, slug string) (*models.Post, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	id, ok := r.data.oldSlugs[slug]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return r.find(id)
+}
+
+// find 返回文章副本并补充分类，调用方需持有锁
+func (r *MemoryPostRepository) find(id uint) (*models.Post, error) {
+	post, ok := r.data.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post.Tags = append([]models.Tag(nil), post.Tags...)
+	post.Category = nil
+	if post.CategoryID != nil {
+		if category, ok := r.data.categories[uint(*post.CategoryID)]; ok {
+			post.Category = &category
+		}
+	}
+	return &post, nil
+}
+
+func (r *MemoryPostRepository) FindByUser(ctx context.Context, userID uint64) ([]models.Post, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	ids := sortedIDs(r.data.posts)
+	posts := make([]models.Post, 0)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if r.data.posts[ids[i]].UserID == userID {
+			post, _ := r.find(ids[i])
+			posts = append(posts, *post)
+		}
+	}
+	return posts, nil
+}
+
+func (r *MemoryPostRepository) Page(ctx context.Context, filter PostFilter, page *utils.Pagination) ([]models.Post, int64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	categories := make(map[uint64]bool, len(filter.CategoryIDs))
+	for _, id := range filter.CategoryIDs {
+		categories[id] = true
+	}
+
+	posts := make([]models.Post, 0)
+	for _, id := range sortedIDs(r.data.posts) {
+		post, _ := r.find(id)
+		if !filter.Visibility.visible(post.IsPublished(), post.UserID) ||
+			(filter.UserID > 0 && post.UserID != filter.UserID) ||
+			(filter.Status != "" && post.Status != filter.Status) ||
+			(filter.Tag != "" && !hasTag(post, filter.Tag)) ||
+			(len(categories) > 0 && (post.CategoryID == nil || !categories[*post.CategoryID])) {
+			continue
+		}
+		posts = append(posts, *post)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if filter.MostDiscussed && posts[i].CommentCount != posts[j].CommentCount {
+			return posts[i].CommentCount > posts[j].CommentCount
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	result, total := pageOf(posts, page)
+	return result, total, nil
+}
+
+func hasTag(post *models.Post, tag string) bool {
+	for _, t := range post.Tags {
+		if t.Name == tag || t.Slug == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryPostRepository) SlugTaken(ctx context.Context, slug string, postID uint) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	return r.slugTaken(slug, postID), nil
+}
+
+func (r *MemoryPostRepository) slugTaken(slug string, postID uint) bool {
+	for id, post := range r.data.posts {
+		if id != postID && slug != "" && post.Slug == slug {
+			return true
+		}
+	}
+	id, ok := r.data.oldSlugs[slug]
+	return ok && id != postID
+}
+
+func (r *MemoryPostRepository) CategoryExists(ctx context.Context, id uint64) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	_, ok := r.data.categories[uint(id)]
+	return ok, nil
+}
+
+func (r *MemoryPostRepository) CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	categories := make([]models.Category, 0, len(r.data.categories))
+	for _, category := range r.data.categories {
+		categories = append(categories, category)
+	}
+	return models.DescendantCategoryIDs(categories, id), nil
+}
+
+func (r *MemoryPostRepository) Create(ctx context.Context, post *models.Post, tags []string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	if post.Status == "" {
+		post.Status = models.PostStatusPublished
+	}
+	if post.Version == 0 {
+		post.Version = 1
+	}
+	// 与数据库的唯一索引一致
+	if r.slugTaken(post.Slug, post.ID) {
+		return ErrDuplicate
+	}
+	r.data.stamp(&post.Model)
+	post.Tags = r.findOrCreateTags(tags)
+	r.store(post)
+	r.addRevision(post, nil, post.UserID, "created")
+	return nil
+}
+
+func (r *MemoryPostRepository) Save(ctx context.Context, post *models.Post, before *models.Post, userID uint64, note string, tags []string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	current, ok := r.data.posts[post.ID]
+	if !ok || current.Version != before.Version {
+		return ErrConflict
+	}
+	if post.Slug != before.Slug && r.slugTaken(post.Slug, post.ID) {
+		return ErrDuplicate
+	}
+	if post.Slug != before.Slug {
+		if before.Slug != "" {
+			r.data.oldSlugs[before.Slug] = post.ID
+		}
+		// 改回以前用过的 slug 时删除对应的历史记录
+		delete(r.data.oldSlugs, post.Slug)
+	}
+	post.Version = before.Version + 1
+	// 与数据库一样只更新内容相关的列，评论数等由其他操作维护
+	current.Title = post.Title
+	current.Content = post.Content
+	current.ContentHTML = post.ContentHTML
+	current.TOC = post.TOC
+	current.Excerpt = post.Excerpt
+	current.Slug = post.Slug
+	current.CategoryID = post.CategoryID
+	current.Version = post.Version
+	current.UpdatedAt = time.Now()
+	if tags != nil {
+		current.Tags = r.findOrCreateTags(tags)
+		post.Tags = current.Tags
+	}
+	r.store(&current)
+	// 标题和内容都没变化时（如只修改标签）不产生新的修订
+	if post.Title != before.Title || post.Content != before.Content {
+		r.addRevision(post, before, userID, note)
+	}
+	return nil
+}
+
+// store 保存文章副本，只保留内存中维护的关联（标签），调用方需持有锁
+func (r *MemoryPostRepository) store(post *models.Post) {
+	stored := *post
+	stored.Tags = append([]models.Tag(nil), post.Tags...)
+	stored.Category = nil
+	stored.Comments = nil
+	r.data.posts[post.ID] = stored
+}
+
+// findOrCreateTags 与 models.FindOrCreateTags 相同：按名称查找标签（不区分大小写），不存在则创建，调用方需持有锁
+func (r *MemoryPostRepository) findOrCreateTags(names []string) []models.Tag {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		var found *models.Tag
+		for _, tag := range r.data.tags {
+			if strings.EqualFold(tag.Name, name) {
+				found = &tag
+				break
+			}
+		}
+		if found == nil {
+			// UniqueSlug 的 taken 不会返回错误
+			slug, _ := models.UniqueSlug(name, func(slug string) (bool, error) {
+				for _, tag := range r.data.tags {
+					if tag.Slug == slug {
+						return true, nil
+					}
+				}
+				return false, nil
+			})
+			found = &models.Tag{Name: name, Slug: slug}
+			r.data.stamp(&found.Model)
+			r.data.tags[found.ID] = *found
+		}
+		tags = append(tags, *found)
+	}
+	return tags
+}
+
+// addRevision 与 models.CreatePostRevision 相同：旧文章还没有修订时先补记修改前的内容，调用方需持有锁
+func (r *MemoryPostRepository) addRevision(post *models.Post, before *models.Post, userID uint64, note string) {
+	version := 0
+	for _, revision := range r.data.revisions {
+		if revision.PostID == uint64(post.ID) && revision.Version > version {
+			version = revision.Version
+		}
+	}
+	if version == 0 && before != nil {
+		version++
+		initial := models.PostRevision{
+			PostID:  uint64(post.ID),
+			Version: version,
+			UserID:  before.UserID,
+			Title:   before.Title,
+			Content: before.Content,
+			Note:    "initial",
+		}
+		r.data.stamp(&initial.Model)
+		r.data.revisions = append(r.data.revisions, initial)
+	}
+	revision := models.PostRevision{
+		PostID:  uint64(post.ID),
+		Version: version + 1,
+		UserID:  userID,
+		Title:   post.Title,
+		Content: post.Content,
+		Note:    note,
+	}
+	r.data.stamp(&revision.Model)
+	r.data.revisions = append(r.data.revisions, revision)
+}
+
+func (r *MemoryPostRepository) Revisions(ctx context.Context, postID uint64) ([]models.PostRevision, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	revisions := make([]models.PostRevision, 0)
+	for _, revision := range r.data.revisions {
+		if revision.PostID == postID {
+			revision.Content = ""
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Version > revisions[j].Version })
+	return revisions, nil
+}
+
+func (r *MemoryPostRepository) FindRevision(ctx context.Context, postID uint64, number int) (*models.PostRevision, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	var found *models.PostRevision
+	for i, revision := range r.data.revisions {
+		if revision.PostID != postID || (number > 0 && revision.Version != number) {
+			continue
+		}
+		if found == nil || revision.Version > found.Version {
+			found = &r.data.revisions[i]
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	revision := *found
+	return &revision, nil
+}
+
+func (r *MemoryPostRepository) UpdateStatus(ctx context.Context, post *models.Post, from string) error {
+	return r.update(post.ID, ErrConflict, func(stored *models.Post) error {
+		if stored.Status != from {
+			return ErrConflict
+		}
+		stored.Status = post.Status
+		stored.PublishedAt = post.PublishedAt
+		stored.ScheduledAt = post.ScheduledAt
+		stored.ScheduledBy = post.ScheduledBy
+		return nil
+	})
+}
+
+func (r *MemoryPostRepository) Schedule(ctx context.Context, id uint, status string, at time.Time, by uint64) error {
+	return r.update(id, ErrConflict, func(stored *models.Post) error {
+		if stored.Status != status {
+			return ErrConflict
+		}
+		stored.ScheduledAt = &at
+		stored.ScheduledBy = &by
+		return nil
+	})
+}
+
+func (r *MemoryPostRepository) Unschedule(ctx context.Context, id uint) error {
+	return r.update(id, nil, func(stored *models.Post) error {
+		stored.ScheduledAt = nil
+		stored.ScheduledBy = nil
+		return nil
+	})
+}
+
+func (r *MemoryPostRepository) ClaimScheduled(ctx context.Context, now time.Time, limit int) ([]models.Post, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	var due []models.Post
+	for _, id := range sortedIDs(r.data.posts) {
+		post := r.data.posts[id]
+		if post.ScheduledAt != nil && !post.ScheduledAt.After(now) && post.CanSchedule() {
+			due = append(due, post)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].ScheduledAt.Before(*due[j].ScheduledAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for _, post := range due {
+		stored := r.data.posts[post.ID]
+		stored.ScheduledAt = nil
+		stored.ScheduledBy = nil
+		r.data.posts[post.ID] = stored
+	}
+	return due, nil
+}
+
+func (r *MemoryPostRepository) SetCommentModeration(ctx context.Context, id uint, mode string) error {
+	return r.update(id, nil, func(stored *models.Post) error {
+		stored.CommentModeration = mode
+		return nil
+	})
+}
+
+// update 修改已保存的文章，文章不存在时返回 missing（与数据库条件更新没有匹配行时的结果一致）
+func (r *MemoryPostRepository) update(id uint, missing error, fn func(stored *models.Post) error) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	stored, ok := r.data.posts[id]
+	if !ok {
+		return missing
+	}
+	if err := fn(&stored); err != nil {
+		return err
+	}
+	stored.UpdatedAt = time.Now()
+	r.data.posts[id] = stored
+	return nil
+}
+
+func (r *MemoryPostRepository) Delete(ctx context.Context, id uint) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	delete(r.data.posts, id)
+	return nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict 带条件的更新没有生效：版本号或状态已被他人修改、令牌已被撤销
+	ErrConflict = errors.New("conditional update conflict")
+	// ErrDuplicate 违反唯一索引，如 slug 在检查之后被并发请求占用
+	ErrDuplicate = errors.New("duplicate key")
+)
+
+// Visibility 列表查询的可见范围
+// All 为 true 时不限制；否则只包含公开的记录（已发布的文章、已通过审核的评论），
+// UserID 不为 0 时再加上该用户自己的记录
+type Visibility struct {
+	All    bool
+	UserID uint64
+}
+
+// Repositories 共享同一存储的一组仓储
+type Repositories struct {
+	Users    UserRepository
+	Tokens   RefreshTokenRepository
+	Posts    PostRepository
+	Comments CommentRepository
+	Taxonomy TaxonomyRepository
+}
+
+// NewGormRepositories 基于 GORM 的仓储
+func NewGormRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:    NewGormUserRepository(db),
+		Tokens:   NewGormRefreshTokenRepository(db),
+		Posts:    NewGormPostRepository(db),
+		Comments: NewGormCommentRepository(db),
+		Taxonomy: NewGormTaxonomyRepository(db),
+	}
+}
+
+// NewMemoryRepositories 基于内存的仓储，用于单元测试和本地调试，数据不持久化
+func NewMemoryRepositories() *Repositories {
+	data := newMemoryData()
+	return &Repositories{
+		Users:    &MemoryUserRepository{data: data},
+		Tokens:   &MemoryRefreshTokenRepository{data: data},
+		Posts:    &MemoryPostRepository{data: data},
+		Comments: &MemoryCommentRepository{data: data},
+		Taxonomy: &MemoryTaxonomyRepository{data: data},
+	}
+}
+
+// notFound 把 GORM 的记录不存在错误转换为 ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// duplicate 把违反唯一索引的错误转换为 ErrDuplicate（需要 gorm.Config.TranslateError）
+func duplicate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
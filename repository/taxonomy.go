@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gavin/blog/models"
+)
+
+// TaxonomyRepository 标签和分类存储
+type TaxonomyRepository interface {
+	// Tags 所有标签及每个标签下已发布的文章数，按文章数倒序
+	Tags(ctx context.Context) ([]models.TagWithCount, error)
+	FindTag(ctx context.Context, id uint64) (*models.Tag, error)
+	// CreateTag 创建标签，名称已被其他标签使用（不区分大小写）时返回 ErrDuplicate
+	CreateTag(ctx context.Context, name string) (*models.Tag, error)
+	// RenameTag 修改标签名，名称已被其他标签使用时返回 ErrDuplicate；只改大小写等不影响 slug 时保留原 slug
+	RenameTag(ctx context.Context, tag *models.Tag, name string) error
+	// DeleteTag 在同一事务中解除文章关联并删除标签，返回原来使用该标签的文章 ID
+	DeleteTag(ctx context.Context, id uint) ([]uint, error)
+	// TagPostIDs 使用该标签的文章 ID
+	TagPostIDs(ctx context.Context, id uint) ([]uint, error)
+
+	// Categories 所有分类，按 ID 升序
+	Categories(ctx context.Context) ([]models.Category, error)
+	FindCategory(ctx context.Context, id uint64) (*models.Category, error)
+	// CategoryDescendantIDs 分类自身及所有子孙分类的 ID
+	CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error)
+	// CategorySlugTaken slug 是否已被其他分类占用（包括已删除的分类）
+	CategorySlugTaken(ctx context.Context, slug string, categoryID uint) (bool, error)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	// SaveCategory 保存分类名称、slug 和上级分类
+	SaveCategory(ctx context.Context, category *models.Category) error
+	// DeleteCategory 删除分类：子分类上移到被删除分类的上级，文章的分类置空
+	DeleteCategory(ctx context.Context, category *models.Category) error
+}
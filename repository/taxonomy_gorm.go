@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// GormTaxonomyRepository 基于 GORM 的标签和分类存储
+type GormTaxonomyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormTaxonomyRepository(db *gorm.DB) *GormTaxonomyRepository {
+	return &GormTaxonomyRepository{db: db}
+}
+
+func (r *GormTaxonomyRepository) Tags(ctx context.Context) ([]models.TagWithCount, error) {
+	var tags []models.TagWithCount
+	err := r.db.WithContext(ctx).Model(&models.Tag{}).
+		Select("tags.id, tags.name, tags.slug, COUNT(posts.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ? AND posts.deleted_at IS NULL", models.PostStatusPublished).
+		Group("tags.id, tags.name, tags.slug").
+		Order("post_count desc, tags.id asc").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *GormTaxonomyRepository) FindTag(ctx context.Context, id uint64) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.WithContext(ctx).First(&tag, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &tag, nil
+}
+
+func (r *GormTaxonomyRepository) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	tag, err := models.CreateTag(r.db.WithContext(ctx), name)
+	if errors.Is(err, models.ErrTagExists) {
+		return nil, ErrDuplicate
+	}
+	return tag, err
+}
+
+func (r *GormTaxonomyRepository) RenameTag(ctx context.Context, tag *models.Tag, name string) error {
+	db := r.db.WithContext(ctx)
+	if _, err := models.FindTagByName(db, name, tag.ID); err == nil {
+		return ErrDuplicate
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if models.Slugify(name) != models.Slugify(tag.Name) {
+		slug, err := models.UniqueTagSlug(db, name, tag.ID)
+		if err != nil {
+			return err
+		}
+		tag.Slug = slug
+	}
+	tag.Name = name
+	return duplicate(db.Save(tag).Error)
+}
+
+func (r *GormTaxonomyRepository) DeleteTag(ctx context.Context, id uint) ([]uint, error) {
+	var postIds []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("post_tags").Where("tag_id = ?", id).Pluck("post_id", &postIds).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		// 硬删除，释放唯一索引上的名称
+		return tx.Unscoped().Delete(&models.Tag{}, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return postIds, nil
+}
+
+func (r *GormTaxonomyRepository) TagPostIDs(ctx context.Context, id uint) ([]uint, error) {
+	var postIds []uint
+	err := r.db.WithContext(ctx).Table("post_tags").Where("tag_id = ?", id).Pluck("post_id", &postIds).Error
+	return postIds, err
+}
+
+func (r *GormTaxonomyRepository) Categories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	if err := r.db.WithContext(ctx).Order("id asc").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *GormTaxonomyRepository) FindCategory(ctx context.Context, id uint64) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &category, nil
+}
+
+func (r *GormTaxonomyRepository) CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	return models.CategoryDescendantIDs(r.db.WithContext(ctx), id)
+}
+
+func (r *GormTaxonomyRepository) CategorySlugTaken(ctx context.Context, slug string, categoryID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Category{}).
+		Where("slug = ? AND id <> ?", slug, categoryID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormTaxonomyRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	return duplicate(r.db.WithContext(ctx).Create(category).Error)
+}
+
+func (r *GormTaxonomyRepository) SaveCategory(ctx context.Context, category *models.Category) error {
+	return duplicate(r.db.WithContext(ctx).Model(category).
+		Select("name", "slug", "parent_id").
+		Updates(map[string]interface{}{"name": category.Name, "slug": category.Slug, "parent_id": category.ParentID}).Error)
+}
+
+func (r *GormTaxonomyRepository) DeleteCategory(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Post{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Category{}, category.ID).Error
+	})
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gavin/blog/models"
+)
+
+// MemoryTaxonomyRepository 基于内存的标签和分类存储，文章中的标签副本同步修改
+type MemoryTaxonomyRepository struct {
+	data *memoryData
+}
+
+func (r *MemoryTaxonomyRepository) Tags(ctx context.Context) ([]models.TagWithCount, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	counts := make(map[uint]int64)
+	for _, post := range r.data.posts {
+		if !post.IsPublished() {
+			continue
+		}
+		for _, tag := range post.Tags {
+			counts[tag.ID]++
+		}
+	}
+	tags := make([]models.TagWithCount, 0, len(r.data.tags))
+	for _, id := range sortedIDs(r.data.tags) {
+		tag := r.data.tags[id]
+		tags = append(tags, models.TagWithCount{ID: tag.ID, Name: tag.Name, Slug: tag.Slug, PostCount: counts[id]})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].PostCount > tags[j].PostCount })
+	return tags, nil
+}
+
+func (r *MemoryTaxonomyRepository) FindTag(ctx context.Context, id uint64) (*models.Tag, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	tag, ok := r.data.tags[uint(id)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &tag, nil
+}
+
+func (r *MemoryTaxonomyRepository) CreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	if r.tagNameTaken(name, 0) {
+		return nil, ErrDuplicate
+	}
+	tag := &models.Tag{Name: name, Slug: r.uniqueTagSlug(name, 0)}
+	r.data.stamp(&tag.Model)
+	r.data.tags[tag.ID] = *tag
+	return tag, nil
+}
+
+func (r *MemoryTaxonomyRepository) RenameTag(ctx context.Context, tag *models.Tag, name string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	if r.tagNameTaken(name, tag.ID) {
+		return ErrDuplicate
+	}
+	if models.Slugify(name) != models.Slugify(tag.Name) {
+		tag.Slug = r.uniqueTagSlug(name, tag.ID)
+	}
+	tag.Name = name
+	tag.UpdatedAt = time.Now()
+	r.data.tags[tag.ID] = *tag
+	r.eachPostTag(tag.ID, func(post *models.Post, i int) {
+		post.Tags[i] = *tag
+	})
+	return nil
+}
+
+func (r *MemoryTaxonomyRepository) DeleteTag(ctx context.Context, id uint) ([]uint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	postIds := r.tagPostIDs(id)
+	for _, postId := range postIds {
+		post := r.data.posts[postId]
+		tags := make([]models.Tag, 0, len(post.Tags))
+		for _, tag := range post.Tags {
+			if tag.ID != id {
+				tags = append(tags, tag)
+			}
+		}
+		post.Tags = tags
+		r.data.posts[postId] = post
+	}
+	delete(r.data.tags, id)
+	return postIds, nil
+}
+
+func (r *MemoryTaxonomyRepository) TagPostIDs(ctx context.Context, id uint) ([]uint, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	return r.tagPostIDs(id), nil
+}
+
+// tagPostIDs 使用该标签的文章 ID，调用方需持有锁
+func (r *MemoryTaxonomyRepository) tagPostIDs(id uint) []uint {
+	var postIds []uint
+	r.eachPostTag(id, func(post *models.Post, i int) {
+		postIds = append(postIds, post.ID)
+	})
+	return postIds
+}
+
+// eachPostTag 对使用该标签的每篇文章调用 fn 并保存修改，调用方需持有锁
+func (r *MemoryTaxonomyRepository) eachPostTag(id uint, fn func(post *models.Post, i int)) {
+	for _, postId := range sortedIDs(r.data.posts) {
+		post := r.data.posts[postId]
+		for i, tag := range post.Tags {
+			if tag.ID == id {
+				post.Tags = append([]models.Tag(nil), post.Tags...)
+				fn(&post, i)
+				r.data.posts[postId] = post
+				break
+			}
+		}
+	}
+}
+
+// tagNameTaken 名称是否已被其他标签使用（不区分大小写），调用方需持有锁
+func (r *MemoryTaxonomyRepository) tagNameTaken(name string, excludeID uint) bool {
+	for id, tag := range r.data.tags {
+		if id != excludeID && strings.EqualFold(tag.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueTagSlug 与 models.UniqueTagSlug 相同，调用方需持有锁
+func (r *MemoryTaxonomyRepository) uniqueTagSlug(name string, excludeID uint) string {
+	// taken 不会返回错误
+	slug, _ := models.UniqueSlug(name, func(slug string) (bool, error) {
+		for id, tag := range r.data.tags {
+			if id != excludeID && tag.Slug == slug {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	return slug
+}
+
+func (r *MemoryTaxonomyRepository) Categories(ctx context.Context) ([]models.Category, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	categories := make([]models.Category, 0, len(r.data.categories))
+	for _, id := range sortedIDs(r.data.categories) {
+		categories = append(categories, r.data.categories[id])
+	}
+	return categories, nil
+}
+
+func (r *MemoryTaxonomyRepository) FindCategory(ctx context.Context, id uint64) (*models.Category, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	category, ok := r.data.categories[uint(id)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &category, nil
+}
+
+func (r *MemoryTaxonomyRepository) CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	categories, _ := r.Categories(ctx)
+	return models.DescendantCategoryIDs(categories, id), nil
+}
+
+func (r *MemoryTaxonomyRepository) CategorySlugTaken(ctx context.Context, slug string, categoryID uint) (bool, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	for id, category := range r.data.categories {
+		if id != categoryID && category.Slug == slug {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryTaxonomyRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	r.data.stamp(&category.Model)
+	r.data.categories[category.ID] = *category
+	return nil
+}
+
+func (r *MemoryTaxonomyRepository) SaveCategory(ctx context.Context, category *models.Category) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	stored, ok := r.data.categories[category.ID]
+	if !ok {
+		return nil
+	}
+	stored.Name = category.Name
+	stored.Slug = category.Slug
+	stored.ParentID = category.ParentID
+	stored.UpdatedAt = time.Now()
+	r.data.categories[category.ID] = stored
+	return nil
+}
+
+func (r *MemoryTaxonomyRepository) DeleteCategory(ctx context.Context, category *models.Category) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	id := uint64(category.ID)
+	for childId, child := range r.data.categories {
+		if child.ParentID != nil && *child.ParentID == id {
+			child.ParentID = category.ParentID
+			r.data.categories[childId] = child
+		}
+	}
+	for postId, post := range r.data.posts {
+		if post.CategoryID != nil && *post.CategoryID == id {
+			post.CategoryID = nil
+			r.data.posts[postId] = post
+		}
+	}
+	delete(r.data.categories, category.ID)
+	return nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gavin/blog/models"
+)
+
+// UserRepository 用户存储
+type UserRepository interface {
+	FindByID(ctx context.Context, id uint64) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	// UpdateRole 修改角色和额外权限（逗号分隔）
+	UpdateRole(ctx context.Context, id uint64, role string, permissions string) error
+}
+
+// RefreshTokenRepository 刷新令牌存储
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// Rotate 撤销旧令牌并保存新令牌，旧令牌已被撤销（并发刷新）时返回 ErrConflict
+	Rotate(ctx context.Context, oldID uint, next *models.RefreshToken) error
+	// RevokeFamily 撤销同一家族下所有未撤销的令牌
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser 撤销用户所有未撤销的令牌
+	RevokeUser(ctx context.Context, userID uint64, at time.Time) error
+}
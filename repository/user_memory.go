@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gavin/blog/models"
+)
+
+// MemoryUserRepository 基于内存的用户存储
+type MemoryUserRepository struct {
+	data *memoryData
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id uint64) (*models.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	user, ok := r.data.users[uint(id)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *MemoryUserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findBy(func(user *models.User) bool { return user.Username == username })
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findBy(func(user *models.User) bool { return user.Email == email })
+}
+
+func (r *MemoryUserRepository) findBy(match func(user *models.User) bool) (*models.User, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	for _, id := range sortedIDs(r.data.users) {
+		user := r.data.users[id]
+		if match(&user) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	// 与数据库的唯一索引保持一致
+	for _, exist := range r.data.users {
+		if exist.Username == user.Username || exist.Email == user.Email {
+			return fmt.Errorf("%w: duplicate username or email", ErrConflict)
+		}
+	}
+	if user.Role == "" {
+		user.Role = models.DefaultRole
+	}
+	r.data.stamp(&user.Model)
+	r.data.users[user.ID] = *user
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateRole(ctx context.Context, id uint64, role string, permissions string) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	user, ok := r.data.users[uint(id)]
+	if !ok {
+		return nil
+	}
+	user.Role = role
+	user.Permissions = permissions
+	user.UpdatedAt = time.Now()
+	r.data.users[user.ID] = user
+	return nil
+}
+
+// MemoryRefreshTokenRepository 基于内存的刷新令牌存储
+type MemoryRefreshTokenRepository struct {
+	data *memoryData
+}
+
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	r.create(token)
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) create(token *models.RefreshToken) {
+	r.data.stamp(&token.Model)
+	r.data.tokens[token.ID] = *token
+}
+
+func (r *MemoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	for _, token := range r.data.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryRefreshTokenRepository) Rotate(ctx context.Context, oldID uint, next *models.RefreshToken) error {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	old, ok := r.data.tokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return ErrConflict
+	}
+	now := time.Now()
+	old.RevokedAt = &now
+	r.data.tokens[oldID] = old
+	r.create(next)
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.revokeWhere(time.Now(), func(token *models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeUser(ctx context.Context, userID uint64, at time.Time) error {
+	r.revokeWhere(at, func(token *models.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) revokeWhere(at time.Time, match func(token *models.RefreshToken) bool) {
+	r.data.mu.Lock()
+	defer r.data.mu.Unlock()
+	for id, token := range r.data.tokens {
+		if token.RevokedAt == nil && match(&token) {
+			revokedAt := at
+			token.RevokedAt = &revokedAt
+			r.data.tokens[id] = token
+		}
+	}
+}
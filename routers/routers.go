@@ -4,43 +4,120 @@ import (
 	"net/http"
 
 	"github.com/gavin/blog/handlers"
+	"github.com/gavin/blog/metrics"
 	"github.com/gavin/blog/middleware"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/search"
+	"github.com/gavin/blog/service"
 	"github.com/gin-gonic/gin"
 )
 
-func InitApi(router *gin.Engine) {
+// InitMetrics 注册 /metrics，token 不为空时需要 Bearer 令牌
+func InitMetrics(router *gin.Engine, token string) {
+	router.GET("/metrics", middleware.MetricsAuth(token), gin.WrapH(metrics.Handler()))
+}
+
+// InitHealth 注册 /healthz 和 /readyz，启动期间（连接数据库、执行迁移）的临时路由也使用它
+func InitHealth(router *gin.Engine) {
+	healthHandler := &handlers.HealthHandler{}
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+}
+
+func InitApi(router *gin.Engine, services *service.Services) {
 	router.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "hello world")
 	})
+	InitHealth(router)
 
-	authHandler := &handlers.AuthHandler{}
-	commentHandle := &handlers.CommentHandle{}
-	postHandler := &handlers.PostHandler{}
+	authHandler := handlers.NewAuthHandler(services.Users)
+	commentHandle := handlers.NewCommentHandle(services.Comments)
+	postHandler := handlers.NewPostHandler(services.Posts)
+	userHandler := handlers.NewUserHandler(services.Users)
+	tagHandler := handlers.NewTagHandler(services.Taxonomy)
+	categoryHandler := handlers.NewCategoryHandler(services.Taxonomy)
+	searchHandler := handlers.NewSearchHandler(search.Default, services.Taxonomy)
+	revisionHandler := handlers.NewRevisionHandler(services.Posts)
+	moderationHandler := handlers.NewModerationHandler(services.Comments)
 
 	// 公共接口（不需要 token）
 	public := router.Group("/auth")
 	{
 		public.POST("/login", authHandler.Login)
 		public.POST("/register", authHandler.Register)
+		public.POST("/refresh", authHandler.Refresh)
+	}
+
+	// 公开只读接口：匿名可访问，携带令牌时识别登录用户
+	read := router.Group("")
+	read.Use(middleware.OptionalAuthMiddleware())
+	{
+		read.GET("/post/:id", postHandler.GetPost)
+		read.GET("/post/slug/:slug", postHandler.GetPostBySlug)
+		read.POST("/post/page", postHandler.GetPagePosts)
+		read.GET("/post/:id/comments", commentHandle.GetPostComments)
+		read.GET("/comment/:id", commentHandle.GetComment)
+		read.GET("/comment/:id/replies", commentHandle.GetReplies)
+		read.POST("/comment/page", commentHandle.GetPageComments)
+		read.GET("/tag/list", tagHandler.ListTags)
+		read.GET("/category/list", categoryHandler.ListCategories)
+		read.GET("/search", searchHandler.Search)
 	}
 
+	// 需要登录的写接口
 	auth := router.Group("")
 	auth.Use(middleware.JWTAuthMiddleware())
 	{
+		auth.POST("/auth/logout", authHandler.Logout)
+		auth.POST("/auth/logout-all", authHandler.LogoutAll)
+
 		post := auth.Group("/post")
-		post.POST("add", postHandler.AddPost)
+		post.POST("add", middleware.RequirePermission(models.PermPostCreate), postHandler.AddPost)
 		post.POST("update", postHandler.UpdatePost)
-		post.GET(":id", postHandler.GetPost)
 		post.DELETE(":id", postHandler.DeletePost)
 		post.GET("user", postHandler.GetUserPost)
-		post.POST("page", postHandler.GetPagePosts)
+		post.POST(":id/submit", postHandler.SubmitPost)
+		post.POST(":id/publish", postHandler.PublishPost)
+		post.POST(":id/unpublish", postHandler.UnpublishPost)
+		post.POST(":id/archive", postHandler.ArchivePost)
+		post.POST(":id/schedule", postHandler.SchedulePost)
+		post.POST(":id/unschedule", postHandler.UnschedulePost)
+		post.GET(":id/revisions", revisionHandler.ListRevisions)
+		post.GET(":id/revisions/:revision", revisionHandler.GetRevision)
+		post.POST(":id/revisions/:revision/restore", revisionHandler.RestoreRevision)
+		post.GET(":id/diff", revisionHandler.DiffRevisions)
+		post.POST(":id/comment-moderation", postHandler.SetCommentModeration)
 
 		comment := auth.Group("/comment")
-		comment.POST("add", commentHandle.AddComment)
+		comment.POST("add", middleware.RequirePermission(models.PermCommentCreate), commentHandle.AddComment)
+		comment.POST("reply", middleware.RequirePermission(models.PermCommentCreate), commentHandle.ReplyComment)
 		comment.POST("update", commentHandle.UpdateComment)
-		comment.GET(":id", commentHandle.GetComment)
 		comment.DELETE(":id", commentHandle.DeleteComment)
 		comment.GET("user", commentHandle.GetUserComment)
-		comment.POST("page", commentHandle.GetPageComments)
+
+		tag := auth.Group("/tag")
+		tag.Use(middleware.RequirePermission(models.PermTaxonomyManage))
+		tag.POST("add", tagHandler.AddTag)
+		tag.POST("update", tagHandler.UpdateTag)
+		tag.DELETE(":id", tagHandler.DeleteTag)
+
+		category := auth.Group("/category")
+		category.Use(middleware.RequirePermission(models.PermTaxonomyManage))
+		category.POST("add", categoryHandler.AddCategory)
+		category.POST("update", categoryHandler.UpdateCategory)
+		category.DELETE(":id", categoryHandler.DeleteCategory)
+
+		admin := auth.Group("/admin")
+		admin.POST("user/role", middleware.RequirePermission(models.PermUserManage), userHandler.UpdateRole)
+
+		moderation := admin.Group("/moderation")
+		moderation.Use(middleware.RequirePermission(models.PermCommentModerate))
+		moderation.GET("settings", moderationHandler.GetSettings)
+		moderation.POST("settings", moderationHandler.UpdateSettings)
+		moderation.POST("comments", moderationHandler.QueryComments)
+		moderation.POST("approve", moderationHandler.Approve)
+		moderation.POST("reject", moderationHandler.Reject)
+		moderation.POST("spam", moderationHandler.MarkSpam)
+		moderation.POST("recount", moderationHandler.RecountComments)
 	}
 }
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/service"
+)
+
+// 每批认领的文章数，避免单个事务过大
+const batchSize = 100
+
+// Scheduler 进程内的定时发布调度器
+// 通过 PostService 认领到期的文章，多实例部署时每篇文章只被一个实例认领；发布时按设置人当前的权限走 Transition
+type Scheduler struct {
+	users    service.UserService
+	posts    service.PostService
+	interval time.Duration
+	// OnPublished 文章被定时发布后的回调（可选），用于发送事件
+	OnPublished func(post models.Post)
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// 最近一轮的完成时间和错误，用于健康检查
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+func New(users service.UserService, posts service.PostService, interval time.Duration) *Scheduler {
+	return &Scheduler{users: users, posts: posts, interval: interval}
+}
+
+// Start 启动后台协程，按间隔检查到期的文章
+func (s *Scheduler) Start() {
+	// 后台任务没有请求 ID，日志用 worker 字段区分，Transition 等服务层日志同样带上
+	ctx, cancel := context.WithCancel(logger.NewContext(context.Background(), "worker", "scheduler"))
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		logger.Log.WithContext(ctx).Infof("scheduler started | interval: %v", s.interval)
+		for {
+			s.RunOnce(ctx)
+			select {
+			case <-ctx.Done():
+				logger.Log.WithContext(ctx).Infof("scheduler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止调度器并等待当前这一轮执行完成，ctx 超时则直接返回
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce 发布所有到期的定时文章
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	for {
+		claimed, err := s.posts.ClaimScheduled(ctx, batchSize)
+		if err != nil {
+			logger.Log.WithContext(ctx).Errorf("scheduler claim err: %v", err)
+			s.finishRun(err)
+			return
+		}
+		var runErr error
+		for i := range claimed {
+			post, err := s.publish(ctx, &claimed[i])
+			if err != nil {
+				logger.Log.WithContext(ctx).Errorf("scheduler publish err: %v | post_id: %d", err, claimed[i].ID)
+				runErr = err
+				continue
+			}
+			if post == nil {
+				continue
+			}
+			logger.Log.WithContext(ctx).Infof("post published by scheduler | post_id: %d, user_id: %d, scheduled_at: %v",
+				post.ID, post.UserID, claimed[i].ScheduledAt)
+			if s.OnPublished != nil {
+				s.OnPublished(*post)
+			}
+		}
+		// 本轮有失败的文章已恢复定时发布，等下一轮重试，避免同一批反复认领
+		if runErr != nil || len(claimed) < batchSize || ctx.Err() != nil {
+			s.finishRun(runErr)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) finishRun(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = time.Now()
+	s.lastErr = err
+}
+
+// Check 健康检查：未启动、最近一轮失败，或超过 3 个间隔没有完成一轮，视为不健康
+func (s *Scheduler) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel == nil {
+		return errors.New("scheduler not started")
+	}
+	if s.lastErr != nil {
+		return fmt.Errorf("last run failed: %w", s.lastErr)
+	}
+	if !s.lastRun.IsZero() && time.Since(s.lastRun) > 3*s.interval {
+		return fmt.Errorf("no run since %s", s.lastRun.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// publish 按设置人当前的权限发布已认领的文章，返回 nil 表示不再发布（权限被收回或状态已被手动修改）
+func (s *Scheduler) publish(ctx context.Context, claimed *models.Post) (*models.Post, error) {
+	by := scheduledBy(claimed)
+	actor, err := s.users.Actor(ctx, by)
+	if err != nil && !errors.Is(err, service.ErrUserNotFound) {
+		s.reschedule(ctx, claimed)
+		return nil, err
+	}
+	// 设置人被删除时以匿名身份流转，同样因为没有权限而取消
+	post, err := s.posts.Transition(ctx, actor, uint64(claimed.ID), models.PostActionPublish)
+	switch {
+	case err == nil:
+		return post, nil
+	case errors.Is(err, service.ErrPermissionDenied), errors.Is(err, service.ErrPostNotFound):
+		// 设置后被降级或删除的用户不能再发布，定时发布已在认领时取消
+		logger.Log.WithContext(ctx).Warnf("scheduled publish cancelled, permission revoked | post_id: %d, user_id: %d", claimed.ID, by)
+		return nil, nil
+	case errors.Is(err, service.ErrInvalidTransition), errors.Is(err, service.ErrStatusChanged):
+		// 认领后文章被手动流转
+		return nil, nil
+	}
+	s.reschedule(ctx, claimed)
+	return nil, err
+}
+
+// reschedule 发布失败时恢复定时发布，下一轮重试
+func (s *Scheduler) reschedule(ctx context.Context, claimed *models.Post) {
+	if err := s.posts.Reschedule(ctx, claimed); err != nil && !errors.Is(err, service.ErrStatusChanged) {
+		logger.Log.WithContext(ctx).Errorf("reschedule post err: %v | post_id: %d, scheduled_at: %v", err, claimed.ID, claimed.ScheduledAt)
+	}
+}
+
+// scheduledBy 设置定时发布的用户，旧数据没有记录时取作者
+func scheduledBy(post *models.Post) uint64 {
+	if post.ScheduledBy != nil {
+		return *post.ScheduledBy
+	}
+	return post.UserID
+}
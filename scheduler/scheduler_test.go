@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/service"
+)
+
+// scheduledPost 创建一篇草稿，由 by 设置在 at 定时发布（可以是过去的时间）
+func scheduledPost(t *testing.T, repos *repository.Repositories, posts service.PostService, author service.Actor, by uint64, at time.Time) *models.Post {
+	t.Helper()
+	ctx := context.Background()
+	post, err := posts.Create(ctx, author, service.PostInput{Title: "scheduled", Content: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Posts.Schedule(ctx, post.ID, post.Status, at, by); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func user(t *testing.T, repos *repository.Repositories, name, role string) service.Actor {
+	t.Helper()
+	u := &models.User{Username: name, Email: name + "@example.com", Role: role}
+	if err := repos.Users.Create(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	return service.Actor{UserID: uint64(u.ID), Username: name, Permissions: u.PermissionList()}
+}
+
+func TestRunOncePublishesDuePosts(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	services := service.New(repos, nil, nil, nil)
+	editor := user(t, repos, "editor", models.RoleEditor)
+	past := time.Now().Add(-time.Minute)
+
+	due := scheduledPost(t, repos, services.Posts, editor, editor.UserID, past)
+	future := scheduledPost(t, repos, services.Posts, editor, editor.UserID, time.Now().Add(time.Hour))
+
+	s := New(services.Users, services.Posts, time.Minute)
+	var published []uint
+	s.OnPublished = func(post models.Post) { published = append(published, post.ID) }
+	s.RunOnce(ctx)
+
+	if len(published) != 1 || published[0] != due.ID {
+		t.Fatalf("published = %v, want [%d]", published, due.ID)
+	}
+	got, err := repos.Posts.FindByID(ctx, uint64(due.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.PostStatusPublished || got.ScheduledAt != nil || got.PublishedAt == nil {
+		t.Errorf("due post = %s, scheduled_at %v, published_at %v", got.Status, got.ScheduledAt, got.PublishedAt)
+	}
+	if got, _ = repos.Posts.FindByID(ctx, uint64(future.ID)); got.Status != models.PostStatusDraft || got.ScheduledAt == nil {
+		t.Errorf("future post = %s, scheduled_at %v; want untouched", got.Status, got.ScheduledAt)
+	}
+
+	// 已认领的文章不会被再次发布
+	published = nil
+	s.RunOnce(ctx)
+	if len(published) != 0 {
+		t.Errorf("second run published %v", published)
+	}
+	if err := s.Check(ctx); err == nil {
+		t.Error("Check before Start should fail")
+	}
+}
+
+func TestRunOnceRechecksPublishPermission(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	services := service.New(repos, nil, nil, nil)
+	author := user(t, repos, "author", models.RoleAuthor)
+	past := time.Now().Add(-time.Minute)
+
+	// 设置定时发布后被收回了发布权限（作者默认没有 post:publish），以及设置人已被删除
+	revoked := scheduledPost(t, repos, services.Posts, author, author.UserID, past)
+	deleted := scheduledPost(t, repos, services.Posts, author, 999, past)
+
+	s := New(services.Users, services.Posts, time.Minute)
+	s.OnPublished = func(post models.Post) { t.Errorf("post %d published without permission", post.ID) }
+	s.RunOnce(ctx)
+
+	for _, post := range []*models.Post{revoked, deleted} {
+		got, err := repos.Posts.FindByID(ctx, uint64(post.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 取消定时发布，下一轮不再检查
+		if got.Status != models.PostStatusDraft || got.ScheduledAt != nil {
+			t.Errorf("post %d = %s, scheduled_at %v; want draft, unscheduled", post.ID, got.Status, got.ScheduledAt)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// 摘要长度（字符数）
+const snippetLength = 120
+
+// Highlight 将文本中的查询词用 <em> 包裹，其余部分做 HTML 转义
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// 大小写转换后长度变化（极少见）时退化为不高亮
+	if len(lower) != len(runes) {
+		return html.EscapeString(text)
+	}
+
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		termRunes := []rune(term)
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) == term {
+				for j := i; j < i+len(termRunes); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<em>")
+			b.WriteString(segment)
+			b.WriteString("</em>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// Snippet 截取第一个查询词附近的一段文本并高亮
+func Snippet(text string, terms []string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return Highlight(text, terms)
+	}
+
+	lower := strings.ToLower(text)
+	first := -1
+	for _, term := range terms {
+		if idx := strings.Index(lower, term); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+
+	start := 0
+	if first > 0 {
+		// 字节偏移转换为字符偏移，关键词前保留 1/4 长度的上下文
+		start = utf8.RuneCountInString(lower[:first]) - length/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + length
+	if end > len(runes) {
+		end = len(runes)
+		start = end - length
+	}
+
+	snippet := Highlight(string(runes[start:end]), terms)
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
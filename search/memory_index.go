@@ -0,0 +1,224 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// 标题中出现的词权重
+	titleBoost = 3
+)
+
+// MemoryIndex 内存倒排索引，进程启动时从数据库重建，增删改时同步更新
+// 只同步本实例处理的写操作，多实例部署时各实例的索引会不一致，需要换成外部搜索服务（实现 Index 接口）
+type MemoryIndex struct {
+	mu sync.RWMutex
+	// term -> 文档键 -> 词频（已计入标题权重）
+	postings map[string]map[string]int
+	docs     map[string]*indexedDoc
+	// 所有文档长度之和，用于计算平均长度
+	totalLength int
+}
+
+type indexedDoc struct {
+	Document
+	terms  map[string]int
+	length int
+	tags   map[string]bool
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		postings: make(map[string]map[string]int),
+		docs:     make(map[string]*indexedDoc),
+	}
+}
+
+func (m *MemoryIndex) Index(doc Document) error {
+	indexed := &indexedDoc{
+		Document: doc,
+		terms:    make(map[string]int),
+		tags:     make(map[string]bool),
+	}
+	// 评论的 Title 是所属文章标题，只用于展示，不参与评分
+	if doc.Type == TypePost {
+		for _, token := range IndexTokens(doc.Title) {
+			indexed.terms[token] += titleBoost
+			indexed.length++
+		}
+	}
+	for _, token := range IndexTokens(doc.Content) {
+		indexed.terms[token]++
+		indexed.length++
+	}
+	for _, tag := range doc.Tags {
+		indexed.tags[normalizeTag(tag)] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := doc.Key()
+	m.remove(key)
+	m.docs[key] = indexed
+	m.totalLength += indexed.length
+	for term, tf := range indexed.terms {
+		if m.postings[term] == nil {
+			m.postings[term] = make(map[string]int)
+		}
+		m.postings[term][key] = tf
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Delete(docType string, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(docKey(docType, id))
+	return nil
+}
+
+// remove 从倒排表中移除文档，调用方需持有写锁
+func (m *MemoryIndex) remove(key string) {
+	doc, ok := m.docs[key]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(m.postings[term], key)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	m.totalLength -= doc.length
+	delete(m.docs, key)
+}
+
+func (m *MemoryIndex) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
+
+// Search 所有查询词都必须命中（AND），按 BM25 得分排序
+func (m *MemoryIndex) Search(query Query) (*Result, error) {
+	terms := uniqueTerms(query.Keyword)
+	result := &Result{Hits: []Hit{}}
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// 从文档最少的词开始求交集
+	sort.Slice(terms, func(i, j int) bool {
+		return len(m.postings[terms[i]]) < len(m.postings[terms[j]])
+	})
+	candidates := m.postings[terms[0]]
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	docCount := float64(len(m.docs))
+	avgLength := float64(m.totalLength) / docCount
+	var hits []Hit
+	for key := range candidates {
+		doc := m.docs[key]
+		if !matchFilters(doc, &query) {
+			continue
+		}
+
+		score := 0.0
+		matched := true
+		for _, term := range terms {
+			tf, ok := m.postings[term][key]
+			if !ok {
+				matched = false
+				break
+			}
+			df := float64(len(m.postings[term]))
+			idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+			norm := float64(tf) * (bm25K1 + 1) /
+				(float64(tf) + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLength))
+			score += idf * norm
+		}
+		if !matched {
+			continue
+		}
+		hits = append(hits, Hit{
+			Type:      doc.Type,
+			ID:        doc.ID,
+			PostID:    doc.PostID,
+			AuthorID:  doc.AuthorID,
+			Title:     doc.Title,
+			Score:     score,
+			CreatedAt: doc.CreatedAt,
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].CreatedAt.After(hits[j].CreatedAt)
+	})
+
+	result.Total = len(hits)
+	hits = pageHits(hits, query.Offset, query.Limit)
+	for i := range hits {
+		doc := m.docs[docKey(hits[i].Type, hits[i].ID)]
+		if doc.Type == TypePost {
+			hits[i].Highlight = Highlight(doc.Title, terms)
+		}
+		hits[i].Snippet = Snippet(doc.Content, terms, snippetLength)
+	}
+	result.Hits = hits
+	return result, nil
+}
+
+func matchFilters(doc *indexedDoc, query *Query) bool {
+	if query.Type != "" && doc.Type != query.Type {
+		return false
+	}
+	if query.AuthorID > 0 && doc.AuthorID != query.AuthorID {
+		return false
+	}
+	if query.Tag != "" && !doc.tags[normalizeTag(query.Tag)] {
+		return false
+	}
+	if len(query.CategoryIDs) > 0 && !containsID(query.CategoryIDs, doc.CategoryID) {
+		return false
+	}
+	if !query.From.IsZero() && doc.CreatedAt.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && doc.CreatedAt.After(query.To) {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []uint64, id uint64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func pageHits(hits []Hit, offset, limit int) []Hit {
+	if offset >= len(hits) {
+		return []Hit{}
+	}
+	hits = hits[offset:]
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return hits
+}
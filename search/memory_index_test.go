@@ -0,0 +1,192 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func index(t *testing.T, docs ...Document) *MemoryIndex {
+	t.Helper()
+	m := NewMemoryIndex()
+	for _, doc := range docs {
+		if err := m.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func hitIDs(t *testing.T, m *MemoryIndex, query Query) []uint {
+	t.Helper()
+	result, err := m.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestSearchBM25Ranking(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 10)
+	m := index(t,
+		// 标题命中，权重高于正文多次命中
+		Document{Type: TypePost, ID: 1, Title: "golang", Content: filler},
+		// 正文多次命中
+		Document{Type: TypePost, ID: 2, Title: "misc", Content: "golang golang " + filler},
+		// 正文一次命中
+		Document{Type: TypePost, ID: 3, Title: "misc", Content: "golang " + filler},
+		// 正文一次命中、文档更长
+		Document{Type: TypePost, ID: 4, Title: "misc", Content: "golang " + filler + filler},
+		Document{Type: TypePost, ID: 5, Title: "other", Content: filler},
+	)
+	got := hitIDs(t, m, Query{Keyword: "golang"})
+	want := []uint{1, 2, 3, 4}
+	if !equalIDs(got, want) {
+		t.Errorf("ranking = %v, want %v", got, want)
+	}
+
+	// 所有词都要命中；罕见词权重更高
+	m = index(t,
+		Document{Type: TypePost, ID: 1, Title: "a", Content: "common common rare"},
+		Document{Type: TypePost, ID: 2, Title: "b", Content: "common common common common rare"},
+		Document{Type: TypePost, ID: 3, Title: "c", Content: "common"},
+		Document{Type: TypePost, ID: 4, Title: "d", Content: "common"},
+	)
+	if got := hitIDs(t, m, Query{Keyword: "common rare"}); !equalIDs(got, []uint{1, 2}) {
+		t.Errorf("AND ranking = %v, want [1 2]", got)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC) }
+	m := index(t,
+		Document{Type: TypePost, ID: 1, PostID: 1, AuthorID: 10, Title: "go", Content: "go", Tags: []string{"Go", "go"}, CategoryID: 1, CreatedAt: day(1)},
+		Document{Type: TypePost, ID: 2, PostID: 2, AuthorID: 20, Title: "go", Content: "go", Tags: []string{"后端", "hou-duan"}, CategoryID: 2, CreatedAt: day(5)},
+		Document{Type: TypeComment, ID: 3, PostID: 1, AuthorID: 20, Title: "go", Content: "go", Tags: []string{"Go", "go"}, CategoryID: 1, CreatedAt: day(9)},
+		Document{Type: TypePost, ID: 4, PostID: 4, AuthorID: 10, Title: "go", Content: "go", CreatedAt: day(9)},
+	)
+	cases := []struct {
+		name  string
+		query Query
+		want  []uint
+	}{
+		{"type", Query{Type: TypeComment}, []uint{3}},
+		{"author", Query{AuthorID: 20}, []uint{2, 3}},
+		{"tag name", Query{Tag: "GO"}, []uint{1, 3}},
+		{"tag slug", Query{Tag: "hou-duan"}, []uint{2}},
+		{"category", Query{CategoryIDs: []uint64{1}}, []uint{1, 3}},
+		{"category with descendants", Query{CategoryIDs: []uint64{1, 2}}, []uint{1, 2, 3}},
+		{"date range", Query{From: day(2), To: day(8)}, []uint{2}},
+		{"combined", Query{Type: TypePost, AuthorID: 10, From: day(2)}, []uint{4}},
+	}
+	for _, tc := range cases {
+		tc.query.Keyword = "go"
+		got := hitIDs(t, m, tc.query)
+		if !sameIDs(got, tc.want) {
+			t.Errorf("%s: hits = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// 分页不影响总数
+	result, err := m.Search(Query{Keyword: "go", Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 4 || len(result.Hits) != 2 {
+		t.Errorf("page = %d hits of %d, want 2 of 4", len(result.Hits), result.Total)
+	}
+}
+
+func TestSearchDeleteAndReindex(t *testing.T) {
+	m := index(t, Document{Type: TypePost, ID: 1, Title: "old", Content: "alpha"})
+	if err := m.Index(Document{Type: TypePost, ID: 1, Title: "new", Content: "beta"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := hitIDs(t, m, Query{Keyword: "alpha"}); len(got) != 0 {
+		t.Errorf("stale terms still match: %v", got)
+	}
+	if err := m.Delete(TypePost, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := hitIDs(t, m, Query{Keyword: "beta"}); len(got) != 0 || m.Count() != 0 {
+		t.Errorf("deleted doc still matches: %v, count %d", got, m.Count())
+	}
+}
+
+func TestSearchHighlightAndSnippet(t *testing.T) {
+	content := strings.Repeat("无关的内容。", 30) + "今天的天气很好，适合出门。" + strings.Repeat("其他文字。", 30)
+	m := index(t,
+		Document{Type: TypePost, ID: 1, Title: "天气预报 <b>Weather</b>", Content: content},
+		Document{Type: TypeComment, ID: 2, Title: "天气预报", Content: "明天天气怎么样"},
+	)
+	result, err := m.Search(Query{Keyword: "天气 weather"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 1 {
+		t.Fatalf("hits = %+v, want post only", result.Hits)
+	}
+	hit := result.Hits[0]
+	if hit.Highlight != "<em>天气</em>预报 &lt;b&gt;<em>Weather</em>&lt;/b&gt;" {
+		t.Errorf("highlight = %q", hit.Highlight)
+	}
+	if !strings.HasPrefix(hit.Snippet, "...") || !strings.HasSuffix(hit.Snippet, "...") ||
+		!strings.Contains(hit.Snippet, "今天的<em>天气</em>很好") {
+		t.Errorf("snippet = %q, want keyword in context with ellipses", hit.Snippet)
+	}
+	if n := len([]rune(strings.NewReplacer("<em>", "", "</em>", "", "...", "").Replace(hit.Snippet))); n != snippetLength {
+		t.Errorf("snippet length = %d, want %d", n, snippetLength)
+	}
+
+	// 评论不高亮标题，短正文整段高亮
+	result, err = m.Search(Query{Keyword: "天气", Type: TypeComment})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hit := result.Hits[0]; hit.Highlight != "" || hit.Snippet != "明天<em>天气</em>怎么样" {
+		t.Errorf("comment hit = %q / %q", hit.Highlight, hit.Snippet)
+	}
+}
+
+func TestSnippetAtEdges(t *testing.T) {
+	text := "关键" + strings.Repeat("字", 200) + "结尾"
+	if got := Snippet(text, []string{"关键"}, 10); got != "<em>关键</em>字字字字字字字字..." {
+		t.Errorf("start snippet = %q", got)
+	}
+	if got := Snippet(text, []string{"结尾"}, 10); got != "...字字字字字字字字<em>结尾</em>" {
+		t.Errorf("end snippet = %q", got)
+	}
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameIDs 不考虑顺序比较
+func sameIDs(a, b []uint) bool {
+	seen := make(map[uint]int)
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		seen[id]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
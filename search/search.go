@@ -0,0 +1,83 @@
+package search
+
+import (
+	"strconv"
+	"time"
+)
+
+// 文档类型
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+)
+
+// Default 全局搜索索引，由 InitIndex 初始化
+var Default Index = NewMemoryIndex()
+
+// Index 搜索索引接口，内置实现为内存倒排索引，可替换为 MySQL FULLTEXT、Elasticsearch 等
+type Index interface {
+	// Index 新增或更新文档
+	Index(doc Document) error
+	// Delete 删除文档，文档不存在时不报错
+	Delete(docType string, id uint) error
+	// Search 关键词搜索
+	Search(query Query) (*Result, error)
+	// Count 已索引的文档数
+	Count() int
+}
+
+// Document 被索引的文档（文章或评论）
+type Document struct {
+	Type     string
+	ID       uint
+	PostID   uint64 // 评论所属文章，文章自身为自己的ID
+	AuthorID uint64
+	Title    string // 评论为所属文章的标题，不参与评分
+	Content  string
+	Tags     []string
+	// 文章的分类，评论为所属文章的分类，0 表示没有分类
+	CategoryID uint64
+	CreatedAt  time.Time
+}
+
+// Key 文档在索引中的唯一键
+func (d *Document) Key() string {
+	return docKey(d.Type, d.ID)
+}
+
+func docKey(docType string, id uint) string {
+	return docType + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// Query 搜索条件
+type Query struct {
+	Keyword  string
+	Type     string // 为空时同时搜索文章和评论
+	AuthorID uint64
+	Tag      string
+	// 分类及其子孙分类，为空时不限制
+	CategoryIDs []uint64
+	From        time.Time // 创建时间范围，零值表示不限制
+	To          time.Time
+	Offset      int
+	Limit       int
+}
+
+// Hit 单条搜索结果
+type Hit struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	PostID    uint64    `json:"post_id"`
+	AuthorID  uint64    `json:"author_id"`
+	Title     string    `json:"title"`
+	Highlight string    `json:"highlight"` // 标题高亮（HTML，关键词用 <em> 包裹）
+	Snippet   string    `json:"snippet"`   // 正文摘要高亮（HTML）
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Result 搜索结果
+type Result struct {
+	Total int   `json:"total"`
+	Hits  []Hit `json:"hits"`
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// InitIndex 启动时从数据库重建索引：已发布的文章及其评论
+func InitIndex(db *gorm.DB) error {
+	var posts []models.Post
+	if err := db.Where("status = ?", models.PostStatusPublished).Preload("Tags").Find(&posts).Error; err != nil {
+		return err
+	}
+	for i := range posts {
+		if err := indexPostWithComments(db, &posts[i]); err != nil {
+			return err
+		}
+	}
+	logger.Log.WithContext(db.Statement.Context).Infof("search index built | documents: %d", Default.Count())
+	indexBuilt.Store(true)
+	return nil
+}
+
+// indexBuilt 启动时的全量索引是否构建成功
+var indexBuilt atomic.Bool
+
+// Check 就绪检查：全量索引未构建成功时搜索结果不完整
+func Check(ctx context.Context) error {
+	if !indexBuilt.Load() {
+		return errors.New("search index not built")
+	}
+	return nil
+}
+
+// DBIndexer 按数据库中的最新数据同步默认索引，供业务服务在写操作后调用
+type DBIndexer struct {
+	DB *gorm.DB
+}
+
+func (i DBIndexer) SyncPost(ctx context.Context, postID uint) {
+	SyncPost(i.DB.WithContext(ctx), postID)
+}
+
+func (i DBIndexer) SyncComment(ctx context.Context, commentID uint) {
+	SyncComment(i.DB.WithContext(ctx), commentID)
+}
+
+// SyncPost 文章新增、修改、状态变化、删除后同步索引
+// 已发布的文章连同评论一起索引，其他情况从索引中移除文章和评论；db 带请求 context 时错误日志带上请求 ID
+func SyncPost(db *gorm.DB, postID uint) {
+	var post models.Post
+	err := db.Preload("Tags").First(&post, postID).Error
+	if err == nil && post.IsPublished() {
+		err = indexPostWithComments(db, &post)
+	} else {
+		err = removePostWithComments(db, postID)
+	}
+	if err != nil {
+		logger.Log.WithContext(db.Statement.Context).Errorf("sync post search index err: %v, post_id: %d", err, postID)
+	}
+}
+
+// SyncComment 评论新增、修改、删除、审核后同步索引，只索引已通过审核的评论
+func SyncComment(db *gorm.DB, commentID uint) {
+	var comment models.Comment
+	err := db.Preload("Post").Preload("Post.Tags").First(&comment, commentID).Error
+	if err == nil && comment.Post.IsPublished() && comment.IsApproved() {
+		err = Default.Index(commentDocument(&comment, &comment.Post))
+	} else {
+		err = Default.Delete(TypeComment, commentID)
+	}
+	if err != nil {
+		logger.Log.WithContext(db.Statement.Context).Errorf("sync comment search index err: %v, comment_id: %d", err, commentID)
+	}
+}
+
+func indexPostWithComments(db *gorm.DB, post *models.Post) error {
+	if err := Default.Index(postDocument(post)); err != nil {
+		return err
+	}
+	var comments []models.Comment
+	if err := db.Where("post_id = ? AND status = ?", post.ID, models.CommentStatusApproved).Find(&comments).Error; err != nil {
+		return err
+	}
+	for i := range comments {
+		if err := Default.Index(commentDocument(&comments[i], post)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removePostWithComments(db *gorm.DB, postID uint) error {
+	if err := Default.Delete(TypePost, postID); err != nil {
+		return err
+	}
+	var commentIds []uint
+	if err := db.Unscoped().Model(&models.Comment{}).Where("post_id = ?", postID).Pluck("id", &commentIds).Error; err != nil {
+		return err
+	}
+	for _, id := range commentIds {
+		if err := Default.Delete(TypeComment, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postDocument(post *models.Post) Document {
+	return Document{
+		Type:       TypePost,
+		ID:         post.ID,
+		PostID:     uint64(post.ID),
+		AuthorID:   post.UserID,
+		Title:      post.Title,
+		Content:    post.Content,
+		Tags:       tagNames(post.Tags),
+		CategoryID: postCategoryID(post),
+		CreatedAt:  post.CreatedAt,
+	}
+}
+
+func commentDocument(comment *models.Comment, post *models.Post) Document {
+	return Document{
+		Type:       TypeComment,
+		ID:         comment.ID,
+		PostID:     comment.PostID,
+		AuthorID:   comment.UserID,
+		Title:      post.Title,
+		Content:    comment.Content,
+		Tags:       tagNames(post.Tags),
+		CategoryID: postCategoryID(post),
+		CreatedAt:  comment.CreatedAt,
+	}
+}
+
+func postCategoryID(post *models.Post) uint64 {
+	if post.CategoryID == nil {
+		return 0
+	}
+	return *post.CategoryID
+}
+
+// tagNames 标签名称和 slug 都可以用于过滤
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags)*2)
+	for _, tag := range tags {
+		names = append(names, tag.Name, tag.Slug)
+	}
+	return names
+}
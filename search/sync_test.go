@@ -0,0 +1,86 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gavin/blog/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useMemoryIndex 测试期间把默认索引换成空索引
+func useMemoryIndex(t *testing.T) *MemoryIndex {
+	t.Helper()
+	index := NewMemoryIndex()
+	old := Default
+	Default = index
+	t.Cleanup(func() { Default = old })
+	return index
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接各自独立
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.Comment{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSyncIndexesOnlyPublishedAndApproved(t *testing.T) {
+	index := useMemoryIndex(t)
+	db := openSQLite(t).WithContext(context.Background())
+	category := uint64(3)
+
+	published := &models.Post{Title: "published keyword", Content: "c", Status: models.PostStatusPublished, CategoryID: &category}
+	draft := &models.Post{Title: "draft keyword", Content: "c", Status: models.PostStatusDraft}
+	for _, post := range []*models.Post{published, draft} {
+		if err := db.Create(post).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	approved := &models.Comment{Content: "approved keyword", PostID: uint64(published.ID), Status: models.CommentStatusApproved}
+	pending := &models.Comment{Content: "pending keyword", PostID: uint64(published.ID), Status: models.CommentStatusPending}
+	onDraft := &models.Comment{Content: "draft comment keyword", PostID: uint64(draft.ID), Status: models.CommentStatusApproved}
+	for _, comment := range []*models.Comment{approved, pending, onDraft} {
+		if err := db.Create(comment).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SyncPost(db, published.ID)
+	SyncPost(db, draft.ID)
+	for _, comment := range []*models.Comment{approved, pending, onDraft} {
+		SyncComment(db, comment.ID)
+	}
+	posts := hitIDs(t, index, Query{Keyword: "keyword", Type: TypePost})
+	comments := hitIDs(t, index, Query{Keyword: "keyword", Type: TypeComment})
+	if !sameIDs(posts, []uint{published.ID}) || !sameIDs(comments, []uint{approved.ID}) {
+		t.Fatalf("indexed posts %v, comments %v; want [%d], [%d]", posts, comments, published.ID, approved.ID)
+	}
+	// 评论带上所属文章的分类
+	if got := hitIDs(t, index, Query{Keyword: "keyword", CategoryIDs: []uint64{category}}); !sameIDs(got, []uint{published.ID, approved.ID}) {
+		t.Errorf("category hits = %v", got)
+	}
+
+	// 撤回发布后文章和评论都从索引中移除
+	if err := db.Model(published).Update("status", models.PostStatusDraft).Error; err != nil {
+		t.Fatal(err)
+	}
+	SyncPost(db, published.ID)
+	if index.Count() != 0 {
+		t.Errorf("count after unpublish = %d, want 0", index.Count())
+	}
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 查询分词：拉丁字母和数字按单词切分并转小写，
+// 中日韩文字按相邻两字切分（CJK bigram），单独的一个字作为一个词
+func Tokenize(text string) []string {
+	return tokenize(text, false)
+}
+
+// IndexTokens 索引分词：在 Tokenize 的基础上，连续的中日韩文字再逐字输出一次（unigram），
+// 这样单字查询（如 "猫"）也能命中多字词中的字，多字查询仍按 bigram 匹配
+func IndexTokens(text string) []string {
+	return tokenize(text, true)
+}
+
+func tokenize(text string, unigrams bool) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 || unigrams {
+			for _, r := range cjk {
+				tokens = append(tokens, string(r))
+			}
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// uniqueTerms 去重后的查询词
+func uniqueTerms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range Tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+// normalizeTag 标签比较时忽略大小写和首尾空格
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
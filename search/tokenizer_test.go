@@ -0,0 +1,48 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := map[string][]string{
+		"Hello, World 2026": {"hello", "world", "2026"},
+		"Go语言入门":            {"go", "语言", "言入", "入门"},
+		"猫":                 {"猫"},
+		"Ünïcode—test":      {"ünïcode", "test"},
+		"  ":                nil,
+	}
+	for text, want := range cases {
+		if got := Tokenize(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestIndexTokensIncludeCJKUnigrams(t *testing.T) {
+	got := IndexTokens("Go语言")
+	want := []string{"go", "语", "言", "语言"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IndexTokens = %q, want %q", got, want)
+	}
+}
+
+func TestSearchSingleCJKCharacter(t *testing.T) {
+	index := NewMemoryIndex()
+	if err := index.Index(Document{Type: TypePost, ID: 1, Title: "小猫咪", Content: "今天天气很好"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Index(Document{Type: TypePost, ID: 2, Title: "小狗", Content: "天气"}); err != nil {
+		t.Fatal(err)
+	}
+	for query, want := range map[string]int{"猫": 1, "天气": 2, "小猫": 1, "小": 2, "鱼": 0} {
+		result, err := index.Search(Query{Keyword: query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Total != want {
+			t.Errorf("Search(%q) total = %d, want %d", query, result.Total, want)
+		}
+	}
+}
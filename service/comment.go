@@ -0,0 +1,499 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/metrics"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/spam"
+	"github.com/gavin/blog/utils"
+)
+
+type CommentQuery struct {
+	utils.Pagination
+	PostID uint64
+	UserID uint64
+	// TopLevel 只查询顶层评论（树形展示时回复内嵌在上级评论里）
+	TopLevel bool
+}
+
+// ModerationQuery 审核队列查询条件，Status 为空时查询待审核的评论
+type ModerationQuery struct {
+	utils.Pagination
+	Status string
+	PostID uint64
+}
+
+// ModerationSettingInput 修改全局审核设置，nil 字段保持不变
+type ModerationSettingInput struct {
+	HoldAll            *bool
+	HoldFirstTime      *bool
+	AutoApproveTrusted *bool
+	TrustedThreshold   *int
+}
+
+// CommentInput 新增评论或回复，ParentID 不为 0 时为回复，所属文章取上级评论的文章
+type CommentInput struct {
+	PostID   uint64
+	ParentID uint64
+	Content  string
+	// 以下字段用于垃圾评论检测
+	Honeypot  string
+	IP        string
+	UserAgent string
+	Referrer  string
+}
+
+type UpdateCommentInput struct {
+	ID      uint64
+	PostID  uint64
+	Content string
+	Version int
+	// 以下字段用于垃圾评论检测
+	IP        string
+	UserAgent string
+	Referrer  string
+}
+
+// CommentThread 树形评论节点，Replies 只包含前 N 条直接回复，NextCursor 非空时还有更多回复
+type CommentThread struct {
+	Comment    models.Comment
+	ReplyCount int64
+	Replies    []CommentThread
+	NextCursor string
+}
+
+// CommentService 评论的查询、发表和编辑
+// 不可见或无权操作的评论都返回 ErrCommentNotFound
+type CommentService interface {
+	// List 分页查询可见文章下的可见评论
+	List(ctx context.Context, actor Actor, query CommentQuery) ([]models.Comment, *utils.PageResult, error)
+	// ListByPost 分页查询某篇文章的可见评论
+	ListByPost(ctx context.Context, actor Actor, postID uint64, page utils.Pagination) ([]models.Comment, *utils.PageResult, error)
+	ListByUser(ctx context.Context, userID uint64) ([]models.Comment, error)
+	Get(ctx context.Context, actor Actor, id uint64) (*models.Comment, error)
+	// Threads 将评论转换为树形节点，embed 大于 0 时内嵌前 embed 条直接回复
+	Threads(ctx context.Context, actor Actor, comments []models.Comment, embed int) ([]CommentThread, error)
+	// Replies 按 ID 升序加载 cursor 之后的直接回复，还有更多时返回下一页游标
+	Replies(ctx context.Context, actor Actor, parentID, cursor uint64, limit int) ([]CommentThread, string, error)
+
+	// Create 发表评论或回复，按审核设置和垃圾检测结果决定状态
+	Create(ctx context.Context, actor Actor, input CommentInput) (*models.Comment, error)
+	// Update 按版本号修改评论，修改后的内容重新做垃圾检测，版本不一致返回 *ConflictError
+	Update(ctx context.Context, actor Actor, input UpdateCommentInput) (*models.Comment, error)
+	// Delete 删除评论，还有回复时保留为已删除的占位，其他用户的回复不受影响，返回被删除或清空的评论 ID
+	Delete(ctx context.Context, actor Actor, id uint64) ([]uint64, error)
+	// Recount 按评论重新统计文章评论数，postIDs 为空时统计全部文章
+	Recount(ctx context.Context, postIDs ...uint64) (int64, error)
+
+	// 以下审核操作需要 comment:moderate 权限，否则返回 ErrPermissionDenied
+
+	// ModerationQueue 审核队列，按状态分页查询评论
+	ModerationQueue(ctx context.Context, actor Actor, query ModerationQuery) ([]models.Comment, *utils.PageResult, error)
+	// Moderate 批量修改评论状态，返回实际修改的条数
+	Moderate(ctx context.Context, actor Actor, ids []uint64, status string) (int64, error)
+	ModerationSetting(ctx context.Context, actor Actor) (*models.ModerationSetting, error)
+	UpdateModerationSetting(ctx context.Context, actor Actor, input ModerationSettingInput) (*models.ModerationSetting, error)
+}
+
+type commentService struct {
+	comments repository.CommentRepository
+	posts    repository.PostRepository
+	checker  spam.Checker
+	indexer  Indexer
+}
+
+func NewCommentService(comments repository.CommentRepository, posts repository.PostRepository, checker spam.Checker, indexer Indexer) CommentService {
+	if indexer == nil {
+		indexer = NopIndexer{}
+	}
+	return &commentService{comments: comments, posts: posts, checker: checker, indexer: indexer}
+}
+
+func (s *commentService) List(ctx context.Context, actor Actor, query CommentQuery) ([]models.Comment, *utils.PageResult, error) {
+	postVisible := postVisibility(actor)
+	comments, total, err := s.comments.Page(ctx, repository.CommentFilter{
+		Visibility:     commentVisibility(actor),
+		PostVisibility: &postVisible,
+		PostID:         query.PostID,
+		UserID:         query.UserID,
+		TopLevel:       query.TopLevel,
+	}, &query.Pagination)
+	if err != nil {
+		return nil, nil, err
+	}
+	return comments, utils.NewPageResult(comments, total, &query.Pagination), nil
+}
+
+func (s *commentService) ListByPost(ctx context.Context, actor Actor, postID uint64, page utils.Pagination) ([]models.Comment, *utils.PageResult, error) {
+	post, err := s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return nil, nil, notFoundAs(err, ErrPostNotFound)
+	}
+	if !canViewPost(actor, post) {
+		return nil, nil, ErrPostNotFound
+	}
+	comments, total, err := s.comments.Page(ctx, repository.CommentFilter{
+		Visibility: commentVisibility(actor),
+		PostID:     uint64(post.ID),
+	}, &page)
+	if err != nil {
+		return nil, nil, err
+	}
+	return comments, utils.NewPageResult(comments, total, &page), nil
+}
+
+func (s *commentService) ListByUser(ctx context.Context, userID uint64) ([]models.Comment, error) {
+	return s.comments.Find(ctx, repository.CommentFilter{
+		Visibility: repository.Visibility{All: true},
+		UserID:     userID,
+	}, 0)
+}
+
+func (s *commentService) Get(ctx context.Context, actor Actor, id uint64) (*models.Comment, error) {
+	comment, err := s.comments.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFoundAs(err, ErrCommentNotFound)
+	}
+	if !canViewPost(actor, &comment.Post) || !canViewComment(actor, comment) {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// Threads 所有评论的内嵌回复一次查出，再统一统计这些回复的回复数，查询次数与评论条数无关
+func (s *commentService) Threads(ctx context.Context, actor Actor, comments []models.Comment, embed int) ([]CommentThread, error) {
+	visibility := commentVisibility(actor)
+	ids := make([]uint64, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, uint64(comment.ID))
+	}
+	counts, err := s.comments.CountReplies(ctx, ids, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	threads := make([]CommentThread, 0, len(comments))
+	for i := range comments {
+		threads = append(threads, CommentThread{
+			Comment:    comments[i],
+			ReplyCount: counts[uint64(comments[i].ID)],
+			Replies:    []CommentThread{},
+		})
+	}
+	if embed <= 0 {
+		return threads, nil
+	}
+
+	// 每条评论多查一条回复，判断是否还有下一页
+	replies, err := s.comments.FindReplies(ctx, ids, visibility, embed+1)
+	if err != nil {
+		return nil, err
+	}
+	var embedded []models.Comment
+	sizes := make([]int, len(threads))
+	for i := range threads {
+		page, nextCursor := nextPage(replies[ids[i]], embed)
+		threads[i].NextCursor = nextCursor
+		sizes[i] = len(page)
+		embedded = append(embedded, page...)
+	}
+	nested, err := s.Threads(ctx, actor, embedded, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range threads {
+		threads[i].Replies, nested = nested[:sizes[i]:sizes[i]], nested[sizes[i]:]
+	}
+	return threads, nil
+}
+
+func (s *commentService) Replies(ctx context.Context, actor Actor, parentID, cursor uint64, limit int) ([]CommentThread, string, error) {
+	parent, err := s.Get(ctx, actor, parentID)
+	if err != nil {
+		return nil, "", err
+	}
+	replies, nextCursor, err := s.loadReplies(ctx, commentVisibility(actor), uint64(parent.ID), cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	threads, err := s.Threads(ctx, actor, replies, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	return threads, nextCursor, nil
+}
+
+// loadReplies 多查一条判断是否还有下一页
+func (s *commentService) loadReplies(ctx context.Context, visibility repository.Visibility, parentID, cursor uint64, limit int) ([]models.Comment, string, error) {
+	replies, err := s.comments.Find(ctx, repository.CommentFilter{
+		Visibility: visibility,
+		ParentID:   &parentID,
+		AfterID:    cursor,
+	}, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	replies, nextCursor := nextPage(replies, limit)
+	return replies, nextCursor, nil
+}
+
+// nextPage 截取多查了一条的结果，超出 limit 时返回下一页游标
+func nextPage(replies []models.Comment, limit int) ([]models.Comment, string) {
+	if len(replies) <= limit {
+		return replies, ""
+	}
+	replies = replies[:limit]
+	return replies, strconv.FormatUint(uint64(replies[limit-1].ID), 10)
+}
+
+// Create 回复与上级评论属于同一篇文章，嵌套层数不超过 MaxCommentDepth
+func (s *commentService) Create(ctx context.Context, actor Actor, input CommentInput) (*models.Comment, error) {
+	var post *models.Post
+	var parent *models.Comment
+	if input.ParentID > 0 {
+		var err error
+		if parent, err = s.comments.FindByID(ctx, input.ParentID); err != nil {
+			return nil, notFoundAs(err, ErrCommentNotFound)
+		}
+		post = &parent.Post
+	} else {
+		var err error
+		if post, err = s.posts.FindByID(ctx, input.PostID); err != nil {
+			return nil, notFoundAs(err, ErrPostNotFound)
+		}
+	}
+	// 只能评论已发布的文章
+	if !post.IsPublished() {
+		return nil, ErrPostNotPublished
+	}
+	if parent != nil && !parent.IsApproved() {
+		return nil, ErrCommentNotApproved
+	}
+	if parent != nil && !parent.CanReply() {
+		return nil, ErrReplyTooDeep
+	}
+
+	status, err := s.decideStatus(ctx, actor, post)
+	if err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{
+		Content: input.Content,
+		UserID:  actor.UserID,
+		PostID:  uint64(post.ID),
+		Status:  status,
+	}
+	if parent != nil {
+		parentID := uint64(parent.ID)
+		comment.ParentID = &parentID
+		comment.Depth = parent.Depth + 1
+	}
+	checked := s.checkSpam(ctx, actor, post, comment, &input)
+	if err := comment.Render(); err != nil {
+		return nil, err
+	}
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	// 保存成功后才计入发帖频率，失败的请求不占用次数
+	if recorder, ok := s.checker.(spam.Recorder); ok && checked != nil {
+		recorder.Record(ctx, checked)
+	}
+	metrics.CommentsCreated.WithLabelValues(comment.Status).Inc()
+	s.indexer.SyncComment(ctx, comment.ID)
+	return comment, nil
+}
+
+// Update 拥有 comment:moderate 权限的用户可以修改任意评论
+func (s *commentService) Update(ctx context.Context, actor Actor, input UpdateCommentInput) (*models.Comment, error) {
+	post, err := s.posts.FindByID(ctx, input.PostID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrPostNotFound)
+	}
+	comment, err := s.findOwned(ctx, actor, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.PostID != input.PostID {
+		return nil, ErrCommentNotFound
+	}
+
+	comment.Content = input.Content
+	// 未通过检测的修改重新进入审核，已通过的评论不能靠修改绕过垃圾检测
+	s.checkSpam(ctx, actor, post, comment, &CommentInput{IP: input.IP, UserAgent: input.UserAgent, Referrer: input.Referrer})
+	if err := comment.Render(); err != nil {
+		return nil, err
+	}
+	// 按版本号条件更新，版本不一致说明评论已被他人修改
+	err = s.comments.UpdateContent(ctx, comment, input.Version)
+	if errors.Is(err, repository.ErrConflict) {
+		conflict := &ConflictError{ID: comment.ID}
+		if current, err := s.comments.FindByID(ctx, uint64(comment.ID)); err == nil {
+			conflict.Current = current.Version
+		}
+		return nil, conflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.indexer.SyncComment(ctx, comment.ID)
+	return comment, nil
+}
+
+func (s *commentService) Delete(ctx context.Context, actor Actor, id uint64) ([]uint64, error) {
+	comment, err := s.findOwned(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.comments.Delete(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	for _, commentId := range ids {
+		s.indexer.SyncComment(ctx, uint(commentId))
+	}
+	return ids, nil
+}
+
+func (s *commentService) Recount(ctx context.Context, postIDs ...uint64) (int64, error) {
+	return s.comments.Recount(ctx, postIDs...)
+}
+
+func (s *commentService) ModerationQueue(ctx context.Context, actor Actor, query ModerationQuery) ([]models.Comment, *utils.PageResult, error) {
+	if !actor.Can(models.PermCommentModerate) {
+		return nil, nil, ErrPermissionDenied
+	}
+	if query.Status == "" {
+		query.Status = models.CommentStatusPending
+	}
+	comments, total, err := s.comments.Page(ctx, repository.CommentFilter{
+		Visibility: repository.Visibility{All: true},
+		PostID:     query.PostID,
+		Status:     query.Status,
+	}, &query.Pagination)
+	if err != nil {
+		return nil, nil, err
+	}
+	return comments, utils.NewPageResult(comments, total, &query.Pagination), nil
+}
+
+func (s *commentService) Moderate(ctx context.Context, actor Actor, ids []uint64, status string) (int64, error) {
+	if !actor.Can(models.PermCommentModerate) {
+		return 0, ErrPermissionDenied
+	}
+	updated, err := s.comments.Moderate(ctx, ids, status)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range updated {
+		s.indexer.SyncComment(ctx, uint(id))
+	}
+	logger.Log.WithContext(ctx).Infof("comments moderated | status: %s, ids: %v, updated: %d, user_id: %v",
+		status, ids, len(updated), actor.UserID)
+	return int64(len(updated)), nil
+}
+
+func (s *commentService) ModerationSetting(ctx context.Context, actor Actor) (*models.ModerationSetting, error) {
+	if !actor.Can(models.PermCommentModerate) {
+		return nil, ErrPermissionDenied
+	}
+	return s.comments.ModerationSetting(ctx)
+}
+
+func (s *commentService) UpdateModerationSetting(ctx context.Context, actor Actor, input ModerationSettingInput) (*models.ModerationSetting, error) {
+	setting, err := s.ModerationSetting(ctx, actor)
+	if err != nil {
+		return nil, err
+	}
+	if input.HoldAll != nil {
+		setting.HoldAll = *input.HoldAll
+	}
+	if input.HoldFirstTime != nil {
+		setting.HoldFirstTime = *input.HoldFirstTime
+	}
+	if input.AutoApproveTrusted != nil {
+		setting.AutoApproveTrusted = *input.AutoApproveTrusted
+	}
+	if input.TrustedThreshold != nil {
+		setting.TrustedThreshold = *input.TrustedThreshold
+	}
+	if err := s.comments.SaveModerationSetting(ctx, setting); err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+// findOwned 查找 actor 自己的评论，拥有 comment:moderate 权限时可以是任意评论，已删除的占位评论不能再操作
+func (s *commentService) findOwned(ctx context.Context, actor Actor, id uint64) (*models.Comment, error) {
+	comment, err := s.comments.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFoundAs(err, ErrCommentNotFound)
+	}
+	if comment.IsDeleted() {
+		return nil, ErrCommentNotFound
+	}
+	if !actor.Can(models.PermCommentModerate) && comment.UserID != actor.UserID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// decideStatus 按全局设置和文章设置决定新评论的审核状态
+// 拥有 comment:moderate 权限或通过审核的评论数达到阈值的用户视为可信用户
+func (s *commentService) decideStatus(ctx context.Context, actor Actor, post *models.Post) (string, error) {
+	setting, err := s.comments.ModerationSetting(ctx)
+	if err != nil {
+		return "", err
+	}
+	approved, err := s.comments.CountApproved(ctx, actor.UserID)
+	if err != nil {
+		return "", err
+	}
+	trusted := actor.Can(models.PermCommentModerate) ||
+		(setting.TrustedThreshold > 0 && approved >= int64(setting.TrustedThreshold))
+	return setting.Decide(post.CommentModeration, trusted, approved == 0), nil
+}
+
+// checkSpam 对新评论或修改后的评论做垃圾检测，记录得分和原因并调整审核状态，返回检测的输入
+// 拥有 comment:moderate 权限的用户跳过检测，返回 nil
+func (s *commentService) checkSpam(ctx context.Context, actor Actor, post *models.Post, comment *models.Comment, input *CommentInput) *spam.Input {
+	if s.checker == nil || actor.Can(models.PermCommentModerate) {
+		return nil
+	}
+	in := &spam.Input{
+		CommentID: uint64(comment.ID),
+		UserID:    comment.UserID,
+		PostID:    comment.PostID,
+		Author:    actor.Username,
+		Content:   comment.Content,
+		IP:        input.IP,
+		UserAgent: input.UserAgent,
+		Referrer:  input.Referrer,
+		Permalink: "/post/" + strconv.FormatUint(uint64(post.ID), 10),
+		Honeypot:  input.Honeypot,
+	}
+	result, err := s.checker.Check(ctx, in)
+	if err != nil {
+		logger.Log.WithContext(ctx).Errorf("spam check err: %v", err)
+		return in
+	}
+	comment.SpamScore = result.Score
+	comment.SpamReasons = strings.Join(result.Reasons, "\n")
+	switch {
+	case result.IsSpam():
+		comment.Status = models.CommentStatusSpam
+	case result.NeedsReview() && comment.Status == models.CommentStatusApproved:
+		comment.Status = models.CommentStatusPending
+	}
+	if result.Score > 0 {
+		logger.Log.WithContext(ctx).Infof("comment flagged | post_id: %d, user_id: %d, score: %.2f, status: %s, reasons: %v",
+			comment.PostID, comment.UserID, result.Score, comment.Status, result.Reasons)
+	}
+	return in
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/spam"
+	"github.com/gavin/blog/utils"
+)
+
+// countingComments 记录 Find 和 FindReplies 的调用次数
+type countingComments struct {
+	repository.CommentRepository
+	queries int
+}
+
+func (r *countingComments) Find(ctx context.Context, filter repository.CommentFilter, limit int) ([]models.Comment, error) {
+	r.queries++
+	return r.CommentRepository.Find(ctx, filter, limit)
+}
+
+func (r *countingComments) FindReplies(ctx context.Context, parentIDs []uint64, visibility repository.Visibility, limit int) (map[uint64][]models.Comment, error) {
+	r.queries++
+	return r.CommentRepository.FindReplies(ctx, parentIDs, visibility, limit)
+}
+
+var editor = Actor{UserID: 100, Permissions: []string{models.PermPostPublish, models.PermPostEdit}}
+
+// publishedPost 准备一篇已发布的文章，关闭评论审核
+func publishedPost(t *testing.T, repos *repository.Repositories) *models.Post {
+	t.Helper()
+	ctx := context.Background()
+	repos.Comments.(*repository.MemoryCommentRepository).SetModerationSetting(models.ModerationSetting{})
+	posts := NewPostService(repos.Posts, repos.Comments, nil)
+	post, err := posts.Create(ctx, editor, PostInput{Title: "post", Content: "content"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, err = posts.Transition(ctx, editor, uint64(post.ID), models.PostActionPublish); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func comment(t *testing.T, svc CommentService, actor Actor, postID, parentID uint64) *models.Comment {
+	t.Helper()
+	c, err := svc.Create(context.Background(), actor, CommentInput{PostID: postID, ParentID: parentID, Content: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestThreadsLoadsRepliesInOneQuery(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	counting := &countingComments{CommentRepository: repos.Comments}
+	svc := NewCommentService(counting, repos.Posts, nil, nil)
+	post := publishedPost(t, repos)
+	user := Actor{UserID: 1}
+
+	var top []models.Comment
+	for i := 0; i < 5; i++ {
+		parent := comment(t, svc, user, uint64(post.ID), 0)
+		for j := 0; j < 3; j++ {
+			comment(t, svc, user, 0, uint64(parent.ID))
+		}
+		top = append(top, *parent)
+	}
+
+	counting.queries = 0
+	threads, err := svc.Threads(ctx, Actor{}, top, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.queries != 1 {
+		t.Errorf("reply queries = %d, want 1", counting.queries)
+	}
+	for _, thread := range threads {
+		if thread.ReplyCount != 3 || len(thread.Replies) != 2 || thread.NextCursor == "" {
+			t.Errorf("thread %d: count=%d replies=%d cursor=%q", thread.Comment.ID, thread.ReplyCount, len(thread.Replies), thread.NextCursor)
+		}
+		for _, reply := range thread.Replies {
+			if reply.Comment.ParentID == nil || *reply.Comment.ParentID != uint64(thread.Comment.ID) {
+				t.Errorf("reply %d embedded under %d", reply.Comment.ID, thread.Comment.ID)
+			}
+		}
+	}
+}
+
+func TestDeleteKeepsOtherUsersReplies(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewCommentService(repos.Comments, repos.Posts, nil, nil)
+	post := publishedPost(t, repos)
+	alice, bob := Actor{UserID: 1}, Actor{UserID: 2}
+
+	parent := comment(t, svc, alice, uint64(post.ID), 0)
+	reply := comment(t, svc, bob, 0, uint64(parent.ID))
+
+	// 有回复：只清空内容保留占位，评论数扣除被删除的一条
+	if _, err := svc.Delete(ctx, alice, uint64(parent.ID)); err != nil {
+		t.Fatal(err)
+	}
+	tombstone, err := svc.Get(ctx, Actor{}, uint64(parent.ID))
+	if err != nil {
+		t.Fatalf("tombstone not visible: %v", err)
+	}
+	if !tombstone.IsDeleted() || tombstone.Content != "" || tombstone.ContentHTML != "" {
+		t.Errorf("tombstone = %+v", tombstone)
+	}
+	if _, err := svc.Get(ctx, Actor{}, uint64(reply.ID)); err != nil {
+		t.Errorf("reply deleted with parent: %v", err)
+	}
+	if got, _ := repos.Posts.FindByID(ctx, uint64(post.ID)); got.CommentCount != 1 {
+		t.Errorf("comment_count = %d, want 1", got.CommentCount)
+	}
+	// 占位评论不能再修改或删除
+	if _, err := svc.Delete(ctx, alice, uint64(parent.ID)); err != ErrCommentNotFound {
+		t.Errorf("delete tombstone err = %v, want ErrCommentNotFound", err)
+	}
+
+	// 最后一条回复删除后占位一并删除
+	ids, err := svc.Delete(ctx, bob, uint64(reply.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Errorf("deleted ids = %v, want reply and tombstone", ids)
+	}
+	if _, err := svc.Get(ctx, Actor{}, uint64(parent.ID)); err != ErrCommentNotFound {
+		t.Errorf("tombstone kept after last reply deleted: %v", err)
+	}
+	if got, _ := repos.Posts.FindByID(ctx, uint64(post.ID)); got.CommentCount != 0 {
+		t.Errorf("comment_count = %d, want 0", got.CommentCount)
+	}
+}
+
+func commentCount(t *testing.T, repos *repository.Repositories, postID uint) int {
+	t.Helper()
+	post, err := repos.Posts.FindByID(context.Background(), uint64(postID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return post.CommentCount
+}
+
+func TestCommentCountTracksApprovedComments(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewCommentService(repos.Comments, repos.Posts, nil, nil)
+	post := publishedPost(t, repos)
+	user := Actor{UserID: 1}
+
+	approved := comment(t, svc, user, uint64(post.ID), 0)
+	if got := commentCount(t, repos, post.ID); got != 1 {
+		t.Fatalf("after approved comment: %d, want 1", got)
+	}
+	// 待审核的评论不计数，删除时也不扣除
+	repos.Comments.(*repository.MemoryCommentRepository).SetModerationSetting(models.ModerationSetting{HoldAll: true})
+	pending := comment(t, svc, Actor{UserID: 2}, uint64(post.ID), 0)
+	if pending.Status != models.CommentStatusPending {
+		t.Fatalf("status = %s, want pending", pending.Status)
+	}
+	if got := commentCount(t, repos, post.ID); got != 1 {
+		t.Errorf("after pending comment: %d, want 1", got)
+	}
+	if _, err := svc.Delete(ctx, Actor{UserID: 2}, uint64(pending.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Delete(ctx, user, uint64(approved.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if got := commentCount(t, repos, post.ID); got != 0 {
+		t.Errorf("after deletes: %d, want 0", got)
+	}
+}
+
+func TestCommentVisibility(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewCommentService(repos.Comments, repos.Posts, nil, nil)
+	post := publishedPost(t, repos)
+	repos.Comments.(*repository.MemoryCommentRepository).SetModerationSetting(models.ModerationSetting{HoldAll: true})
+	author := Actor{UserID: 1}
+	pending := comment(t, svc, author, uint64(post.ID), 0)
+
+	moderator := Actor{UserID: 9, Permissions: []string{models.PermCommentModerate}}
+	for name, actor := range map[string]Actor{"author": author, "moderator": moderator} {
+		if _, err := svc.Get(ctx, actor, uint64(pending.ID)); err != nil {
+			t.Errorf("%s get pending err = %v", name, err)
+		}
+	}
+	for name, actor := range map[string]Actor{"anonymous": {}, "other": {UserID: 2}} {
+		if _, err := svc.Get(ctx, actor, uint64(pending.ID)); err != ErrCommentNotFound {
+			t.Errorf("%s get pending err = %v, want ErrCommentNotFound", name, err)
+		}
+		comments, _, err := svc.ListByPost(ctx, actor, uint64(post.ID), utils.Pagination{Page: 1, PageSize: 10})
+		if err != nil || len(comments) != 0 {
+			t.Errorf("%s list = %d comments, %v; want none", name, len(comments), err)
+		}
+		// 别人的评论不能修改
+		if _, err := svc.Update(ctx, actor, UpdateCommentInput{ID: uint64(pending.ID), PostID: uint64(post.ID), Content: "x", Version: pending.Version}); err != ErrCommentNotFound {
+			t.Errorf("%s update err = %v, want ErrCommentNotFound", name, err)
+		}
+	}
+}
+
+func TestCommentUpdateVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewCommentService(repos.Comments, repos.Posts, nil, nil)
+	post := publishedPost(t, repos)
+	user := Actor{UserID: 1}
+	c := comment(t, svc, user, uint64(post.ID), 0)
+
+	input := UpdateCommentInput{ID: uint64(c.ID), PostID: uint64(post.ID), Content: "edited", Version: c.Version}
+	updated, err := svc.Update(ctx, user, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != c.Version+1 {
+		t.Errorf("version = %d, want %d", updated.Version, c.Version+1)
+	}
+	_, err = svc.Update(ctx, user, input)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Current != updated.Version {
+		t.Errorf("stale update err = %v, want *ConflictError with current %d", err, updated.Version)
+	}
+}
+
+// wordChecker 内容包含 word 时判为垃圾评论
+type wordChecker struct {
+	word   string
+	inputs []spam.Input
+}
+
+func (w *wordChecker) Check(_ context.Context, in *spam.Input) (spam.Result, error) {
+	w.inputs = append(w.inputs, *in)
+	var r spam.Result
+	if strings.Contains(in.Content, w.word) {
+		r.Add(spam.SpamScore, "word: "+w.word)
+	}
+	return r, nil
+}
+
+func TestCommentUpdateRechecksSpam(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	checker := &wordChecker{word: "casino"}
+	svc := NewCommentService(repos.Comments, repos.Posts, checker, nil)
+	post := publishedPost(t, repos)
+	user := Actor{UserID: 1}
+	c := comment(t, svc, user, uint64(post.ID), 0)
+	if c.Status != models.CommentStatusApproved || commentCount(t, repos, post.ID) != 1 {
+		t.Fatalf("status = %s, count = %d", c.Status, commentCount(t, repos, post.ID))
+	}
+
+	updated, err := svc.Update(ctx, user, UpdateCommentInput{ID: uint64(c.ID), PostID: uint64(post.ID), Content: "visit my casino", Version: c.Version, IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != models.CommentStatusSpam || updated.SpamReasons == "" {
+		t.Errorf("edited comment status = %s, reasons = %q", updated.Status, updated.SpamReasons)
+	}
+	last := checker.inputs[len(checker.inputs)-1]
+	if last.CommentID != uint64(c.ID) || last.IP != "10.0.0.1" {
+		t.Errorf("check input = %+v", last)
+	}
+	if got := commentCount(t, repos, post.ID); got != 0 {
+		t.Errorf("comment_count = %d, want 0 after edit flagged as spam", got)
+	}
+	if _, err := svc.Get(ctx, Actor{}, uint64(c.ID)); err != ErrCommentNotFound {
+		t.Errorf("flagged edit still public: %v", err)
+	}
+
+	// 审核员修改不做检测
+	moderator := Actor{UserID: 9, Permissions: []string{models.PermCommentModerate}}
+	checked := len(checker.inputs)
+	if _, err := svc.Update(ctx, moderator, UpdateCommentInput{ID: uint64(c.ID), PostID: uint64(post.ID), Content: "casino", Version: updated.Version}); err != nil {
+		t.Fatal(err)
+	}
+	if len(checker.inputs) != checked {
+		t.Error("moderator edit was spam checked")
+	}
+}
+
+func TestModerateUpdatesCountsAndIndex(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	indexer := &recordingIndexer{}
+	svc := NewCommentService(repos.Comments, repos.Posts, nil, indexer)
+	post := publishedPost(t, repos)
+	repos.Comments.(*repository.MemoryCommentRepository).SetModerationSetting(models.ModerationSetting{HoldAll: true})
+	first := comment(t, svc, Actor{UserID: 1}, uint64(post.ID), 0)
+	second := comment(t, svc, Actor{UserID: 2}, uint64(post.ID), 0)
+	ids := []uint64{uint64(first.ID), uint64(second.ID)}
+
+	if _, err := svc.Moderate(ctx, editor, ids, models.CommentStatusApproved); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Moderate err = %v, want ErrPermissionDenied", err)
+	}
+	moderator := Actor{UserID: 9, Permissions: []string{models.PermCommentModerate}}
+	queue, _, err := svc.ModerationQueue(ctx, moderator, ModerationQuery{Pagination: utils.Pagination{Page: 1, PageSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("pending queue = %d comments, want 2", len(queue))
+	}
+
+	indexer.comments = nil
+	updated, err := svc.Moderate(ctx, moderator, ids, models.CommentStatusApproved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 2 || commentCount(t, repos, post.ID) != 2 {
+		t.Errorf("approved %d, count %d; want 2, 2", updated, commentCount(t, repos, post.ID))
+	}
+	if !reflect.DeepEqual(indexer.comments, []uint{first.ID, second.ID}) {
+		t.Errorf("synced comments = %v, want both", indexer.comments)
+	}
+	// 已是目标状态的评论不重复计数
+	if updated, _ = svc.Moderate(ctx, moderator, ids, models.CommentStatusApproved); updated != 0 {
+		t.Errorf("approved again: %d, want 0", updated)
+	}
+	if updated, _ = svc.Moderate(ctx, moderator, ids[:1], models.CommentStatusSpam); updated != 1 || commentCount(t, repos, post.ID) != 1 {
+		t.Errorf("spam %d, count %d; want 1, 1", updated, commentCount(t, repos, post.ID))
+	}
+
+	setting, err := svc.UpdateModerationSetting(ctx, moderator, ModerationSettingInput{HoldAll: new(bool)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setting.HoldAll {
+		t.Error("hold_all not cleared")
+	}
+	if _, err := svc.ModerationSetting(ctx, editor); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("ModerationSetting err = %v, want ErrPermissionDenied", err)
+	}
+}
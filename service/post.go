@@ -0,0 +1,505 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/metrics"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/utils"
+)
+
+// 文章列表排序方式
+const (
+	PostSortLatest        = "latest"
+	PostSortMostDiscussed = "most_discussed"
+)
+
+// 文章详情附带的评论数
+const postDetailComments = 10
+
+type PostQuery struct {
+	utils.Pagination
+	UserID uint64
+	Status string
+	// 标签名称或 slug
+	Tag string
+	// 分类 ID，包含子分类
+	CategoryID uint64
+	Sort       string
+}
+
+// PostInput 新建或修改文章，ID 和 Version 只在修改时使用
+type PostInput struct {
+	ID      uint64
+	Title   string
+	Content string
+	// 修改时为 nil 表示不修改标签，空数组表示清空
+	Tags []string
+	// 修改时为 nil 表示不修改分类，0 表示清空分类
+	CategoryID *uint64
+	Version    int
+}
+
+// PostService 文章的查询、编辑和状态流转
+// 写操作的 actor 必须是已登录用户，文章不可见或无权操作时都返回 ErrPostNotFound
+type PostService interface {
+	List(ctx context.Context, actor Actor, query PostQuery) ([]models.Post, *utils.PageResult, error)
+	// Get 查询单篇文章，附带前 10 条可见评论
+	Get(ctx context.Context, actor Actor, id uint64) (*models.Post, error)
+	// GetBySlug 按 slug 查询文章，slug 是旧 slug 时返回当前 slug 用于跳转
+	GetBySlug(ctx context.Context, actor Actor, slug string) (*models.Post, string, error)
+	// ListByUser 当前用户的文章，每篇附带最新 10 条可见评论
+	ListByUser(ctx context.Context, actor Actor) ([]models.Post, error)
+	// FindEditable 查找 actor 可以操作的文章：作者本人或拥有 post:edit 权限，permission 不为空时还需拥有该权限
+	FindEditable(ctx context.Context, actor Actor, id uint64, permission string) (*models.Post, error)
+
+	// Create 新建文章，默认为草稿
+	Create(ctx context.Context, actor Actor, input PostInput) (*models.Post, error)
+	// Update 按版本号修改文章，版本不一致返回 *ConflictError；修改已发布的文章需要 post:publish
+	Update(ctx context.Context, actor Actor, input PostInput) (*models.Post, error)
+	// Revisions 文章的修订列表（不含正文），按修订号倒序，需要能操作该文章
+	Revisions(ctx context.Context, actor Actor, postID uint64) ([]models.PostRevision, error)
+	// Revision 按修订号查询修订，number 为 0 时返回最新的修订，不存在返回 ErrRevisionNotFound
+	Revision(ctx context.Context, actor Actor, postID uint64, number int) (*models.PostRevision, error)
+	// Restore 将文章恢复到某个修订，恢复本身作为一个新修订记录；与 Update 一样，已发布的文章需要 post:publish
+	Restore(ctx context.Context, actor Actor, postID uint64, number int) (*models.Post, error)
+	// Delete 删除文章，拥有 post:delete 权限时可以删除任意文章
+	Delete(ctx context.Context, actor Actor, id uint64) error
+	// Transition 按动作流转文章状态，不允许的流转返回 ErrInvalidTransition 和当前状态的文章
+	Transition(ctx context.Context, actor Actor, id uint64, action string) (*models.Post, error)
+	// Schedule 设置定时发布时间，当前状态不允许时返回 ErrInvalidTransition 和当前状态的文章
+	Schedule(ctx context.Context, actor Actor, id uint64, at time.Time) (*models.Post, error)
+	Unschedule(ctx context.Context, actor Actor, id uint64) error
+	// ClaimScheduled 认领到期的定时文章并清除其定时发布时间，由调度器调用，之后按设置人的权限 Transition 发布
+	ClaimScheduled(ctx context.Context, limit int) ([]models.Post, error)
+	// Reschedule 认领后发布失败时恢复文章原来的定时发布时间和设置人，下一轮重试
+	Reschedule(ctx context.Context, post *models.Post) error
+	// SetCommentModeration 设置文章的评论审核方式：inherit、open、hold
+	// 设为 open 会绕过全局审核设置，需要 comment:moderate 权限，否则返回 ErrPermissionDenied
+	SetCommentModeration(ctx context.Context, actor Actor, id uint64, mode string) (string, error)
+}
+
+type postService struct {
+	posts    repository.PostRepository
+	comments repository.CommentRepository
+	indexer  Indexer
+}
+
+func NewPostService(posts repository.PostRepository, comments repository.CommentRepository, indexer Indexer) PostService {
+	if indexer == nil {
+		indexer = NopIndexer{}
+	}
+	return &postService{posts: posts, comments: comments, indexer: indexer}
+}
+
+func (s *postService) List(ctx context.Context, actor Actor, query PostQuery) ([]models.Post, *utils.PageResult, error) {
+	filter := repository.PostFilter{
+		Visibility:    postVisibility(actor),
+		UserID:        query.UserID,
+		Status:        query.Status,
+		Tag:           query.Tag,
+		MostDiscussed: query.Sort == PostSortMostDiscussed,
+	}
+	if query.CategoryID > 0 {
+		categoryIds, err := s.posts.CategoryDescendantIDs(ctx, query.CategoryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.CategoryIDs = categoryIds
+	}
+	posts, total, err := s.posts.Page(ctx, filter, &query.Pagination)
+	if err != nil {
+		return nil, nil, err
+	}
+	return posts, utils.NewPageResult(posts, total, &query.Pagination), nil
+}
+
+func (s *postService) Get(ctx context.Context, actor Actor, id uint64) (*models.Post, error) {
+	post, err := s.posts.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFoundAs(err, ErrPostNotFound)
+	}
+	return s.detail(ctx, actor, post)
+}
+
+func (s *postService) GetBySlug(ctx context.Context, actor Actor, slug string) (*models.Post, string, error) {
+	post, err := s.posts.FindBySlug(ctx, slug)
+	if errors.Is(err, repository.ErrNotFound) {
+		// 旧 slug 跳转到当前 slug
+		if current, err := s.posts.FindByOldSlug(ctx, slug); err == nil && current.Slug != "" {
+			return nil, current.Slug, nil
+		}
+		return nil, "", ErrPostNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	post, err = s.detail(ctx, actor, post)
+	return post, "", err
+}
+
+// detail 校验可见性并加载前 10 条可见评论
+func (s *postService) detail(ctx context.Context, actor Actor, post *models.Post) (*models.Post, error) {
+	if !canViewPost(actor, post) {
+		return nil, ErrPostNotFound
+	}
+	comments, err := s.comments.Find(ctx, repository.CommentFilter{
+		Visibility: commentVisibility(actor),
+		PostID:     uint64(post.ID),
+	}, postDetailComments)
+	if err != nil {
+		return nil, err
+	}
+	post.Comments = comments
+	return post, nil
+}
+
+func (s *postService) ListByUser(ctx context.Context, actor Actor) ([]models.Post, error) {
+	posts, err := s.posts.FindByUser(ctx, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		comments, err := s.comments.Find(ctx, repository.CommentFilter{
+			Visibility: commentVisibility(actor),
+			PostID:     uint64(posts[i].ID),
+			Desc:       true,
+		}, postDetailComments)
+		if err != nil {
+			return nil, err
+		}
+		posts[i].Comments = comments
+	}
+	return posts, nil
+}
+
+func (s *postService) FindEditable(ctx context.Context, actor Actor, id uint64, permission string) (*models.Post, error) {
+	if permission != "" && !actor.Can(permission) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, permission)
+	}
+	return s.findOwned(ctx, actor, id, models.PermPostEdit)
+}
+
+// findOwned 查找 actor 自己的文章，拥有 override 权限时可以是任意文章
+func (s *postService) findOwned(ctx context.Context, actor Actor, id uint64, override string) (*models.Post, error) {
+	post, err := s.posts.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFoundAs(err, ErrPostNotFound)
+	}
+	if !actor.Can(override) && post.UserID != actor.UserID {
+		return nil, ErrPostNotFound
+	}
+	return post, nil
+}
+
+func (s *postService) Create(ctx context.Context, actor Actor, input PostInput) (*models.Post, error) {
+	post := &models.Post{
+		Title:   input.Title,
+		Content: input.Content,
+		UserID:  actor.UserID,
+		Status:  models.PostStatusDraft,
+	}
+	if err := post.Render(); err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(ctx, input.CategoryID); err != nil {
+		return nil, err
+	}
+	post.CategoryID = categoryID(input.CategoryID)
+
+	tags := models.NormalizeTagNames(input.Tags)
+	err := retrySlug(func() error {
+		slug, err := s.uniqueSlug(ctx, input.Title, 0)
+		if err != nil {
+			return err
+		}
+		post.Slug = slug
+		return s.posts.Create(ctx, post, tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.PostsCreated.Inc()
+	s.indexer.SyncPost(ctx, post.ID)
+	return post, nil
+}
+
+func (s *postService) Update(ctx context.Context, actor Actor, input PostInput) (*models.Post, error) {
+	post, err := s.findOwned(ctx, actor, input.ID, models.PermPostEdit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCategory(ctx, input.CategoryID); err != nil {
+		return nil, err
+	}
+	if err := checkLiveEdit(actor, post); err != nil {
+		return nil, err
+	}
+	if input.Version != post.Version {
+		return nil, &ConflictError{ID: post.ID, Current: post.Version}
+	}
+
+	before := *post
+	post.Title = input.Title
+	post.Content = input.Content
+	if input.CategoryID != nil {
+		post.CategoryID = categoryID(input.CategoryID)
+	}
+	var tags []string
+	if input.Tags != nil {
+		tags = models.NormalizeTagNames(input.Tags)
+	}
+	if err := s.save(ctx, actor, post, &before, "", tags); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (s *postService) Revisions(ctx context.Context, actor Actor, postID uint64) ([]models.PostRevision, error) {
+	post, err := s.FindEditable(ctx, actor, postID, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.posts.Revisions(ctx, uint64(post.ID))
+}
+
+func (s *postService) Revision(ctx context.Context, actor Actor, postID uint64, number int) (*models.PostRevision, error) {
+	post, err := s.FindEditable(ctx, actor, postID, "")
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.posts.FindRevision(ctx, uint64(post.ID), number)
+	if err != nil {
+		return nil, notFoundAs(err, ErrRevisionNotFound)
+	}
+	return revision, nil
+}
+
+func (s *postService) Restore(ctx context.Context, actor Actor, postID uint64, number int) (*models.Post, error) {
+	post, err := s.FindEditable(ctx, actor, postID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLiveEdit(actor, post); err != nil {
+		return nil, err
+	}
+	revision, err := s.posts.FindRevision(ctx, uint64(post.ID), number)
+	if err != nil {
+		return nil, notFoundAs(err, ErrRevisionNotFound)
+	}
+	before := *post
+	post.Title = revision.Title
+	post.Content = revision.Content
+	if err := s.save(ctx, actor, post, &before, fmt.Sprintf("restore from r%d", revision.Version), nil); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// save 保存文章内容并记录修订，标题变化时重新生成 slug，旧 slug 记入历史继续可访问
+func (s *postService) save(ctx context.Context, actor Actor, post *models.Post, before *models.Post, note string, tags []string) error {
+	if err := post.Render(); err != nil {
+		return err
+	}
+	err := retrySlug(func() error {
+		if post.Title != before.Title {
+			slug, err := s.uniqueSlug(ctx, post.Title, post.ID)
+			if err != nil {
+				return err
+			}
+			post.Slug = slug
+		}
+		return s.posts.Save(ctx, post, before, actor.UserID, note, tags)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return s.conflict(ctx, post.ID)
+	}
+	if err != nil {
+		return err
+	}
+	s.indexer.SyncPost(ctx, post.ID)
+	return nil
+}
+
+// conflict 版本冲突时带上服务端当前版本
+func (s *postService) conflict(ctx context.Context, id uint) error {
+	conflict := &ConflictError{ID: id}
+	if current, err := s.posts.FindByID(ctx, uint64(id)); err == nil {
+		conflict.Current = current.Version
+	}
+	return conflict
+}
+
+func (s *postService) Delete(ctx context.Context, actor Actor, id uint64) error {
+	post, err := s.findOwned(ctx, actor, id, models.PermPostDelete)
+	if err != nil {
+		return err
+	}
+	if err := s.posts.Delete(ctx, post.ID); err != nil {
+		return err
+	}
+	s.indexer.SyncPost(ctx, post.ID)
+	return nil
+}
+
+// Transition 作者可以操作自己的文章，拥有 post:edit 的编辑可以操作任意文章
+// 没有 post:publish 时只能提交审核（submit）或撤回待审核的文章，发布、下线、归档都由有发布权限的人完成
+func (s *postService) Transition(ctx context.Context, actor Actor, id uint64, action string) (*models.Post, error) {
+	post, err := s.FindEditable(ctx, actor, id, "")
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Can(models.PermPostPublish) && !authorAction(action, post.Status) {
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, models.PermPostPublish)
+	}
+
+	from := post.Status
+	if !post.Transition(action) {
+		return post, ErrInvalidTransition
+	}
+	// 带原状态条件更新，避免并发流转互相覆盖
+	err = s.posts.UpdateStatus(ctx, post, from)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, ErrStatusChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexer.SyncPost(ctx, post.ID)
+	logger.Log.WithContext(ctx).Infof("post status changed | post_id: %d, action: %s, from: %s, to: %s, user_id: %v",
+		post.ID, action, from, post.Status, actor.UserID)
+	return post, nil
+}
+
+// checkLiveEdit 已发布的文章修改后立即对所有人可见，与发布一样需要 post:publish
+func checkLiveEdit(actor Actor, post *models.Post) error {
+	if post.IsPublished() && !actor.Can(models.PermPostPublish) {
+		return fmt.Errorf("%w: %s", ErrPermissionDenied, models.PermPostPublish)
+	}
+	return nil
+}
+
+// authorAction 没有发布权限的作者可以执行的流转：提交审核，或把待审核的文章撤回草稿
+func authorAction(action, from string) bool {
+	switch action {
+	case models.PostActionSubmit:
+		return true
+	case models.PostActionUnpublish:
+		return from == models.PostStatusPendingReview
+	}
+	return false
+}
+
+func (s *postService) Schedule(ctx context.Context, actor Actor, id uint64, at time.Time) (*models.Post, error) {
+	if !at.After(time.Now()) {
+		return nil, ErrPublishTimePassed
+	}
+	post, err := s.FindEditable(ctx, actor, id, models.PermPostPublish)
+	if err != nil {
+		return nil, err
+	}
+	if !post.CanSchedule() {
+		return post, ErrInvalidTransition
+	}
+	err = s.posts.Schedule(ctx, post.ID, post.Status, at, actor.UserID)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, ErrStatusChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+	post.ScheduledAt = &at
+	post.ScheduledBy = &actor.UserID
+	return post, nil
+}
+
+func (s *postService) Unschedule(ctx context.Context, actor Actor, id uint64) error {
+	post, err := s.FindEditable(ctx, actor, id, models.PermPostPublish)
+	if err != nil {
+		return err
+	}
+	return s.posts.Unschedule(ctx, post.ID)
+}
+
+func (s *postService) ClaimScheduled(ctx context.Context, limit int) ([]models.Post, error) {
+	return s.posts.ClaimScheduled(ctx, time.Now(), limit)
+}
+
+func (s *postService) Reschedule(ctx context.Context, post *models.Post) error {
+	if post.ScheduledAt == nil || post.ScheduledBy == nil {
+		return nil
+	}
+	err := s.posts.Schedule(ctx, post.ID, post.Status, *post.ScheduledAt, *post.ScheduledBy)
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrStatusChanged
+	}
+	return err
+}
+
+func (s *postService) SetCommentModeration(ctx context.Context, actor Actor, id uint64, mode string) (string, error) {
+	post, err := s.FindEditable(ctx, actor, id, "")
+	if err != nil {
+		return "", err
+	}
+	if mode == "inherit" {
+		mode = models.PostModerationInherit
+	}
+	// 作者只能收紧审核方式（inherit、hold），放宽到 open 需要审核员
+	if mode == models.PostModerationOpen && post.CommentModeration != models.PostModerationOpen &&
+		!actor.Can(models.PermCommentModerate) {
+		return "", ErrPermissionDenied
+	}
+	if err := s.posts.SetCommentModeration(ctx, post.ID, mode); err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+// 写入时 slug 被并发请求占用的最大重试次数
+const slugRetries = 3
+
+// retrySlug 生成 slug 到写入之间，同一 slug 可能被并发请求占用（违反唯一索引），此时重新生成 slug 再写入
+func retrySlug(write func() error) error {
+	for i := 0; i < slugRetries; i++ {
+		if err := write(); !errors.Is(err, repository.ErrDuplicate) {
+			return err
+		}
+	}
+	return ErrSlugConflict
+}
+
+// uniqueSlug 根据标题生成唯一 slug，其他文章的当前 slug 和历史 slug 都视为冲突
+func (s *postService) uniqueSlug(ctx context.Context, title string, postID uint) (string, error) {
+	return models.UniqueSlug(title, func(slug string) (bool, error) {
+		return s.posts.SlugTaken(ctx, slug, postID)
+	})
+}
+
+// categoryID 请求中的分类，0 表示不设置分类
+func categoryID(id *uint64) *uint64 {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+// checkCategory 校验分类是否存在，nil 和 0 不需要校验
+func (s *postService) checkCategory(ctx context.Context, categoryID *uint64) error {
+	if categoryID == nil || *categoryID == 0 {
+		return nil
+	}
+	exists, err := s.posts.CategoryExists(ctx, *categoryID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
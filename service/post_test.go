@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/utils"
+)
+
+// racySlugs 模拟并发：SlugTaken 检查时 slug 还没被占用，写入时已被其他请求占用
+type racySlugs struct {
+	repository.PostRepository
+	races int
+}
+
+func (r *racySlugs) SlugTaken(ctx context.Context, slug string, postID uint) (bool, error) {
+	if r.races > 0 {
+		r.races--
+		return false, nil
+	}
+	return r.PostRepository.SlugTaken(ctx, slug, postID)
+}
+
+func TestCreateRetriesSlugRace(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	posts := &racySlugs{PostRepository: repos.Posts}
+	svc := NewPostService(posts, repos.Comments, nil)
+	author := Actor{UserID: 1}
+
+	if _, err := svc.Create(ctx, author, PostInput{Title: "Hello", Content: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	posts.races = 1
+	post, err := svc.Create(ctx, author, PostInput{Title: "Hello", Content: "b"})
+	if err != nil {
+		t.Fatalf("Create after slug race: %v", err)
+	}
+	if post.Slug != "hello-2" {
+		t.Errorf("slug = %q, want hello-2", post.Slug)
+	}
+
+	// 一直冲突时返回 ErrSlugConflict，而不是笼统的数据库错误
+	posts.races = slugRetries
+	if _, err := svc.Create(ctx, author, PostInput{Title: "Hello", Content: "c"}); !errors.Is(err, ErrSlugConflict) {
+		t.Errorf("err = %v, want ErrSlugConflict", err)
+	}
+}
+
+func TestUpdateKeepsCategoryUnlessCleared(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	posts := repos.Posts.(*repository.MemoryPostRepository)
+	golang := &models.Category{Name: "Go", Slug: "go"}
+	posts.AddCategory(golang)
+	category := uint64(golang.ID)
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1}
+
+	post, err := svc.Create(ctx, author, PostInput{Title: "t", Content: "c", CategoryID: &category})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不传分类：保持不变
+	post, err = svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t2", Content: "c", Version: post.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.CategoryID == nil || *post.CategoryID != category {
+		t.Fatalf("category changed to %v without category_id", post.CategoryID)
+	}
+	// 传 0：清空
+	zero := uint64(0)
+	post, err = svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t2", Content: "c", CategoryID: &zero, Version: post.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.CategoryID != nil {
+		t.Fatalf("category = %v, want cleared", *post.CategoryID)
+	}
+	missing := uint64(999)
+	if _, err := svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t2", Content: "c", CategoryID: &missing, Version: post.Version}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("err = %v, want ErrCategoryNotFound", err)
+	}
+}
+
+func TestTagsCaseInsensitiveWithUniqueSlugs(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1}
+
+	first, err := svc.Create(ctx, author, PostInput{Title: "a", Content: "c", Tags: []string{"C++", "C#", "go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Create(ctx, author, PostInput{Title: "b", Content: "c", Tags: []string{"Go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Tags[0].Slug == first.Tags[1].Slug {
+		t.Errorf("C++ and C# share slug %q", first.Tags[0].Slug)
+	}
+	if second.Tags[0].ID != first.Tags[2].ID {
+		t.Errorf("Go created a new tag %+v instead of reusing %+v", second.Tags[0], first.Tags[2])
+	}
+}
+
+func TestChineseTitleAndTagSlugs(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	post, err := svc.Create(context.Background(), Actor{UserID: 1}, PostInput{Title: "你好世界", Content: "c", Tags: []string{"后端开发"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Slug != "ni-hao-shi-jie" || post.Tags[0].Slug != "hou-duan-kai-fa" {
+		t.Errorf("slugs = %q, %q; want pinyin", post.Slug, post.Tags[0].Slug)
+	}
+}
+
+func TestAuthorCannotOpenCommentModeration(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1, Permissions: []string{models.PermPostCreate}}
+	post, err := svc.Create(ctx, author, PostInput{Title: "t", Content: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uint64(post.ID)
+
+	if _, err := svc.SetCommentModeration(ctx, author, id, models.PostModerationOpen); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("author open err = %v, want ErrPermissionDenied", err)
+	}
+	// 收紧不需要额外权限
+	if mode, err := svc.SetCommentModeration(ctx, author, id, models.PostModerationHold); err != nil || mode != models.PostModerationHold {
+		t.Errorf("author hold = %q, %v", mode, err)
+	}
+	if _, err := svc.SetCommentModeration(ctx, author, id, "inherit"); err != nil {
+		t.Errorf("author inherit err = %v", err)
+	}
+	moderator := Actor{UserID: 2, Permissions: []string{models.PermPostEdit, models.PermCommentModerate}}
+	if _, err := svc.SetCommentModeration(ctx, moderator, id, models.PostModerationOpen); err != nil {
+		t.Errorf("moderator open err = %v", err)
+	}
+}
+
+func TestPostVisibility(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1, Permissions: []string{models.PermPostCreate}}
+	other := Actor{UserID: 2, Permissions: []string{models.PermPostCreate}}
+
+	draft, err := svc.Create(ctx, author, PostInput{Title: "draft", Content: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uint64(draft.ID)
+	for name, actor := range map[string]Actor{"anonymous": {}, "other": other} {
+		if _, err := svc.Get(ctx, actor, id); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("%s get draft err = %v, want ErrPostNotFound", name, err)
+		}
+		if _, err := svc.FindEditable(ctx, actor, id, ""); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("%s edit draft err = %v, want ErrPostNotFound", name, err)
+		}
+	}
+	for name, actor := range map[string]Actor{"author": author, "editor": editor} {
+		if _, err := svc.Get(ctx, actor, id); err != nil {
+			t.Errorf("%s get draft err = %v", name, err)
+		}
+	}
+	posts, _, err := svc.List(ctx, Actor{}, PostQuery{Pagination: utils.Pagination{Page: 1, PageSize: 10}})
+	if err != nil || len(posts) != 0 {
+		t.Errorf("anonymous list = %d posts, %v; want no drafts", len(posts), err)
+	}
+
+	if _, err := svc.Transition(ctx, editor, id, models.PostActionPublish); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Get(ctx, Actor{}, id); err != nil {
+		t.Errorf("anonymous get published err = %v", err)
+	}
+	posts, _, _ = svc.List(ctx, Actor{}, PostQuery{Pagination: utils.Pagination{Page: 1, PageSize: 10}})
+	if len(posts) != 1 {
+		t.Errorf("anonymous list = %d posts, want 1", len(posts))
+	}
+}
+
+func TestTransitionPermissions(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1, Permissions: []string{models.PermPostCreate}}
+	post, err := svc.Create(ctx, author, PostInput{Title: "t", Content: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uint64(post.ID)
+
+	// 没有 post:publish 的作者不能直接发布，只能提交审核和撤回
+	if _, err := svc.Transition(ctx, author, id, models.PostActionPublish); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("author publish err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := svc.Transition(ctx, author, id, models.PostActionArchive); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("author archive err = %v, want ErrPermissionDenied", err)
+	}
+	steps := []struct {
+		actor  Actor
+		action string
+		status string
+	}{
+		{author, models.PostActionSubmit, models.PostStatusPendingReview},
+		{author, models.PostActionUnpublish, models.PostStatusDraft},
+		{author, models.PostActionSubmit, models.PostStatusPendingReview},
+		{editor, models.PostActionPublish, models.PostStatusPublished},
+	}
+	for _, step := range steps {
+		post, err := svc.Transition(ctx, step.actor, id, step.action)
+		if err != nil {
+			t.Fatalf("%s: %v", step.action, err)
+		}
+		if post.Status != step.status {
+			t.Errorf("%s: status %s, want %s", step.action, post.Status, step.status)
+		}
+	}
+	// 已发布的文章作者不能撤回，编辑不能再提交审核
+	if _, err := svc.Transition(ctx, author, id, models.PostActionUnpublish); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("author unpublish published err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := svc.Transition(ctx, editor, id, models.PostActionSubmit); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("submit published err = %v, want ErrInvalidTransition", err)
+	}
+	if got, _ := repos.Posts.FindByID(ctx, id); got.PublishedAt == nil {
+		t.Error("published_at not set")
+	}
+}
+
+func TestPostUpdateVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1}
+	post, err := svc.Create(ctx, author, PostInput{Title: "t", Content: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := post.Version
+	if _, err := svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t", Content: "v2", Version: stale}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t", Content: "v2'", Version: stale})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("stale update err = %v, want *ConflictError", err)
+	}
+	if conflict.Current != stale+1 {
+		t.Errorf("current version = %d, want %d", conflict.Current, stale+1)
+	}
+}
+
+func TestRevisionsAndRestore(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1}
+
+	post, err := svc.Create(ctx, author, PostInput{Title: "v1", Content: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err = svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "v2", Content: "second", Version: post.Version})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := svc.Revisions(ctx, author, uint64(post.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Version != 2 || revisions[0].Content != "" {
+		t.Fatalf("revisions = %+v, want r2, r1 without content", revisions)
+	}
+	latest, err := svc.Revision(ctx, author, uint64(post.ID), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != 2 || latest.Content != "second" {
+		t.Errorf("latest = r%d %q, want r2", latest.Version, latest.Content)
+	}
+	if _, err := svc.Revision(ctx, author, uint64(post.ID), 9); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("Revision err = %v, want ErrRevisionNotFound", err)
+	}
+	// 别人的草稿看不到修订
+	if _, err := svc.Revisions(ctx, Actor{UserID: 2}, uint64(post.ID)); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("Revisions err = %v, want ErrPostNotFound", err)
+	}
+
+	restored, err := svc.Restore(ctx, author, uint64(post.ID), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Title != "v1" || restored.Content != "first" {
+		t.Errorf("restored = %q/%q, want v1/first", restored.Title, restored.Content)
+	}
+	if latest, _ = svc.Revision(ctx, author, uint64(post.ID), 0); latest.Version != 3 || latest.Note != "restore from r1" {
+		t.Errorf("latest = r%d %q, want r3 restore note", latest.Version, latest.Note)
+	}
+}
+
+func TestAuthorCannotEditPublishedPost(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewPostService(repos.Posts, repos.Comments, nil)
+	author := Actor{UserID: 1, Permissions: []string{models.PermPostCreate}}
+
+	post, err := svc.Create(ctx, author, PostInput{Title: "t", Content: "reviewed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 草稿可以随意修改
+	if post, err = svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t", Content: "draft edit", Version: post.Version}); err != nil {
+		t.Fatal(err)
+	}
+	if post, err = svc.Transition(ctx, editor, uint64(post.ID), models.PostActionPublish); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Update(ctx, author, PostInput{ID: uint64(post.ID), Title: "t", Content: "unreviewed", Version: post.Version}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Update err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := svc.Restore(ctx, author, uint64(post.ID), 1); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Restore err = %v, want ErrPermissionDenied", err)
+	}
+	got, err := svc.Get(ctx, Actor{}, uint64(post.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "draft edit" {
+		t.Errorf("public content = %q, want unchanged", got.Content)
+	}
+	// 有发布权限的编辑可以直接修改
+	if _, err := svc.Update(ctx, editor, PostInput{ID: uint64(post.ID), Title: "t", Content: "edited", Version: post.Version}); err != nil {
+		t.Errorf("editor Update err = %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/spam"
+	"github.com/gavin/blog/store"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrPasswordIncorrect   = errors.New("password incorrect")
+	ErrUsernameTaken       = errors.New("username is exist")
+	ErrEmailTaken          = errors.New("email is exist")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrInvalidPermission   = errors.New("invalid permission")
+
+	ErrPermissionDenied  = errors.New("permission denied")
+	ErrPostNotFound      = errors.New("post not found")
+	ErrPostNotPublished  = errors.New("post not published")
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrInvalidTransition = errors.New("invalid post status transition")
+	ErrStatusChanged     = errors.New("post status changed")
+	ErrPublishTimePassed = errors.New("publish time must be in the future")
+	ErrSlugConflict      = errors.New("slug conflict")
+	ErrRevisionNotFound  = errors.New("revision not found")
+
+	ErrTagNotFound            = errors.New("tag not found")
+	ErrTagExists              = errors.New("tag is exist")
+	ErrCategoryExists         = errors.New("category is exist")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("category cannot be its own ancestor")
+
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrCommentNotApproved = errors.New("comment not approved")
+	ErrReplyTooDeep       = errors.New("reply depth limit reached")
+
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// ConflictError 文章或评论已被他人修改，Current 为服务端当前版本
+type ConflictError struct {
+	ID      uint
+	Current int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: id %d, current version %d", e.ID, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// Actor 发起操作的用户，匿名访问时 UserID 为 0
+type Actor struct {
+	UserID      uint64
+	Username    string
+	Permissions []string
+}
+
+// IsAnonymous 是否为匿名访问
+func (a Actor) IsAnonymous() bool {
+	return a.UserID == 0
+}
+
+// Can 是否拥有某个权限
+func (a Actor) Can(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Indexer 文章、评论变化后同步搜索索引，ctx 为发起写操作的请求 context
+type Indexer interface {
+	SyncPost(ctx context.Context, postID uint)
+	SyncComment(ctx context.Context, commentID uint)
+}
+
+// NopIndexer 不同步索引，用于命令行和测试
+type NopIndexer struct{}
+
+func (NopIndexer) SyncPost(ctx context.Context, postID uint)       {}
+func (NopIndexer) SyncComment(ctx context.Context, commentID uint) {}
+
+// Services 业务服务，在 main 中构造后注入到 HTTP 处理器、命令行和后台任务
+type Services struct {
+	Users    UserService
+	Posts    PostService
+	Comments CommentService
+	Taxonomy TaxonomyService
+}
+
+// New 基于同一组仓储构造所有服务，checker 为空时不做垃圾评论检测
+func New(repos *repository.Repositories, revocations store.RevocationStore, checker spam.Checker, indexer Indexer) *Services {
+	return &Services{
+		Users:    NewUserService(repos.Users, repos.Tokens, revocations),
+		Posts:    NewPostService(repos.Posts, repos.Comments, indexer),
+		Comments: NewCommentService(repos.Comments, repos.Posts, checker, indexer),
+		Taxonomy: NewTaxonomyService(repos.Taxonomy, indexer),
+	}
+}
+
+// postVisibility 文章的可见范围：已发布的文章 + 自己的文章（拥有 post:edit 权限时不限制）
+func postVisibility(actor Actor) repository.Visibility {
+	return repository.Visibility{All: actor.Can(models.PermPostEdit), UserID: actor.UserID}
+}
+
+// commentVisibility 评论的可见范围：已通过审核的评论 + 自己的评论（审核员不限制）
+func commentVisibility(actor Actor) repository.Visibility {
+	return repository.Visibility{All: actor.Can(models.PermCommentModerate), UserID: actor.UserID}
+}
+
+// canViewPost 已发布的文章所有人可见，其他状态只有作者和拥有 post:edit 权限的用户可见
+func canViewPost(actor Actor, post *models.Post) bool {
+	return post.IsPublished() || actor.Can(models.PermPostEdit) ||
+		(!actor.IsAnonymous() && actor.UserID == post.UserID)
+}
+
+// canViewComment 已通过审核的评论和占位评论所有人可见，其他状态只有评论者和审核员可见
+func canViewComment(actor Actor, comment *models.Comment) bool {
+	return comment.IsListed() || actor.Can(models.PermCommentModerate) ||
+		(!actor.IsAnonymous() && actor.UserID == comment.UserID)
+}
+
+// notFoundAs 把仓储的记录不存在错误替换为业务错误
+func notFoundAs(err error, target error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return target
+	}
+	return err
+}
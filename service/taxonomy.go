@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+)
+
+// CategoryInput 创建、修改分类的参数，ParentID 为空时修改保持原上级、创建为顶级分类，为 0 时改为顶级分类
+type CategoryInput struct {
+	ID       uint64
+	Name     string
+	ParentID *uint64
+}
+
+type TaxonomyService interface {
+	// ListTags 标签列表，附带每个标签下已发布的文章数
+	ListTags(ctx context.Context) ([]models.TagWithCount, error)
+	CreateTag(ctx context.Context, actor Actor, name string) (*models.Tag, error)
+	UpdateTag(ctx context.Context, actor Actor, id uint64, name string) (*models.Tag, error)
+	// DeleteTag 删除标签，同时解除与文章的关联
+	DeleteTag(ctx context.Context, actor Actor, id uint64) error
+
+	// ListCategories 平铺的分类列表，按 ID 升序
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	// CategoryDescendantIDs 分类自身及所有子孙分类的 ID，分类不存在返回 ErrCategoryNotFound
+	CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error)
+	CreateCategory(ctx context.Context, actor Actor, input CategoryInput) (*models.Category, error)
+	// UpdateCategory 修改分类，不能把分类挂到自己或自己的子孙分类下
+	UpdateCategory(ctx context.Context, actor Actor, input CategoryInput) (*models.Category, error)
+	// DeleteCategory 删除分类：子分类上移到被删除分类的上级，文章的分类置空
+	DeleteCategory(ctx context.Context, actor Actor, id uint64) error
+}
+
+type taxonomyService struct {
+	taxonomy repository.TaxonomyRepository
+	indexer  Indexer
+}
+
+func NewTaxonomyService(taxonomy repository.TaxonomyRepository, indexer Indexer) TaxonomyService {
+	if indexer == nil {
+		indexer = NopIndexer{}
+	}
+	return &taxonomyService{taxonomy: taxonomy, indexer: indexer}
+}
+
+func (s *taxonomyService) ListTags(ctx context.Context) ([]models.TagWithCount, error) {
+	return s.taxonomy.Tags(ctx)
+}
+
+func (s *taxonomyService) CreateTag(ctx context.Context, actor Actor, name string) (*models.Tag, error) {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return nil, ErrPermissionDenied
+	}
+	tag, err := s.taxonomy.CreateTag(ctx, strings.TrimSpace(name))
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, ErrTagExists
+	}
+	return tag, err
+}
+
+func (s *taxonomyService) UpdateTag(ctx context.Context, actor Actor, id uint64, name string) (*models.Tag, error) {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return nil, ErrPermissionDenied
+	}
+	tag, err := s.taxonomy.FindTag(ctx, id)
+	if err != nil {
+		return nil, notFoundAs(err, ErrTagNotFound)
+	}
+	if err := s.taxonomy.RenameTag(ctx, tag, strings.TrimSpace(name)); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	// 标签名变化后重新索引使用该标签的文章
+	postIds, err := s.taxonomy.TagPostIDs(ctx, tag.ID)
+	if err != nil {
+		logger.Log.WithContext(ctx).Errorf("tag posts err: %v", err)
+	}
+	s.syncPosts(ctx, postIds)
+	return tag, nil
+}
+
+func (s *taxonomyService) DeleteTag(ctx context.Context, actor Actor, id uint64) error {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return ErrPermissionDenied
+	}
+	tag, err := s.taxonomy.FindTag(ctx, id)
+	if err != nil {
+		return notFoundAs(err, ErrTagNotFound)
+	}
+	postIds, err := s.taxonomy.DeleteTag(ctx, tag.ID)
+	if err != nil {
+		return err
+	}
+	s.syncPosts(ctx, postIds)
+	return nil
+}
+
+func (s *taxonomyService) syncPosts(ctx context.Context, postIds []uint) {
+	for _, postId := range postIds {
+		s.indexer.SyncPost(ctx, postId)
+	}
+}
+
+func (s *taxonomyService) ListCategories(ctx context.Context) ([]models.Category, error) {
+	return s.taxonomy.Categories(ctx)
+}
+
+func (s *taxonomyService) CategoryDescendantIDs(ctx context.Context, id uint64) ([]uint64, error) {
+	if _, err := s.taxonomy.FindCategory(ctx, id); err != nil {
+		return nil, notFoundAs(err, ErrCategoryNotFound)
+	}
+	return s.taxonomy.CategoryDescendantIDs(ctx, id)
+}
+
+func (s *taxonomyService) CreateCategory(ctx context.Context, actor Actor, input CategoryInput) (*models.Category, error) {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return nil, ErrPermissionDenied
+	}
+	parentID := categoryID(input.ParentID)
+	if parentID != nil {
+		if _, err := s.taxonomy.FindCategory(ctx, *parentID); err != nil {
+			return nil, notFoundAs(err, ErrParentCategoryNotFound)
+		}
+	}
+	name := strings.TrimSpace(input.Name)
+	slug, err := s.categorySlug(ctx, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	category := &models.Category{Name: name, Slug: slug, ParentID: parentID}
+	if err := s.taxonomy.CreateCategory(ctx, category); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *taxonomyService) UpdateCategory(ctx context.Context, actor Actor, input CategoryInput) (*models.Category, error) {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return nil, ErrPermissionDenied
+	}
+	category, err := s.taxonomy.FindCategory(ctx, input.ID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrCategoryNotFound)
+	}
+
+	// 不传 parent_id 保持原上级，传 0 改为顶级分类
+	if parentID := categoryID(input.ParentID); parentID != nil {
+		descendants, err := s.taxonomy.CategoryDescendantIDs(ctx, uint64(category.ID))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range descendants {
+			if id == *parentID {
+				return nil, ErrCategoryCycle
+			}
+		}
+		if _, err := s.taxonomy.FindCategory(ctx, *parentID); err != nil {
+			return nil, notFoundAs(err, ErrParentCategoryNotFound)
+		}
+	}
+
+	name := strings.TrimSpace(input.Name)
+	slug, err := s.categorySlug(ctx, name, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	category.Name = name
+	category.Slug = slug
+	if input.ParentID != nil {
+		category.ParentID = categoryID(input.ParentID)
+	}
+	if err := s.taxonomy.SaveCategory(ctx, category); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *taxonomyService) DeleteCategory(ctx context.Context, actor Actor, id uint64) error {
+	if !actor.Can(models.PermTaxonomyManage) {
+		return ErrPermissionDenied
+	}
+	category, err := s.taxonomy.FindCategory(ctx, id)
+	if err != nil {
+		return notFoundAs(err, ErrCategoryNotFound)
+	}
+	return s.taxonomy.DeleteCategory(ctx, category)
+}
+
+// categorySlug 生成分类 slug，已被其他分类占用时返回 ErrCategoryExists
+func (s *taxonomyService) categorySlug(ctx context.Context, name string, categoryID uint) (string, error) {
+	slug := models.Slugify(name)
+	taken, err := s.taxonomy.CategorySlugTaken(ctx, slug, categoryID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrCategoryExists
+	}
+	return slug, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+)
+
+// recordingIndexer 记录需要重新索引的文章和评论
+type recordingIndexer struct {
+	posts    []uint
+	comments []uint
+}
+
+func (i *recordingIndexer) SyncPost(ctx context.Context, postID uint) {
+	i.posts = append(i.posts, postID)
+}
+
+func (i *recordingIndexer) SyncComment(ctx context.Context, commentID uint) {
+	i.comments = append(i.comments, commentID)
+}
+
+var taxonomyManager = Actor{UserID: 100, Permissions: []string{models.PermTaxonomyManage}}
+
+func TestTaxonomyRequiresPermission(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewTaxonomyService(repos.Taxonomy, nil)
+	author := Actor{UserID: 1, Permissions: []string{models.PermPostCreate, models.PermCommentCreate}}
+
+	if _, err := svc.CreateTag(ctx, author, "go"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("CreateTag err = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := svc.CreateCategory(ctx, author, CategoryInput{Name: "Go"}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("CreateCategory err = %v, want ErrPermissionDenied", err)
+	}
+	if err := svc.DeleteCategory(ctx, author, 1); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("DeleteCategory err = %v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestTagRenameAndDeleteUpdatePosts(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	indexer := &recordingIndexer{}
+	svc := NewTaxonomyService(repos.Taxonomy, indexer)
+	posts := NewPostService(repos.Posts, repos.Comments, nil)
+
+	post, err := posts.Create(ctx, editor, PostInput{Title: "t", Content: "c", Tags: []string{"golang", "web"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := posts.Transition(ctx, editor, uint64(post.ID), models.PostActionPublish); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateTag(ctx, taxonomyManager, "GOLANG"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("CreateTag err = %v, want ErrTagExists", err)
+	}
+
+	golang := post.Tags[0]
+	// 只改大小写保留原 slug
+	renamed, err := svc.UpdateTag(ctx, taxonomyManager, uint64(golang.ID), " GoLang ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "GoLang" || renamed.Slug != golang.Slug {
+		t.Errorf("renamed = %q/%q, want GoLang/%q", renamed.Name, renamed.Slug, golang.Slug)
+	}
+	if _, err := svc.UpdateTag(ctx, taxonomyManager, uint64(golang.ID), "Web"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("UpdateTag err = %v, want ErrTagExists", err)
+	}
+	got, err := posts.Get(ctx, editor, uint64(post.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tags[0].Name != "GoLang" {
+		t.Errorf("post tag = %q, want renamed", got.Tags[0].Name)
+	}
+
+	tags, err := svc.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].PostCount != 1 {
+		t.Errorf("tags = %+v, want 2 tags with 1 post each", tags)
+	}
+
+	indexer.posts = nil
+	if err := svc.DeleteTag(ctx, taxonomyManager, uint64(golang.ID)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indexer.posts, []uint{post.ID}) {
+		t.Errorf("synced posts = %v, want [%d]", indexer.posts, post.ID)
+	}
+	if got, _ = posts.Get(ctx, editor, uint64(post.ID)); len(got.Tags) != 1 || got.Tags[0].Name != "web" {
+		t.Errorf("post tags = %+v, want only web", got.Tags)
+	}
+	if err := svc.DeleteTag(ctx, taxonomyManager, uint64(golang.ID)); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("DeleteTag err = %v, want ErrTagNotFound", err)
+	}
+}
+
+func TestUpdateCategoryParent(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewTaxonomyService(repos.Taxonomy, nil)
+
+	zero := uint64(0)
+	root, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "后端", ParentID: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.ParentID != nil || root.Slug != "hou-duan" {
+		t.Errorf("root = %+v, want top level with pinyin slug", root)
+	}
+	rootID := uint64(root.ID)
+	child, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "Go", ParentID: &rootID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "go"}); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("CreateCategory err = %v, want ErrCategoryExists", err)
+	}
+	missing := uint64(999)
+	if _, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "Rust", ParentID: &missing}); !errors.Is(err, ErrParentCategoryNotFound) {
+		t.Errorf("CreateCategory err = %v, want ErrParentCategoryNotFound", err)
+	}
+
+	// 不传 parent_id：保持原上级
+	child, err = svc.UpdateCategory(ctx, taxonomyManager, CategoryInput{ID: uint64(child.ID), Name: "Golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.ParentID == nil || *child.ParentID != rootID {
+		t.Errorf("parent = %v, want %d", child.ParentID, rootID)
+	}
+	// 不能挂到自己的子分类下
+	childID := uint64(child.ID)
+	if _, err := svc.UpdateCategory(ctx, taxonomyManager, CategoryInput{ID: rootID, Name: "后端", ParentID: &childID}); !errors.Is(err, ErrCategoryCycle) {
+		t.Errorf("UpdateCategory err = %v, want ErrCategoryCycle", err)
+	}
+	// 传 0：改为顶级分类
+	child, err = svc.UpdateCategory(ctx, taxonomyManager, CategoryInput{ID: childID, Name: "Golang", ParentID: &zero})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.ParentID != nil {
+		t.Errorf("parent = %d, want cleared", *child.ParentID)
+	}
+}
+
+func TestDeleteCategoryMovesChildrenUp(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemoryRepositories()
+	svc := NewTaxonomyService(repos.Taxonomy, nil)
+	posts := NewPostService(repos.Posts, repos.Comments, nil)
+
+	root, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootID := uint64(root.ID)
+	middle, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "middle", ParentID: &rootID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleID := uint64(middle.ID)
+	leaf, err := svc.CreateCategory(ctx, taxonomyManager, CategoryInput{Name: "leaf", ParentID: &middleID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := posts.Create(ctx, editor, PostInput{Title: "t", Content: "c", CategoryID: &middleID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.DeleteCategory(ctx, taxonomyManager, middleID); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := svc.ListCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories[1].ID != leaf.ID || categories[1].ParentID == nil || *categories[1].ParentID != rootID {
+		t.Errorf("categories = %+v, want leaf moved under root", categories)
+	}
+	if got, _ := posts.Get(ctx, editor, uint64(post.ID)); got.CategoryID != nil {
+		t.Errorf("post category = %d, want cleared", *got.CategoryID)
+	}
+}
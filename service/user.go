@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavin/blog/logger"
+	"github.com/gavin/blog/metrics"
+	"github.com/gavin/blog/models"
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/store"
+	"github.com/gavin/blog/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Tokens 签发给客户端的访问令牌和刷新令牌
+type Tokens struct {
+	Username              string
+	Token                 string
+	TokenExpiresAt        time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type RegisterInput struct {
+	Username string
+	Email    string
+	Password string
+}
+
+// UserService 注册登录、令牌轮换和会话撤销
+type UserService interface {
+	Register(ctx context.Context, input RegisterInput) (*Tokens, error)
+	Login(ctx context.Context, username, password string) (*Tokens, error)
+	// Refresh 用刷新令牌换取新的访问令牌，同时轮换刷新令牌
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	// Logout 撤销当前访问令牌（jti），以及 refreshToken 所在的令牌家族
+	Logout(ctx context.Context, userID uint64, jti string, expiresAt time.Time, refreshToken string) error
+	// LogoutAll 撤销用户此前签发的全部访问令牌和刷新令牌
+	LogoutAll(ctx context.Context, userID uint64) error
+	// UpdateRole 修改用户角色和额外权限，并撤销现有会话使新权限立即生效
+	UpdateRole(ctx context.Context, userID uint64, role string, permissions []string) error
+	// Actor 按用户当前的角色和权限构造 Actor，后台任务代用户操作时使用；用户不存在返回 ErrUserNotFound
+	Actor(ctx context.Context, userID uint64) (Actor, error)
+}
+
+type userService struct {
+	users       repository.UserRepository
+	tokens      repository.RefreshTokenRepository
+	revocations store.RevocationStore
+}
+
+func NewUserService(users repository.UserRepository, tokens repository.RefreshTokenRepository, revocations store.RevocationStore) UserService {
+	return &userService{users: users, tokens: tokens, revocations: revocations}
+}
+
+func (s *userService) Register(ctx context.Context, input RegisterInput) (*Tokens, error) {
+	if _, err := s.users.FindByUsername(ctx, input.Username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if _, err := s.users.FindByEmail(ctx, input.Email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username: input.Username,
+		Email:    input.Email,
+		Password: string(hashedPassword),
+		Role:     models.DefaultRole,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	metrics.Registrations.Inc()
+	return s.issueTokens(ctx, user)
+}
+
+func (s *userService) Login(ctx context.Context, username, password string) (*Tokens, error) {
+	user, err := s.users.FindByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			metrics.Logins.WithLabelValues("failure").Inc()
+		}
+		return nil, notFoundAs(err, ErrUserNotFound)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		return nil, ErrPasswordIncorrect
+	}
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Logins.WithLabelValues("success").Inc()
+	return tokens, nil
+}
+
+func (s *userService) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	existToken, err := s.tokens.FindByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, notFoundAs(err, ErrInvalidRefreshToken)
+	}
+
+	// 已撤销的令牌再次出现，说明令牌可能已泄露，撤销整个家族
+	if existToken.RevokedAt != nil {
+		s.revokeFamily(ctx, existToken.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(existToken.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	user, err := s.users.FindByID(ctx, existToken.UserID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrUserNotFound)
+	}
+	next, tokens, err := newTokens(user, existToken.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokens.Rotate(ctx, existToken.ID, next)
+	// 并发刷新时旧令牌已被另一个请求撤销
+	if errors.Is(err, repository.ErrConflict) {
+		s.revokeFamily(ctx, existToken.FamilyID)
+		return nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (s *userService) Logout(ctx context.Context, userID uint64, jti string, expiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.revocations.RevokeToken(jti, expiresAt); err != nil {
+			return err
+		}
+	}
+	if refreshToken != "" {
+		existToken, err := s.tokens.FindByHash(ctx, utils.HashToken(refreshToken))
+		if err == nil && existToken.UserID == userID {
+			s.revokeFamily(ctx, existToken.FamilyID)
+		}
+	}
+	return nil
+}
+
+func (s *userService) LogoutAll(ctx context.Context, userID uint64) error {
+	now := time.Now()
+	if err := s.revocations.RevokeUserTokens(userID, now); err != nil {
+		return err
+	}
+	return s.tokens.RevokeUser(ctx, userID, now)
+}
+
+func (s *userService) UpdateRole(ctx context.Context, userID uint64, role string, permissions []string) error {
+	if !models.IsValidRole(role) {
+		return fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+	extra := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if !models.IsValidPermission(p) {
+			return fmt.Errorf("%w: %s", ErrInvalidPermission, p)
+		}
+		extra = append(extra, p)
+	}
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return notFoundAs(err, ErrUserNotFound)
+	}
+	if err := s.users.UpdateRole(ctx, userID, role, strings.Join(extra, ",")); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		logger.Log.WithContext(ctx).Errorf("revoke user tokens err: %v", err)
+	}
+	return nil
+}
+
+// issueTokens 签发令牌并开启新的令牌家族
+func (s *userService) issueTokens(ctx context.Context, user *models.User) (*Tokens, error) {
+	refresh, tokens, err := newTokens(user, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.Create(ctx, refresh); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// revokeFamily 撤销同一家族下所有未撤销的刷新令牌，失败只记录日志
+func (s *userService) revokeFamily(ctx context.Context, familyID string) {
+	if err := s.tokens.RevokeFamily(ctx, familyID); err != nil {
+		logger.Log.WithContext(ctx).Errorf("revoke token family err: %v", err)
+	}
+}
+
+// newTokens 生成访问令牌和待保存的刷新令牌，familyID 为空时开启新的令牌家族
+func newTokens(user *models.User, familyID string) (*models.RefreshToken, *Tokens, error) {
+	token, tokenExpiresAt, err := utils.GenerateToken(uint64(user.ID), user.Username, user.Role, user.PermissionList())
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	if familyID == "" {
+		if familyID, err = utils.NewTokenFamily(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	refreshExpiresAt := time.Now().Add(utils.RefreshTokenExpire)
+	refresh := &models.RefreshToken{
+		UserID:    uint64(user.ID),
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: refreshExpiresAt,
+	}
+	return refresh, &Tokens{
+		Username:              user.Username,
+		Token:                 token,
+		TokenExpiresAt:        tokenExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (s *userService) Actor(ctx context.Context, userID uint64) (Actor, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return Actor{}, notFoundAs(err, ErrUserNotFound)
+	}
+	return Actor{UserID: uint64(user.ID), Username: user.Username, Permissions: user.PermissionList()}, nil
+}
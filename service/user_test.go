@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gavin/blog/repository"
+	"github.com/gavin/blog/utils"
+)
+
+// memoryRevocations 内存中的访问令牌撤销记录
+type memoryRevocations struct {
+	tokens map[string]time.Time
+	users  map[uint64]time.Time
+}
+
+func newMemoryRevocations() *memoryRevocations {
+	return &memoryRevocations{tokens: make(map[string]time.Time), users: make(map[uint64]time.Time)}
+}
+
+func (s *memoryRevocations) RevokeToken(jti string, expiresAt time.Time) error {
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *memoryRevocations) IsTokenRevoked(jti string) (bool, error) {
+	_, ok := s.tokens[jti]
+	return ok, nil
+}
+
+func (s *memoryRevocations) RevokeUserTokens(userID uint64, before time.Time) error {
+	s.users[userID] = before
+	return nil
+}
+
+func (s *memoryRevocations) UserTokensRevokedAt(userID uint64) (time.Time, error) {
+	return s.users[userID], nil
+}
+
+func newUserService(t *testing.T) (UserService, *memoryRevocations, *Tokens) {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	revocations := newMemoryRevocations()
+	svc := NewUserService(repos.Users, repos.Tokens, revocations)
+	tokens, err := svc.Register(context.Background(), RegisterInput{Username: "alice", Email: "alice@example.com", Password: "secret1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc, revocations, tokens
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	svc, _, first := newUserService(t)
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.Token == "" {
+		t.Fatalf("refresh returned %+v, want a new token pair", second)
+	}
+	// 新令牌可以继续轮换
+	if _, err := svc.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("refresh with rotated token: %v", err)
+	}
+	if _, err := svc.Refresh(ctx, "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	svc, _, first := newUserService(t)
+	other, err := svc.Login(ctx, "alice", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧令牌被重放：视为泄露，同一家族的令牌全部失效
+	if _, err := svc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replay err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := svc.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("latest token in family err = %v, want ErrRefreshTokenReused", err)
+	}
+	// 其他会话（另一个家族）不受影响
+	if _, err := svc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("other family err = %v", err)
+	}
+}
+
+func TestLogoutAllRevokesOutstandingTokens(t *testing.T) {
+	ctx := context.Background()
+	svc, revocations, first := newUserService(t)
+	other, err := svc.Login(ctx, "alice", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.ParseToken(first.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.LogoutAll(ctx, claims.UserID); err != nil {
+		t.Fatal(err)
+	}
+	for _, tokens := range []*Tokens{first, other} {
+		if _, err := svc.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Errorf("refresh after logout-all err = %v, want ErrRefreshTokenReused", err)
+		}
+	}
+	// 之前签发的访问令牌按用户级撤销时间失效
+	if revokedAt := revocations.users[claims.UserID]; revokedAt.Before(claims.IssuedAt.Time) {
+		t.Errorf("user tokens revoked at %v, before access token issued at %v", revokedAt, claims.IssuedAt.Time)
+	}
+
+	// 重新登录后的会话正常
+	again, err := svc.Login(ctx, "alice", "secret1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Refresh(ctx, again.RefreshToken); err != nil {
+		t.Errorf("refresh after re-login err = %v", err)
+	}
+}
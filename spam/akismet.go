@@ -0,0 +1,74 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AkismetClient Akismet comment-check 接口客户端
+// Endpoint 可以是官方地址（https://<key>.rest.akismet.com）或本地兼容服务
+type AkismetClient struct {
+	Endpoint   string
+	Key        string
+	Blog       string
+	HTTPClient *http.Client
+}
+
+func NewAkismetClient(endpoint, key, blog string) *AkismetClient {
+	return &AkismetClient{
+		Endpoint:   strings.TrimRight(endpoint, "/"),
+		Key:        key,
+		Blog:       blog,
+		HTTPClient: &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+func (a *AkismetClient) Check(ctx context.Context, in *Input) (Result, error) {
+	var r Result
+	form := url.Values{
+		"api_key":         {a.Key},
+		"blog":            {a.Blog},
+		"user_ip":         {in.IP},
+		"user_agent":      {in.UserAgent},
+		"referrer":        {in.Referrer},
+		"permalink":       {in.Permalink},
+		"comment_type":    {"comment"},
+		"comment_author":  {in.Author},
+		"comment_content": {in.Content},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Endpoint+"/1.1/comment-check", strings.NewReader(form.Encode()))
+	if err != nil {
+		return r, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return r, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return r, err
+	}
+
+	switch strings.TrimSpace(string(body)) {
+	case "true":
+		// discard 表示明显的垃圾评论
+		if resp.Header.Get("X-akismet-pro-tip") == "discard" {
+			r.Add(SpamScore*2, "akismet: discard")
+		} else {
+			r.Add(SpamScore, "akismet: spam")
+		}
+	case "false":
+	default:
+		return r, fmt.Errorf("akismet: unexpected response %d %q, debug: %s",
+			resp.StatusCode, body, resp.Header.Get("X-akismet-debug-help"))
+	}
+	return r, nil
+}
@@ -0,0 +1,189 @@
+package spam
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+)
+
+// HoneypotChecker 蜜罐字段被填写时直接判定为机器人
+type HoneypotChecker struct{}
+
+func (HoneypotChecker) Check(_ context.Context, in *Input) (Result, error) {
+	var r Result
+	if strings.TrimSpace(in.Honeypot) != "" {
+		r.Add(SpamScore, "honeypot field filled")
+	}
+	return r, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// LinkChecker 链接数超过 MaxLinks 时加分，每多一个链接加 0.25
+type LinkChecker struct {
+	MaxLinks int
+}
+
+func (l LinkChecker) Check(_ context.Context, in *Input) (Result, error) {
+	var r Result
+	links := len(linkPattern.FindAllStringIndex(in.Content, -1))
+	if links > l.MaxLinks {
+		r.Add(0.25*float64(links-l.MaxLinks)+0.25, fmt.Sprintf("too many links: %d", links))
+	}
+	return r, nil
+}
+
+// BannedWordChecker 命中违禁词（忽略大小写）
+type BannedWordChecker struct {
+	words []string
+}
+
+func NewBannedWordChecker(words []string) *BannedWordChecker {
+	lower := make([]string, 0, len(words))
+	for _, w := range words {
+		lower = append(lower, strings.ToLower(w))
+	}
+	return &BannedWordChecker{words: lower}
+}
+
+func (b *BannedWordChecker) Check(_ context.Context, in *Input) (Result, error) {
+	var r Result
+	content := strings.ToLower(in.Content)
+	for _, w := range b.words {
+		if strings.Contains(content, w) {
+			r.Add(SpamScore, "banned word: "+w)
+		}
+	}
+	return r, nil
+}
+
+// DuplicateChecker Window 时间内出现完全相同的评论内容（按 content_hash 索引查询）
+// 同一用户重复发送判为垃圾评论，不同用户发送相同内容转人工审核；修改评论时不与评论自身比较
+type DuplicateChecker struct {
+	DB     *gorm.DB
+	Window time.Duration
+}
+
+func (d DuplicateChecker) Check(_ context.Context, in *Input) (Result, error) {
+	var r Result
+	content := strings.TrimSpace(in.Content)
+	if content == "" {
+		return r, nil
+	}
+	var rows []struct {
+		UserID uint64
+	}
+	query := d.DB.Model(&models.Comment{}).Select("user_id").
+		Where("content_hash = ? AND created_at > ?", models.CommentContentHash(content), time.Now().Add(-d.Window))
+	if in.CommentID > 0 {
+		query = query.Where("id <> ?", in.CommentID)
+	}
+	err := query.Limit(10).Scan(&rows).Error
+	if err != nil {
+		return r, err
+	}
+	for _, row := range rows {
+		if row.UserID == in.UserID {
+			r.Add(SpamScore, "duplicate content from same user")
+			return r, nil
+		}
+	}
+	if len(rows) > 0 {
+		r.Add(HoldScore, fmt.Sprintf("duplicate content from %d other comments", len(rows)))
+	}
+	return r, nil
+}
+
+// VelocityChecker 每个用户、每个 IP 在 Window 内最多评论 Limit 次，超出部分加分
+// Check 只统计不记录，评论保存成功后由 Record 记录；修改评论不计入次数；计数保存在内存中，多实例部署时各实例分别计数
+type VelocityChecker struct {
+	Limit  int
+	Window time.Duration
+
+	mu     sync.Mutex
+	events map[string][]time.Time
+	// 上次清理过期 key 的时间
+	swept time.Time
+}
+
+func NewVelocityChecker(limit int, window time.Duration) *VelocityChecker {
+	return &VelocityChecker{Limit: limit, Window: window, events: make(map[string][]time.Time)}
+}
+
+func (v *VelocityChecker) Check(_ context.Context, in *Input) (Result, error) {
+	var r Result
+	if v.Limit <= 0 || in.CommentID > 0 {
+		return r, nil
+	}
+	now := time.Now()
+	for _, key := range velocityKeys(in) {
+		// 加上本次评论
+		if count := v.count(key, now) + 1; count > v.Limit {
+			r.Add(SpamScore, fmt.Sprintf("posting too fast: %s, %d in %s", key, count, v.Window))
+		}
+	}
+	return r, nil
+}
+
+// Record 评论保存成功后记录一次
+func (v *VelocityChecker) Record(_ context.Context, in *Input) {
+	if v.Limit <= 0 {
+		return
+	}
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, key := range velocityKeys(in) {
+		v.events[key] = append(v.prune(key, now), now)
+	}
+	v.sweep(now)
+}
+
+func velocityKeys(in *Input) []string {
+	keys := []string{fmt.Sprintf("user:%d", in.UserID)}
+	if in.IP != "" {
+		keys = append(keys, "ip:"+in.IP)
+	}
+	return keys
+}
+
+// count 窗口内的次数
+func (v *VelocityChecker) count(key string, now time.Time) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return len(v.prune(key, now))
+}
+
+// prune 去掉 key 的过期记录，没有剩余记录时删除 key，调用方需持有锁
+func (v *VelocityChecker) prune(key string, now time.Time) []time.Time {
+	cutoff := now.Add(-v.Window)
+	events := v.events[key]
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+	if len(events) == 0 {
+		delete(v.events, key)
+		return nil
+	}
+	v.events[key] = events
+	return events
+}
+
+// sweep 每个窗口清理一次所有 key，不再评论的用户和 IP 不会一直占用内存，调用方需持有锁
+func (v *VelocityChecker) sweep(now time.Time) {
+	if now.Sub(v.swept) < v.Window {
+		return
+	}
+	v.swept = now
+	for key := range v.events {
+		v.prune(key, now)
+	}
+}
@@ -0,0 +1,92 @@
+package spam
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVelocityCountsOnlyRecordedComments(t *testing.T) {
+	ctx := context.Background()
+	v := NewVelocityChecker(2, time.Minute)
+	in := &Input{UserID: 1, IP: "10.0.0.1"}
+
+	// 只检测不保存（例如写库失败）不占用次数
+	for i := 0; i < 5; i++ {
+		if r, _ := v.Check(ctx, in); r.Score != 0 {
+			t.Fatalf("check %d without record: score %v", i, r.Score)
+		}
+	}
+	v.Record(ctx, in)
+	v.Record(ctx, in)
+	r, _ := v.Check(ctx, in)
+	if !r.IsSpam() || len(r.Reasons) != 2 {
+		t.Errorf("third comment in window: %+v, want spam for user and ip", r)
+	}
+	// 修改已有评论不算新评论
+	if r, _ := v.Check(ctx, &Input{CommentID: 7, UserID: 1, IP: "10.0.0.1"}); r.Score != 0 {
+		t.Errorf("edit counted against rate limit: %+v", r)
+	}
+}
+
+func TestVelocityEvictsIdleKeys(t *testing.T) {
+	ctx := context.Background()
+	v := NewVelocityChecker(1, time.Minute)
+	for i := uint64(1); i <= 100; i++ {
+		v.Record(ctx, &Input{UserID: i})
+	}
+	// 模拟一个窗口之后：过期的 key 全部被清理
+	past := time.Now().Add(-2 * time.Minute)
+	for key := range v.events {
+		v.events[key] = []time.Time{past}
+	}
+	v.swept = past
+	v.Record(ctx, &Input{UserID: 1000})
+	if len(v.events) != 1 {
+		t.Errorf("keys after sweep = %d, want 1", len(v.events))
+	}
+
+	// 窗口内只检测时也会删除已经没有记录的 key
+	v.events["user:1000"] = []time.Time{past}
+	v.Check(ctx, &Input{UserID: 1000})
+	if _, ok := v.events["user:1000"]; ok {
+		t.Error("expired key kept after check")
+	}
+}
+
+func TestHeuristicCheckers(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name    string
+		checker Checker
+		in      Input
+		want    float64
+	}{
+		{"honeypot", HoneypotChecker{}, Input{Honeypot: "x"}, SpamScore},
+		{"honeypot empty", HoneypotChecker{}, Input{Honeypot: " "}, 0},
+		{"links under limit", LinkChecker{MaxLinks: 2}, Input{Content: "http://a http://b"}, 0},
+		{"links over limit", LinkChecker{MaxLinks: 1}, Input{Content: "http://a www.b https://c"}, 0.75},
+		{"banned word", NewBannedWordChecker([]string{"Casino"}), Input{Content: "best CASINO here"}, SpamScore},
+		{"clean", NewBannedWordChecker([]string{"casino"}), Input{Content: "hello"}, 0},
+	}
+	for _, c := range cases {
+		r, err := c.checker.Check(ctx, &c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Score != c.want {
+			t.Errorf("%s: score %v, want %v (%v)", c.name, r.Score, c.want, r.Reasons)
+		}
+	}
+}
+
+func TestChainRecordsRecorders(t *testing.T) {
+	ctx := context.Background()
+	v := NewVelocityChecker(1, time.Minute)
+	chain := Chain{HoneypotChecker{}, v}
+	in := &Input{UserID: 1}
+	chain.Record(ctx, in)
+	if r, _ := chain.Check(ctx, in); !r.IsSpam() {
+		t.Errorf("second comment after Chain.Record: %+v", r)
+	}
+}
@@ -0,0 +1,108 @@
+package spam
+
+import (
+	"context"
+	"time"
+
+	"github.com/gavin/blog/config"
+	"github.com/gavin/blog/logger"
+	"gorm.io/gorm"
+)
+
+// 评分阈值：达到 HoldScore 转人工审核，达到 SpamScore 直接标记为垃圾评论
+const (
+	HoldScore = 0.5
+	SpamScore = 1.0
+)
+
+// Default 全局垃圾评论检测器，由 InitChecker 初始化
+var Default Checker = Chain{}
+
+// Input 待检测的评论
+type Input struct {
+	// CommentID 修改已有评论时不为 0
+	CommentID uint64
+	UserID    uint64
+	PostID    uint64
+	Author    string
+	Content   string
+	IP        string
+	UserAgent string
+	Referrer  string
+	Permalink string
+	// 蜜罐字段，正常用户看不到也不会填写
+	Honeypot string
+}
+
+// Result 检测结果，Score 越高越可能是垃圾评论
+type Result struct {
+	Score   float64
+	Reasons []string
+}
+
+// Add 累加分数并记录原因
+func (r *Result) Add(score float64, reason string) {
+	r.Score += score
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// IsSpam 是否判定为垃圾评论
+func (r Result) IsSpam() bool {
+	return r.Score >= SpamScore
+}
+
+// NeedsReview 是否需要人工审核
+func (r Result) NeedsReview() bool {
+	return r.Score >= HoldScore
+}
+
+// Checker 垃圾评论检测接口，内置启发式规则，也可以接入 Akismet 等外部服务
+type Checker interface {
+	Check(ctx context.Context, in *Input) (Result, error)
+}
+
+// Recorder 需要在评论保存成功后记录本次评论的检测器，例如按频率限制的 VelocityChecker
+type Recorder interface {
+	Record(ctx context.Context, in *Input)
+}
+
+// Chain 依次执行多个检测器并累加分数，单个检测器出错时记录日志并跳过
+type Chain []Checker
+
+func (c Chain) Check(ctx context.Context, in *Input) (Result, error) {
+	var result Result
+	for _, checker := range c {
+		r, err := checker.Check(ctx, in)
+		if err != nil {
+			logger.Log.WithContext(ctx).Errorf("spam check err: %v", err)
+			continue
+		}
+		result.Score += r.Score
+		result.Reasons = append(result.Reasons, r.Reasons...)
+	}
+	return result, nil
+}
+
+// Record 转发给实现了 Recorder 的检测器
+func (c Chain) Record(ctx context.Context, in *Input) {
+	for _, checker := range c {
+		if recorder, ok := checker.(Recorder); ok {
+			recorder.Record(ctx, in)
+		}
+	}
+}
+
+// InitChecker 按配置组装检测器：内置启发式规则，配置了 Akismet 地址时追加 Akismet 检测
+func InitChecker(db *gorm.DB, c config.SpamConfig) {
+	chain := Chain{
+		HoneypotChecker{},
+		LinkChecker{MaxLinks: c.MaxLinks},
+		NewBannedWordChecker(c.BannedWords),
+		DuplicateChecker{DB: db, Window: 24 * time.Hour},
+		NewVelocityChecker(c.RateLimit, time.Minute),
+	}
+	if c.AkismetEndpoint != "" {
+		chain = append(chain, NewAkismetClient(c.AkismetEndpoint, c.AkismetKey, c.AkismetBlog))
+	}
+	Default = chain
+}
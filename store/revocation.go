@@ -0,0 +1,45 @@
+package store
+
+import (
+	"time"
+
+	"github.com/gavin/blog/config"
+)
+
+// Revocations 全局令牌撤销存储，由 InitRevocationStore 初始化
+var Revocations RevocationStore
+
+// RevocationStore 令牌撤销存储接口
+// 默认实现基于数据库，可替换为 Redis 等兼容后端（见 KVRevocationStore）
+type RevocationStore interface {
+	// RevokeToken 撤销单个令牌，expiresAt 之后记录可以被清理
+	RevokeToken(jti string, expiresAt time.Time) error
+	// IsTokenRevoked 判断令牌是否已撤销
+	IsTokenRevoked(jti string) (bool, error)
+	// RevokeUserTokens 撤销用户在 before 之前签发的所有令牌
+	RevokeUserTokens(userID uint64, before time.Time) error
+	// UserTokensRevokedAt 返回用户级撤销时间，零值表示没有撤销
+	UserTokensRevokedAt(userID uint64) (time.Time, error)
+}
+
+// IsRevoked 综合判断令牌是否失效：单个令牌被撤销，或签发时间早于用户级撤销时间所在的秒
+func IsRevoked(s RevocationStore, jti string, userID uint64, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := s.IsTokenRevoked(jti)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	revokedAt, err := s.UserTokensRevokedAt(userID)
+	if err != nil {
+		return false, err
+	}
+	// JWT 的 iat 精度为秒，撤销时间也截断到秒再比较，
+	// 否则撤销后同一秒内重新签发的令牌（iat 被截断到更早）会被误判为失效
+	return !revokedAt.IsZero() && issuedAt.Before(revokedAt.Truncate(time.Second)), nil
+}
+
+// InitRevocationStore 初始化撤销存储：数据库 + 内存缓存
+func InitRevocationStore() {
+	Revocations = NewCachedRevocationStore(NewDBRevocationStore(config.DB), 30*time.Second)
+}
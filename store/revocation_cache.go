@@ -0,0 +1,116 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// CachedRevocationStore 在任意撤销存储前加一层内存缓存
+// 已撤销的结果一直缓存到令牌过期；未撤销的结果只缓存 ttl，
+// 多实例部署时其他实例的撤销最多延迟 ttl 生效
+type CachedRevocationStore struct {
+	backend RevocationStore
+	ttl     time.Duration
+
+	mu     sync.RWMutex
+	tokens map[string]cacheEntry
+	users  map[uint64]userCacheEntry
+	lastGC time.Time
+}
+
+type cacheEntry struct {
+	revoked  bool
+	expireAt time.Time
+}
+
+type userCacheEntry struct {
+	revokedAt time.Time
+	expireAt  time.Time
+}
+
+func NewCachedRevocationStore(backend RevocationStore, ttl time.Duration) *CachedRevocationStore {
+	return &CachedRevocationStore{
+		backend: backend,
+		ttl:     ttl,
+		tokens:  make(map[string]cacheEntry),
+		users:   make(map[uint64]userCacheEntry),
+		lastGC:  time.Now(),
+	}
+}
+
+func (s *CachedRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	if err := s.backend.RevokeToken(jti, expiresAt); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.tokens[jti] = cacheEntry{revoked: true, expireAt: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *CachedRevocationStore) IsTokenRevoked(jti string) (bool, error) {
+	now := time.Now()
+	s.mu.RLock()
+	entry, ok := s.tokens[jti]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.backend.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.tokens[jti] = cacheEntry{revoked: revoked, expireAt: now.Add(s.ttl)}
+	s.gc(now)
+	s.mu.Unlock()
+	return revoked, nil
+}
+
+func (s *CachedRevocationStore) RevokeUserTokens(userID uint64, before time.Time) error {
+	if err := s.backend.RevokeUserTokens(userID, before); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.users[userID] = userCacheEntry{revokedAt: before, expireAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *CachedRevocationStore) UserTokensRevokedAt(userID uint64) (time.Time, error) {
+	now := time.Now()
+	s.mu.RLock()
+	entry, ok := s.users[userID]
+	s.mu.RUnlock()
+	if ok && now.Before(entry.expireAt) {
+		return entry.revokedAt, nil
+	}
+
+	revokedAt, err := s.backend.UserTokensRevokedAt(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	s.mu.Lock()
+	s.users[userID] = userCacheEntry{revokedAt: revokedAt, expireAt: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return revokedAt, nil
+}
+
+// gc 定期清理过期的缓存项，调用方需持有写锁
+func (s *CachedRevocationStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < time.Minute {
+		return
+	}
+	s.lastGC = now
+	for jti, entry := range s.tokens {
+		if !now.Before(entry.expireAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.users {
+		if !now.Before(entry.expireAt) {
+			delete(s.users, userID)
+		}
+	}
+}
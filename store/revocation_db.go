@@ -0,0 +1,59 @@
+package store
+
+import (
+	"time"
+
+	"github.com/gavin/blog/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBRevocationStore 基于数据库的撤销存储
+type DBRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewDBRevocationStore(db *gorm.DB) *DBRevocationStore {
+	return &DBRevocationStore{db: db}
+}
+
+func (s *DBRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+func (s *DBRevocationStore) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (s *DBRevocationStore) RevokeUserTokens(userID uint64, before time.Time) error {
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "updated_at"}),
+	}).Create(&models.UserTokenRevocation{
+		UserID:    userID,
+		RevokedAt: before,
+	}).Error
+}
+
+func (s *DBRevocationStore) UserTokensRevokedAt(userID uint64) (time.Time, error) {
+	var revocations []models.UserTokenRevocation
+	if err := s.db.Where("user_id = ?", userID).Limit(1).Find(&revocations).Error; err != nil {
+		return time.Time{}, err
+	}
+	if len(revocations) == 0 {
+		return time.Time{}, nil
+	}
+	return revocations[0].RevokedAt, nil
+}
+
+// PurgeExpired 清理已经过期的撤销记录（过期令牌本身已无法通过校验）
+func (s *DBRevocationStore) PurgeExpired() error {
+	return s.db.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrKeyNotFound KVClient 在 key 不存在时返回的错误
+var ErrKeyNotFound = errors.New("key not found")
+
+// KVClient 最小化的键值客户端接口，Redis 兼容后端（go-redis 等）包装一层即可接入
+type KVClient interface {
+	Set(key string, value string, ttl time.Duration) error
+	Get(key string) (string, error)
+}
+
+// KVRevocationStore 基于键值存储的撤销存储，令牌记录随 TTL 自动过期
+type KVRevocationStore struct {
+	client KVClient
+	prefix string
+}
+
+func NewKVRevocationStore(client KVClient, prefix string) *KVRevocationStore {
+	return &KVRevocationStore{client: client, prefix: prefix}
+}
+
+func (s *KVRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(s.prefix+"jti:"+jti, "1", ttl)
+}
+
+func (s *KVRevocationStore) IsTokenRevoked(jti string) (bool, error) {
+	_, err := s.client.Get(s.prefix + "jti:" + jti)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *KVRevocationStore) RevokeUserTokens(userID uint64, before time.Time) error {
+	return s.client.Set(s.userKey(userID), strconv.FormatInt(before.Unix(), 10), 0)
+}
+
+func (s *KVRevocationStore) UserTokensRevokedAt(userID uint64) (time.Time, error) {
+	value, err := s.client.Get(s.userKey(userID))
+	if errors.Is(err, ErrKeyNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid revocation value %q: %w", value, err)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+func (s *KVRevocationStore) userKey(userID uint64) string {
+	return s.prefix + "user:" + strconv.FormatUint(userID, 10)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+type memoryKV map[string]string
+
+func (m memoryKV) Set(key string, value string, ttl time.Duration) error {
+	m[key] = value
+	return nil
+}
+
+func (m memoryKV) Get(key string) (string, error) {
+	value, ok := m[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// userRevocations 只实现用户级撤销，保留撤销时间的亚秒精度（与数据库存储一致）
+type userRevocations map[uint64]time.Time
+
+func (userRevocations) RevokeToken(jti string, expiresAt time.Time) error { return nil }
+
+func (userRevocations) IsTokenRevoked(jti string) (bool, error) { return false, nil }
+
+func (s userRevocations) RevokeUserTokens(userID uint64, before time.Time) error {
+	s[userID] = before
+	return nil
+}
+
+func (s userRevocations) UserTokensRevokedAt(userID uint64) (time.Time, error) {
+	return s[userID], nil
+}
+
+func TestIsRevokedSecondPrecision(t *testing.T) {
+	revokedAt := time.Date(2026, 10, 18, 12, 0, 10, 700*int(time.Millisecond), time.UTC)
+	for name, s := range map[string]RevocationStore{
+		"db": userRevocations{},
+		"kv": NewKVRevocationStore(memoryKV{}, "test:"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			testIsRevoked(t, s, revokedAt)
+		})
+	}
+}
+
+func testIsRevoked(t *testing.T, s RevocationStore, revokedAt time.Time) {
+	if err := s.RevokeUserTokens(1, revokedAt); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"earlier second", time.Unix(revokedAt.Unix()-1, 0), true},
+		// 撤销后同一秒内重新登录，iat 截断后与撤销时间同秒
+		{"same second", time.Unix(revokedAt.Unix(), 0), false},
+		{"later second", time.Unix(revokedAt.Unix()+1, 0), false},
+	}
+	for _, tc := range cases {
+		got, err := IsRevoked(s, "", 1, tc.issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: IsRevoked = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if got, _ := IsRevoked(s, "", 2, time.Unix(0, 0)); got {
+		t.Error("user without revocation should not be revoked")
+	}
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// 保存 span 开始前的 context，语句结束后恢复，避免同一个 Statement 上的后续语句挂到已结束的 span 下
+const parentContextKey = "otel:parent_context"
+
+// GormPlugin 为每条 SQL 创建 client span，通过 db.Use(tracing.GormPlugin{}) 启用
+// 需要用 db.WithContext(ctx) 传入请求的 context，span 才会挂在请求的 span 下
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "otel:tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("otel:before_create", beforeStatement("insert")),
+		callback.Create().After("gorm:create").Register("otel:after_create", afterStatement),
+		callback.Query().Before("gorm:query").Register("otel:before_query", beforeStatement("select")),
+		callback.Query().After("gorm:query").Register("otel:after_query", afterStatement),
+		callback.Update().Before("gorm:update").Register("otel:before_update", beforeStatement("update")),
+		callback.Update().After("gorm:update").Register("otel:after_update", afterStatement),
+		callback.Delete().Before("gorm:delete").Register("otel:before_delete", beforeStatement("delete")),
+		callback.Delete().After("gorm:delete").Register("otel:after_delete", afterStatement),
+		callback.Row().Before("gorm:row").Register("otel:before_row", beforeStatement("row")),
+		callback.Row().After("gorm:row").Register("otel:after_row", afterStatement),
+		callback.Raw().Before("gorm:raw").Register("otel:before_raw", beforeStatement("raw")),
+		callback.Raw().After("gorm:raw").Register("otel:after_raw", afterStatement),
+	)
+}
+
+func beforeStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, _ := Tracer().Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBOperationName(operation)))
+		db.InstanceSet(parentContextKey, parent)
+		db.Statement.Context = ctx
+	}
+}
+
+func afterStatement(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	if parent, ok := db.InstanceGet(parentContextKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+	if !span.IsRecording() {
+		span.End()
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBSystemNameKey.String(dbSystem(db.Dialector.Name())),
+		semconv.DBQueryText(SanitizeSQL(db.Statement.SQL.String())),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	// 记录不存在是正常的业务结果，不标记为错误
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// dbSystem GORM 方言名转换为 OTel 约定的 db.system.name
+func dbSystem(dialector string) string {
+	if dialector == "postgres" {
+		return "postgresql"
+	}
+	return dialector
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?`)
+)
+
+// SanitizeSQL 去掉 SQL 中的字符串和数字字面量
+// GORM 生成的语句本身是参数化的，这里主要处理 Raw / Exec 中直接拼写的值
+func SanitizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	return numericLiteral.ReplaceAllString(sql, "${1}?")
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"parameterized", "SELECT * FROM `posts` WHERE id = ? AND deleted_at IS NULL", "SELECT * FROM `posts` WHERE id = ? AND deleted_at IS NULL"},
+		{"string literal", "SELECT * FROM users WHERE username = 'alice'", "SELECT * FROM users WHERE username = ?"},
+		{"escaped quote", "UPDATE posts SET title = 'it''s ok' WHERE id = 1", "UPDATE posts SET title = ? WHERE id = ?"},
+		{"numbers", "DELETE FROM post_tags WHERE tag_id = 42 AND score > -1.5", "DELETE FROM post_tags WHERE tag_id = ? AND score > ?"},
+		{"limit offset", "SELECT * FROM comments LIMIT 10 OFFSET 20", "SELECT * FROM comments LIMIT ? OFFSET ?"},
+		{"in list", "SELECT * FROM tags WHERE id IN (1,2,3)", "SELECT * FROM tags WHERE id IN (?,?,?)"},
+		{"identifiers with digits", "SELECT t1.id FROM table2 AS t1 WHERE t1.v2 = $1", "SELECT t1.id FROM table2 AS t1 WHERE t1.v2 = $1"},
+		{"leading number", "1 = 1", "? = ?"},
+		{"digits inside string", "SELECT * FROM posts WHERE slug = 'go-1-21'", "SELECT * FROM posts WHERE slug = ?"},
+	}
+	for _, tc := range cases {
+		if got := SanitizeSQL(tc.sql); got != tc.want {
+			t.Errorf("%s: SanitizeSQL(%q) = %q, want %q", tc.name, tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestDBSystem(t *testing.T) {
+	for dialector, want := range map[string]string{"postgres": "postgresql", "mysql": "mysql", "sqlite": "sqlite"} {
+		if got := dbSystem(dialector); got != want {
+			t.Errorf("dbSystem(%q) = %q, want %q", dialector, got, want)
+		}
+	}
+}
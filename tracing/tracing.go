@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gavin/blog/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation 本项目埋点使用的 tracer 名称
+const instrumentation = "github.com/gavin/blog"
+
+// Tracer 全局 tracer，Init 之前和未启用时为 no-op
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Provider 已启用的链路追踪，退出时调用 Stop 导出剩余的 span
+type Provider struct {
+	tp     *sdktrace.TracerProvider
+	closer io.Closer
+}
+
+// Stop 导出缓冲中的 span 并关闭导出器
+func (p *Provider) Stop(ctx context.Context) error {
+	err := p.tp.Shutdown(ctx)
+	if p.closer != nil {
+		if closeErr := p.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Init 按配置设置全局 TracerProvider 和 W3C traceparent 传播器
+// exporter 为 none 时只设置传播器，返回 nil，埋点全部为 no-op
+func Init(c *config.TracingConfig) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if c.Exporter == config.TracingExporterNone {
+		return nil, nil
+	}
+
+	provider := &Provider{}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch c.Exporter {
+	case config.TracingExporterOTLP:
+		var opts []otlptracehttp.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case config.TracingExporterFile:
+		var file *os.File
+		if err = os.MkdirAll(filepath.Dir(c.File), 0o755); err != nil {
+			break
+		}
+		if file, err = os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			break
+		}
+		provider.closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		err = fmt.Errorf("unsupported exporter %q", c.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init tracing exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(c.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("init tracing resource: %w", err)
+	}
+	provider.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider.tp)
+	return provider, nil
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 行差异类型
+const (
+	DiffEqual  = " "
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+// DiffLine 一行差异
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxDiffLines 两段文本合计的最大行数，超过时 LineDiff 返回 ErrDiffTooLarge
+// 最坏情况下耗时与 行数 × 差异行数 成正比，限制行数避免单个请求占用过多 CPU
+const MaxDiffLines = 10000
+
+// ErrDiffTooLarge 文本行数超过 MaxDiffLines
+var ErrDiffTooLarge = errors.New("diff input too large")
+
+// LineDiff 使用线性空间的 Myers 算法（中间蛇分治）计算两段文本的逐行差异
+func LineDiff(a, b string) ([]DiffLine, error) {
+	x := splitLines(a)
+	y := splitLines(b)
+	if len(x)+len(y) > MaxDiffLines {
+		return nil, fmt.Errorf("%w: %d lines, max %d", ErrDiffTooLarge, len(x)+len(y), MaxDiffLines)
+	}
+	size := len(x) + len(y) + 4
+	d := &differ{x: x, y: y, vf: make([]int, size), vb: make([]int, size)}
+	d.diff(0, len(x), 0, len(y))
+	return d.lines, nil
+}
+
+// differ 保存差异结果和中间蛇搜索复用的两个数组，内存占用与行数成正比
+type differ struct {
+	x, y   []string
+	lines  []DiffLine
+	vf, vb []int
+}
+
+// diff 计算 x[x0:x1] 与 y[y0:y1] 的差异：去掉公共前后缀后，找到最短编辑路径中间的一段公共行（中间蛇），
+// 以它为界递归处理前后两部分
+func (d *differ) diff(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && d.x[x0] == d.y[y0] {
+		d.lines = append(d.lines, DiffLine{Op: DiffEqual, Text: d.x[x0]})
+		x0++
+		y0++
+	}
+	suffix := x1
+	for x1 > x0 && y1 > y0 && d.x[x1-1] == d.y[y1-1] {
+		x1--
+		y1--
+	}
+
+	switch {
+	case x0 == x1:
+		for _, text := range d.y[y0:y1] {
+			d.lines = append(d.lines, DiffLine{Op: DiffInsert, Text: text})
+		}
+	case y0 == y1:
+		for _, text := range d.x[x0:x1] {
+			d.lines = append(d.lines, DiffLine{Op: DiffDelete, Text: text})
+		}
+	default:
+		xs, ys, xe, ye := d.middleSnake(x0, x1, y0, y1)
+		d.diff(x0, xs, y0, ys)
+		for _, text := range d.x[xs:xe] {
+			d.lines = append(d.lines, DiffLine{Op: DiffEqual, Text: text})
+		}
+		d.diff(xe, x1, ye, y1)
+	}
+
+	for _, text := range d.x[x1:suffix] {
+		d.lines = append(d.lines, DiffLine{Op: DiffEqual, Text: text})
+	}
+}
+
+// middleSnake 从两端同时搜索最短编辑路径，返回两端相遇处的蛇（对角线上连续相等的行）的起点和终点
+// vf[k] 为正向第 k 条对角线上到达的最远 x，vb[k] 为反向（从末尾往前）第 k 条对角线上到达的最远距离
+func (d *differ) middleSnake(x0, x1, y0, y1 int) (xs, ys, xe, ye int) {
+	n, m := x1-x0, y1-y0
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+	vf, vb := d.vf[:2*max+3], d.vb[:2*max+3]
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	for step := 0; step <= max; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.x[x0+x] == d.y[y0+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			// 编辑距离为奇数时，在正向搜索中与上一步的反向路径相遇
+			if odd && k >= delta-(step-1) && k <= delta+(step-1) && x+vb[offset+delta-k] >= n {
+				return x0 + startX, y0 + startY, x0 + x, y0 + y
+			}
+		}
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.x[x1-1-x] == d.y[y1-1-y] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			// 编辑距离为偶数时，在反向搜索中与同一步的正向路径相遇；反向坐标换算回正向坐标
+			if !odd && delta-k >= -step && delta-k <= step && x+vf[offset+delta-k] >= n {
+				return x1 - x, y1 - y, x1 - startX, y1 - startY
+			}
+		}
+	}
+	// 两个方向在 max 步之内一定相遇
+	panic("utils: middle snake not found")
+}
+
+// UnifiedDiff 根据 LineDiff 的结果生成 unified 格式的差异文本，context 为变更前后保留的上下文行数
+func UnifiedDiff(lines []DiffLine, fromName, toName string, context int) string {
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	// a、b 中的行号（从 1 开始）
+	aLine, bLine := make([]int, len(lines)), make([]int, len(lines))
+	ai, bi := 1, 1
+	for idx, line := range lines {
+		aLine[idx], bLine[idx] = ai, bi
+		if line.Op != DiffInsert {
+			ai++
+		}
+		if line.Op != DiffDelete {
+			bi++
+		}
+	}
+
+	for start := 0; start < len(lines); {
+		// 找到下一处变更
+		first := start
+		for first < len(lines) && lines[first].Op == DiffEqual {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		// 合并相距不超过 2*context 的变更为一个 hunk
+		hunkStart := first - context
+		if hunkStart < start {
+			hunkStart = start
+		}
+		end := first
+		for end < len(lines) {
+			if lines[end].Op != DiffEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == DiffEqual {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		hunkEnd := end + context
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+
+		aCount, bCount := 0, 0
+		for _, line := range lines[hunkStart:hunkEnd] {
+			if line.Op != DiffInsert {
+				aCount++
+			}
+			if line.Op != DiffDelete {
+				bCount++
+			}
+		}
+		aStart, bStart := aLine[hunkStart], bLine[hunkStart]
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, line := range lines[hunkStart:hunkEnd] {
+			out.WriteString(line.Op)
+			out.WriteString(line.Text)
+			out.WriteByte('\n')
+		}
+		start = hunkEnd
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
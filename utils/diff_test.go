@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// apply 从差异结果还原两段文本
+func apply(lines []DiffLine) (a, b []string) {
+	for _, line := range lines {
+		if line.Op != DiffInsert {
+			a = append(a, line.Text)
+		}
+		if line.Op != DiffDelete {
+			b = append(b, line.Text)
+		}
+	}
+	return a, b
+}
+
+// lcsLength 动态规划求最长公共子序列长度，用于校验差异是否最短
+func lcsLength(x, y []string) int {
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			switch {
+			case x[i-1] == y[j-1]:
+				cur[j] = prev[j-1] + 1
+			case prev[j] > cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+func checkDiff(t *testing.T, a, b string) []DiffLine {
+	t.Helper()
+	lines, err := LineDiff(a, b)
+	if err != nil {
+		t.Fatalf("LineDiff: %v", err)
+	}
+	x, y := splitLines(a), splitLines(b)
+	gotA, gotB := apply(lines)
+	if strings.Join(gotA, "\n") != strings.Join(x, "\n") || strings.Join(gotB, "\n") != strings.Join(y, "\n") {
+		t.Fatalf("diff does not reproduce inputs\na=%q\nb=%q\ndiff=%v", a, b, lines)
+	}
+	changes := 0
+	for _, line := range lines {
+		if line.Op != DiffEqual {
+			changes++
+		}
+	}
+	if want := len(x) + len(y) - 2*lcsLength(x, y); changes != want {
+		t.Fatalf("diff has %d changes, shortest is %d\na=%q\nb=%q", changes, want, a, b)
+	}
+	return lines
+}
+
+func TestLineDiff(t *testing.T) {
+	cases := [][2]string{
+		{"", ""},
+		{"a", ""},
+		{"", "a\nb"},
+		{"a\nb\nc", "a\nb\nc"},
+		{"a\nb\nc", "a\nx\nc"},
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc"},
+		{"line\r\nwindows\r\n", "line\nunix\n"},
+	}
+	for _, tc := range cases {
+		checkDiff(t, tc[0], tc[1])
+	}
+}
+
+func TestLineDiffRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := func() string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+	for i := 0; i < 500; i++ {
+		checkDiff(t, gen(), gen())
+	}
+}
+
+func TestLineDiffLargeInput(t *testing.T) {
+	var a, b strings.Builder
+	n := MaxDiffLines / 2
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&a, "line %d\n", i)
+		// 每 7 行改一行，每 50 行插入一行，制造大量分散的差异
+		if i%7 == 0 {
+			fmt.Fprintf(&b, "changed %d\n", i)
+		} else if i%50 != 0 || i == 0 {
+			fmt.Fprintf(&b, "line %d\n", i)
+		} else {
+			fmt.Fprintf(&b, "line %d\ninserted %d\n", i, i)
+		}
+	}
+	// 合计行数略超上限，去掉 b 的最后几行
+	bLines := splitLines(b.String())
+	bText := strings.Join(bLines[:MaxDiffLines-n], "\n")
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	lines, err := LineDiff(a.String(), bText)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotA, gotB := apply(lines)
+	if len(gotA) != n || strings.Join(gotB, "\n") != bText {
+		t.Fatal("diff does not reproduce inputs")
+	}
+	// 线性空间：分配量与行数同一量级，而不是 行数 × 差异数
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("LineDiff allocated %d bytes for %d lines", allocated, MaxDiffLines)
+	}
+}
+
+func TestLineDiffTooLarge(t *testing.T) {
+	big := strings.Repeat("x\n", MaxDiffLines)
+	if _, err := LineDiff(big, "y"); !errors.Is(err, ErrDiffTooLarge) {
+		t.Errorf("err = %v, want ErrDiffTooLarge", err)
+	}
+	if _, err := LineDiff(strings.Repeat("x\n", 20000), strings.Repeat("y\n", 20000)); !errors.Is(err, ErrDiffTooLarge) {
+		t.Errorf("err = %v, want ErrDiffTooLarge", err)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines, err := LineDiff("a\nb\nc\nd\ne\nf\ng\nh\ni\nk\nl\nm", "a\nb\nc\nD\ne\nf\ng\nh\ni\nk\nl\nm\nj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `--- v1
++++ v2
+@@ -2,5 +2,5 @@
+ b
+ c
+-d
++D
+ e
+ f
+@@ -11,2 +11,3 @@
+ l
+ m
++j
+`
+	if got := UnifiedDiff(lines, "v1", "v2", 2); got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+}
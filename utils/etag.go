@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetETag 以版本号作为 ETag 响应头，客户端修改时通过 If-Match 带回
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+var (
+	// ErrVersionRequired 既没有 If-Match 请求头，也没有 version
+	ErrVersionRequired = errors.New("version is required")
+	// ErrInvalidIfMatch If-Match 不是本服务签发的 ETag（带引号的正整数版本号）
+	ErrInvalidIfMatch = errors.New("invalid If-Match header")
+)
+
+// ExpectedVersion 获取客户端期望的版本号：优先使用 If-Match 请求头，其次使用请求体中的 version
+func ExpectedVersion(c *gin.Context, bodyVersion int) (int, error) {
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		value := strings.TrimPrefix(strings.TrimSpace(ifMatch), "W/")
+		version, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || version <= 0 {
+			return 0, ErrInvalidIfMatch
+		}
+		return version, nil
+	}
+	if bodyVersion <= 0 {
+		return 0, ErrVersionRequired
+	}
+	return bodyVersion, nil
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	cases := []struct {
+		ifMatch     string
+		bodyVersion int
+		want        int
+		err         error
+	}{
+		{`"3"`, 0, 3, nil},
+		{`W/"4"`, 1, 4, nil},
+		{"", 2, 2, nil},
+		{"", 0, 0, ErrVersionRequired},
+		{`"abc"`, 2, 0, ErrInvalidIfMatch},
+		{`"0"`, 0, 0, ErrInvalidIfMatch},
+		{"*", 5, 0, ErrInvalidIfMatch},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/post/update", nil)
+		if tc.ifMatch != "" {
+			c.Request.Header.Set("If-Match", tc.ifMatch)
+		}
+		got, err := ExpectedVersion(c, tc.bodyVersion)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("ExpectedVersion(If-Match %q, body %d) = %d, %v, want %d, %v", tc.ifMatch, tc.bodyVersion, got, err, tc.want, tc.err)
+		}
+	}
+}
@@ -1,38 +1,61 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/gavin/blog/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	// 密钥
-	secretKey = config.GetEnv("JWT_SECRET_KEY", "")
-	// 令牌有效期（访问令牌：1小时，刷新令牌：7天）
-	tokenExpire = time.Hour * 24
+	// 密钥，由 InitJWT 在加载配置后设置
+	secretKey string
+	// 令牌有效期（默认访问令牌：1小时，刷新令牌：7天）
+	AccessTokenExpire  = time.Hour
+	RefreshTokenExpire = time.Hour * 24 * 7
 )
 
+// InitJWT 设置签名密钥和令牌有效期
+func InitJWT(secret string, accessTTL, refreshTTL time.Duration) {
+	secretKey = secret
+	AccessTokenExpire = accessTTL
+	RefreshTokenExpire = refreshTTL
+}
+
 type CustomClaims struct {
-	UserID               uint64 `json:"user_id"`  // 用户ID
-	Username             string `json:"username"` // 用户名
-	jwt.RegisteredClaims        // 嵌入官方标准声明（包含exp/iss等）
+	UserID               uint64   `json:"user_id"`     // 用户ID
+	Username             string   `json:"username"`    // 用户名
+	Role                 string   `json:"role"`        // 角色
+	Permissions          []string `json:"permissions"` // 权限（角色权限 + 额外授予）
+	jwt.RegisteredClaims          // 嵌入官方标准声明（包含exp/iss等）
 }
 
-// 生成JWT令牌（通用函数）
-func GenerateToken(userID uint64, username string) (string, error) {
+// 生成JWT访问令牌，同时返回过期时间
+func GenerateToken(userID uint64, username string, role string, permissions []string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenExpire)
+	// jti 用于撤销单个令牌（退出登录）
+	jti, err := NewTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
 	// 构建自定义载荷
 	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
+		UserID:      userID,
+		Username:    username,
+		Role:        role,
+		Permissions: permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID: jti,
 			// 过期时间
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExpire)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
@@ -40,7 +63,11 @@ func GenerateToken(userID uint64, username string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// 签名生成最终令牌字符串
-	return token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString([]byte(secretKey))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
 }
 
 // 4. 验证并解析JWT令牌
@@ -70,3 +97,37 @@ func ParseToken(tokenString string) (*CustomClaims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// GenerateRefreshToken 生成随机的刷新令牌（不透明字符串，只把哈希值存库）
+func GenerateRefreshToken() (string, error) {
+	return RandomString(32)
+}
+
+// HashToken 计算令牌的 SHA-256 哈希，用于存储和查找刷新令牌
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenFamily 生成令牌家族ID，同一次登录轮换出来的刷新令牌共享一个家族
+func NewTokenFamily() (string, error) {
+	return NewTokenID()
+}
+
+// NewTokenID 生成 32 位十六进制的随机ID
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RandomString 生成 n 字节随机数并做 URL 安全的 base64 编码
+func RandomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
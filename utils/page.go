@@ -68,18 +68,27 @@ func GetPaginatedData(db *gorm.DB, dest interface{}, pagination *Pagination) (*P
 		return nil, err
 	}
 
-	// 3. 计算总页数
+	// 3. 构造响应
+	return NewPageResult(dest, total, pagination), nil
+}
+
+// Offset 设置默认值后返回偏移量，用于不经过 GORM 的分页
+func (p *Pagination) Offset() int {
+	p.setDefault()
+	return (p.Page - 1) * p.PageSize
+}
+
+// NewPageResult 根据数据和总记录数构造分页响应，pagination 需已设置默认值
+func NewPageResult(data interface{}, total int64, pagination *Pagination) *PageResult {
+	// 计算总页数
 	totalPages := int((total + int64(pagination.PageSize) - 1) / int64(pagination.PageSize))
 
-	// 4. 构造响应
-	PageResult := &PageResult{
-		Data:       dest,
+	return &PageResult{
+		Data:       data,
 		Total:      total,
 		Page:       pagination.Page,
 		PageSize:   pagination.PageSize,
 		TotalPages: totalPages,
 		HasMore:    int64(pagination.Page) < (total/int64(pagination.PageSize) + 1),
 	}
-
-	return PageResult, nil
 }
@@ -0,0 +1,13 @@
+package utils
+
+import "github.com/gin-gonic/gin"
+
+// HasPermission 判断当前登录用户是否拥有某个权限（由 JWTAuthMiddleware 写入上下文）
+func HasPermission(c *gin.Context, permission string) bool {
+	for _, p := range c.GetStringSlice("permissions") {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
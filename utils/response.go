@@ -11,6 +11,8 @@ type Response struct {
 	Code int         `json:"code"`
 	Msg  string      `json:"msg"`
 	Data interface{} `json:"data"`
+	// 请求 ID，与响应头 X-Request-ID 相同，反馈问题时提供给我们用于查日志
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func Success(c *gin.Context, data interface{}, msg string) {
@@ -18,22 +20,35 @@ func Success(c *gin.Context, data interface{}, msg string) {
 		msg = "success"
 	}
 	c.JSON(http.StatusOK, Response{
-		Code: 0,
-		Msg:  msg,
-		Data: data,
+		Code:      0,
+		Msg:       msg,
+		Data:      data,
+		RequestID: c.GetString("request_id"),
 	})
 }
 
 func Fail(c *gin.Context, code int, msg string) {
 	c.JSON(http.StatusOK, Response{
-		Code: code,
-		Msg:  msg,
+		Code:      code,
+		Msg:       msg,
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// FailWithData 失败响应并附带数据，如版本冲突时返回服务端当前版本
+func FailWithData(c *gin.Context, code int, msg string, data interface{}) {
+	c.JSON(http.StatusOK, Response{
+		Code:      code,
+		Msg:       msg,
+		Data:      data,
+		RequestID: c.GetString("request_id"),
 	})
 }
 
 func Error(c *gin.Context, msg string) {
 	c.JSON(http.StatusOK, Response{
-		Code: errors.SYSTEM_ERROR,
-		Msg:  msg,
+		Code:      errors.SYSTEM_ERROR,
+		Msg:       msg,
+		RequestID: c.GetString("request_id"),
 	})
 }